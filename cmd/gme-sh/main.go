@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/geoip"
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/web"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
 	"github.com/gofiber/adaptor/v2"
@@ -62,6 +63,10 @@ func main() {
 		// TODO
 		pubSub = db.MustPubSub(db.NewRedisPubSub(cfg.Database.Redis))
 		break
+	case "memory":
+		log.Println("👉 Using Memory as pubsub-backend (single process only)")
+		pubSub = db.MustPubSub(db.NewMemoryPubSub())
+		break
 	default:
 		log.Fatalln("🚨 Unknown pubsub backend:", cfg.Backends.PubSubBackend)
 		return
@@ -71,7 +76,19 @@ func main() {
 	switch strings.ToLower(cfg.Backends.StatsBackend) {
 	case "redis":
 		log.Println("👉 Using Redis as stats-backend")
-		statsDB = db.MustStats(db.NewRedisStats(cfg.Database.Redis))
+		statsDB = db.MustStats(db.NewRedisStats(cfg.Database.Redis, cfg.Stats))
+		break
+	case "mongo":
+		log.Println("👉 Using MongoDB as stats-backend")
+		statsDB = db.MustStats(db.NewMongoStats(cfg.Database.Mongo, cfg.Stats))
+		break
+	case "bbolt":
+		log.Println("👉 Using BBolt as stats-backend")
+		statsDB = db.MustStats(db.NewBBoltStats(cfg.Database.BBolt, cfg.Stats))
+		break
+	case "memory":
+		log.Println("👉 Using Memory as stats-backend (non-persistent)")
+		statsDB = db.MustStats(db.NewMemoryStats(cfg.Stats))
 		break
 	default:
 		log.Fatalln("🚨 Unknown stats backend:", cfg.Backends.StatsBackend)
@@ -111,6 +128,14 @@ func main() {
 		log.Println("👉 Using Redis as persistent-backend")
 		persistentDB = db.MustPersistent(db.NewRedisDatabase(cfg.Database.Redis))
 		break
+	case "sql":
+		log.Println("👉 Using SQL (" + cfg.Database.SQL.Driver + ") as persistent-backend")
+		persistentDB = db.MustPersistent(db.NewSQLDatabase(cfg.Database.SQL, cache))
+		break
+	case "memory":
+		log.Println("👉 Using Memory as persistent-backend (non-persistent)")
+		persistentDB = db.MustPersistent(db.NewMemoryDatabase(cache))
+		break
 	default:
 		log.Fatalln("🚨 Unknown persistent backend:", cfg.Backends.PersistentBackend)
 		return
@@ -141,6 +166,20 @@ func main() {
 
 	////////////////////////////////////////////////////////////////////////////////////////
 
+	// GeoIP (optional)
+	geoIP, err := geoip.New(cfg.GeoIP)
+	if err != nil {
+		log.Fatalln("🚨 Error loading GeoIP database:", err)
+		return
+	}
+	if geoIP != nil {
+		log.Println("👉 Using GeoIP database", cfg.GeoIP.Path)
+	} else {
+		log.Println("👉 No GeoIP database configured")
+	}
+
+	////////////////////////////////////////////////////////////////////////////////////////
+
 	health, err := db.NewHealthCheck(persistentDB, statsDB, pubSub)
 	if err != nil {
 		log.Fatalln("Error creating health check:", err)
@@ -150,7 +189,7 @@ func main() {
 	////
 
 	//// Web-Server
-	server := web.NewWebServer(persistentDB, statsDB, cfg)
+	server := web.NewWebServer(persistentDB, statsDB, geoIP, cfg)
 	// stats
 	server.App.Get("/health", adaptor.HTTPHandler(health.Handler()))
 
@@ -178,7 +217,7 @@ func main() {
 
 	for _, t := range templates {
 		t.Check()
-		t.Register(server.App)
+		t.Register(server.App, server.DefaultRedirectStatus())
 	}
 	///
 	go server.Start()
@@ -194,6 +233,10 @@ func main() {
 	// cancel expiration
 	exc <- true
 
+	if err := geoIP.Close(); err != nil {
+		log.Println("  🤬", err)
+	}
+
 	// after CTRL+c
 	if pubSub != nil {
 		log.Println("Shutting down pubsub")
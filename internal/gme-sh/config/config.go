@@ -13,6 +13,10 @@ type Config struct {
 	Backends                *BackendConfig
 	Database                *DatabaseConfig
 	WebServer               *WebServerConfig
+	Stats                   *StatsConfig
+	GeoIP                   *GeoIPConfig
+	UTM                     *UTMConfig `env:"UTM_RULES"`
+	RateLimit               *RateLimitConfig
 }
 
 type DummyConfig struct {
@@ -23,6 +27,10 @@ type DummyConfig struct {
 	Backends                *BackendConfig
 	Database                *DatabaseConfig
 	WebServer               *WebServerConfig
+	Stats                   *StatsConfig
+	GeoIP                   *GeoIPConfig
+	UTM                     *UTMConfig `env:"UTM_RULES"`
+	RateLimit               *RateLimitConfig
 }
 
 type BackendConfig struct {
@@ -37,6 +45,7 @@ type DatabaseConfig struct {
 	Mongo *MongoConfig
 	Redis *RedisConfig
 	BBolt *BBoltConfig
+	SQL   *SQLConfig
 	Maria *MariaConfig
 }
 
@@ -44,16 +53,84 @@ type DatabaseConfig struct {
 type WebServerConfig struct {
 	Addr       string `env:"WEB_ADDR"`
 	DefaultURL string `env:"DEFAULT_URL"`
+	// FallbackURL -> visitors of unknown, deleted, expired, exhausted or not yet active short urls
+	// are redirected here if the short url has no fallback url itself. Disabled if empty.
+	FallbackURL string `env:"FALLBACK_URL"`
+	// RedirectStatus -> status code of redirects (301, 302, 307 or 308),
+	// unless the short url or template has its own redirect status
+	RedirectStatus int `env:"REDIRECT_STATUS"`
+	// Deduplicate -> return the existing short url for an identical long url instead of creating a new one,
+	// unless the request sets "deduplicate" itself
+	Deduplicate bool `env:"DEDUPLICATE"`
+	// IDGenerator -> strategy for the ids of new short urls without alias:
+	// "random" (default), "sequential", "hashids" or "words". Requests can choose another strategy.
+	IDGenerator string `env:"ID_GENERATOR"`
+	// IDLength -> initial length of random and hashids ids (default 5)
+	IDLength int `env:"ID_LENGTH"`
+	// IDSalt -> salt of the hashids ids. All instances must use the same salt.
+	IDSalt string `env:"ID_SALT"`
+	// AdminToken -> token of the admin api (Authorization: Bearer {token}), e. g. to reset lost secrets
+	// or to manage the accounts.
+	// The admin api is disabled if empty.
+	AdminToken string `env:"ADMIN_TOKEN"`
+}
+
+// StatsConfig -> Config for StatsDatabase implementations
+type StatsConfig struct {
+	// HourlyRetentionDays -> number of days hourly buckets are kept
+	HourlyRetentionDays int `env:"STATS_HOURLY_RETENTION_DAYS"`
+	// DailyRetentionDays -> number of days daily buckets are kept
+	DailyRetentionDays int `env:"STATS_DAILY_RETENTION_DAYS"`
+	// VisitorSecret -> secret for the anonymous visitor ids (see short.VisitorID).
+	// All instances must use the same secret, otherwise a random secret is generated on startup.
+	VisitorSecret string `env:"STATS_VISITOR_SECRET"`
+}
+
+// RateLimitConfig -> rate limits of the web server per route group.
+// Requests are counted per client ip or, with an API key, per account.
+// The counters are stored in the stats backend, so the limits hold across all instances.
+type RateLimitConfig struct {
+	// Disabled -> don't limit any requests
+	Disabled bool `env:"RATE_LIMIT_DISABLED"`
+	// Create -> POST /create, POST /create/bulk
+	Create *RateLimitPolicy `env:"RATE_LIMIT_CREATE"`
+	// Delete -> deleting, updating and rotating the secret of short urls
+	Delete *RateLimitPolicy `env:"RATE_LIMIT_DELETE"`
+	// Stats -> GET /stats/..., GET /links
+	Stats *RateLimitPolicy `env:"RATE_LIMIT_STATS"`
+	// Redirect -> GET /{id}, POST /{id} (password)
+	Redirect *RateLimitPolicy `env:"RATE_LIMIT_REDIRECT"`
+	// Pool -> /pool/...
+	Pool *RateLimitPolicy `env:"RATE_LIMIT_POOL"`
+	// Admin -> /admin/..., always counted per ip
+	Admin *RateLimitPolicy `env:"RATE_LIMIT_ADMIN"`
+	// Keys -> policies of single API keys by account id,
+	// which replace the policies of all limited groups (except Admin) for the account
+	Keys RateLimitKeys `env:"RATE_LIMIT_KEYS"`
+}
+
+// GeoIPConfig -> Config for the offline GeoIP lookup of clicks (MaxMind .mmdb files)
+type GeoIPConfig struct {
+	// Path -> GeoIP2 / GeoLite2 Country or City database. GeoIP is disabled if empty.
+	Path string `env:"GEOIP_PATH"`
+	// ASNPath -> optional GeoLite2 ASN database
+	ASNPath string `env:"GEOIP_ASN_PATH"`
+	// City -> break down clicks by city, too (requires a City database)
+	City bool `env:"GEOIP_CITY"`
 }
 
 // MongoConfig -> Config for MongoDB implementation
 type MongoConfig struct {
-	ApplyURI           string `env:"MDB_APPLY_URI"`
-	Database           string `env:"MDB_DATABASE"`
-	ShortURLCollection string `env:"MDB_COLLECTION_SHORT_URLS"`
-	MetaCollection     string `env:"MDB_COLLECTION_META"`
-	TplCollection      string `env:"MDB_COLLECTION_TPL"`
-	PoolCollection     string `env:"MDB_POOL_COLLECTION"`
+	ApplyURI            string `env:"MDB_APPLY_URI"`
+	Database            string `env:"MDB_DATABASE"`
+	ShortURLCollection  string `env:"MDB_COLLECTION_SHORT_URLS"`
+	MetaCollection      string `env:"MDB_COLLECTION_META"`
+	TplCollection       string `env:"MDB_COLLECTION_TPL"`
+	PoolCollection      string `env:"MDB_POOL_COLLECTION"`
+	StatsCollection     string `env:"MDB_COLLECTION_STATS"`
+	DedupCollection     string `env:"MDB_COLLECTION_DEDUP"`
+	AccountCollection   string `env:"MDB_COLLECTION_ACCOUNTS"`
+	RateLimitCollection string `env:"MDB_COLLECTION_RATELIMIT"`
 }
 
 // RedisConfig -> Config for Redis implementation
@@ -71,9 +148,24 @@ type BBoltConfig struct {
 	MetaBucketName        string      `env:"BBOLT_BUCKET_META"`
 	TplBucketName         string      `env:"BBOLT_BUCKET_TPL"`
 	PoolBucketName        string      `env:"BBOLT_BUCKET_POOL"`
+	StatsBucketName       string      `env:"BBOLT_BUCKET_STATS"`
+	ClicksBucketName      string      `env:"BBOLT_BUCKET_CLICKS"`
+	DedupBucketName       string      `env:"BBOLT_BUCKET_DEDUP"`
+	AccountBucketName     string      `env:"BBOLT_BUCKET_ACCOUNTS"`
+	RateLimitBucketName   string      `env:"BBOLT_BUCKET_RATELIMIT"`
+}
+
+// SQLConfig -> Config for SQL implementation (MariaDB/MySQL, PostgreSQL, SQLite)
+type SQLConfig struct {
+	// Driver is one of "mysql" (or "mariadb"), "postgres" or "sqlite3"
+	Driver      string `env:"SQL_DRIVER"`
+	DSN         string `env:"SQL_DSN"`
+	TablePrefix string `env:"SQL_TABLE_PREFIX"`
 }
 
 // MariaConfig -> Config for Maria Imlementation
+//
+// Deprecated: use SQLConfig with Driver = "mysql"
 type MariaConfig struct {
 	Addr        string `env:"MARIA_ADDR"`
 	User        string `env:"MARIA_USER"`
@@ -5,8 +5,23 @@ import (
 	"github.com/BurntSushi/toml"
 	"io/ioutil"
 	"log"
+	"time"
 )
 
+// DefaultRateLimit -> 30 requests per minute and client ip for all route groups except redirects,
+// 300 requests per minute and API key for creations
+func DefaultRateLimit() *RateLimitConfig {
+	minute := duration{time.Minute}
+	return &RateLimitConfig{
+		Create: &RateLimitPolicy{Max: 30, KeyMax: 300, Window: minute},
+		Delete: &RateLimitPolicy{Max: 30, Window: minute},
+		Stats:  &RateLimitPolicy{Max: 30, Window: minute},
+		Pool:   &RateLimitPolicy{Max: 30, Window: minute},
+		Admin:  &RateLimitPolicy{Max: 10, Window: minute},
+		Keys:   RateLimitKeys{},
+	}
+}
+
 // CreateDefault -> create default config
 func CreateDefault() (err error) {
 	var buf bytes.Buffer
@@ -26,11 +41,15 @@ func CreateDefault() (err error) {
 		},
 		Database: &DatabaseConfig{
 			Mongo: &MongoConfig{
-				ApplyURI:           "mongodb://localhost:27017",
-				Database:           "stonksdb",
-				ShortURLCollection: "stonks-url-collection",
-				MetaCollection:     "meta",
-				TplCollection:      "tpl",
+				ApplyURI:            "mongodb://localhost:27017",
+				Database:            "stonksdb",
+				ShortURLCollection:  "stonks-url-collection",
+				MetaCollection:      "meta",
+				TplCollection:       "tpl",
+				StatsCollection:     "stats",
+				DedupCollection:     "dedup",
+				AccountCollection:   "accounts",
+				RateLimitCollection: "ratelimit",
 			},
 			Redis: &RedisConfig{
 				Addr:     "localhost:6379",
@@ -43,6 +62,16 @@ func CreateDefault() (err error) {
 				ShortedURLsBucketName: "stonks-url-bucket",
 				MetaBucketName:        "meta",
 				TplBucketName:         "tpl",
+				StatsBucketName:       "stats",
+				ClicksBucketName:      "clicks",
+				DedupBucketName:       "dedup",
+				AccountBucketName:     "accounts",
+				RateLimitBucketName:   "ratelimit",
+			},
+			SQL: &SQLConfig{
+				Driver:      "sqlite3",
+				DSN:         "file:gme.db?cache=shared",
+				TablePrefix: "gme_",
 			},
 			Maria: &MariaConfig{
 				Addr:        "localhost",
@@ -53,9 +82,29 @@ func CreateDefault() (err error) {
 			},
 		},
 		WebServer: &WebServerConfig{
-			Addr:       ":80",
-			DefaultURL: "https://github.com/gme-sh/gme.sh-api",
+			Addr:           ":80",
+			DefaultURL:     "https://github.com/gme-sh/gme.sh-api",
+			FallbackURL:    "",
+			RedirectStatus: 302,
+			Deduplicate:    false,
+			IDGenerator:    "random",
+			IDLength:       5,
+			IDSalt:         "",
+			AdminToken:     "",
+		},
+		Stats: &StatsConfig{
+			HourlyRetentionDays: 7,
+			DailyRetentionDays:  365,
+		},
+		GeoIP: &GeoIPConfig{
+			Path:    "",
+			ASNPath: "",
+			City:    false,
+		},
+		UTM: &UTMConfig{
+			Rules: []*UTMRule{},
 		},
+		RateLimit: DefaultRateLimit(),
 	})
 	if err != nil {
 		log.Fatalln("Error encoding default config:", err)
@@ -10,14 +10,37 @@ import (
 func FromEnv(cfg *Config) (err []error) {
 	loader := env.New("GME_", log.Printf)
 	err = append(err, loader.Load(cfg))
+	err = append(err, loader.Load(cfg.Backends))
 
 	// Database
 	err = append(err, loader.Load(cfg.Database))
 	err = append(err, loader.Load(cfg.Database.Mongo))
 	err = append(err, loader.Load(cfg.Database.Redis))
 	err = append(err, loader.Load(cfg.Database.BBolt))
+	if cfg.Database.SQL == nil {
+		cfg.Database.SQL = new(SQLConfig)
+	}
+	err = append(err, loader.Load(cfg.Database.SQL))
 
 	// Web Server
 	err = append(err, loader.Load(cfg.WebServer))
+
+	// Stats
+	if cfg.Stats == nil {
+		cfg.Stats = new(StatsConfig)
+	}
+	err = append(err, loader.Load(cfg.Stats))
+
+	// GeoIP
+	if cfg.GeoIP == nil {
+		cfg.GeoIP = new(GeoIPConfig)
+	}
+	err = append(err, loader.Load(cfg.GeoIP))
+
+	// Rate Limit
+	if cfg.RateLimit == nil {
+		cfg.RateLimit = DefaultRateLimit()
+	}
+	err = append(err, loader.Load(cfg.RateLimit))
 	return
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"log"
 	"strings"
 	"time"
@@ -18,6 +19,51 @@ func (b *BlockedHosts) Set(val string) error {
 	return nil
 }
 
+// UTMConfig -> default UTM parameters for new short urls by destination host
+type UTMConfig struct {
+	Rules []*UTMRule
+}
+
+// Set -> Set UTMConfig.Rules from a JSON array
+func (u *UTMConfig) Set(val string) error {
+	return json.Unmarshal([]byte(val), &u.Rules)
+}
+
+// UTMRule -> UTM parameters for urls to one of the Hosts.
+// "example.com" matches only the host itself, "*.example.com" all subdomains.
+type UTMRule struct {
+	Hosts    []string
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// RateLimitPolicy -> at most Max requests per Window.
+// A route group without policy is not limited.
+type RateLimitPolicy struct {
+	// Max -> requests per window and client ip, 0 means unlimited
+	Max uint64
+	// KeyMax -> requests per window and API key, Max if 0
+	KeyMax uint64
+	// Window -> length of the window (default 1m)
+	Window duration
+}
+
+// Set -> Set RateLimitPolicy from a JSON object, e. g. {"Max": 30, "KeyMax": 300, "Window": "1m"}
+func (p *RateLimitPolicy) Set(val string) error {
+	return json.Unmarshal([]byte(val), p)
+}
+
+// RateLimitKeys -> RateLimitPolicy by account id
+type RateLimitKeys map[string]*RateLimitPolicy
+
+// Set -> Set RateLimitKeys from a JSON object, e. g. {"<account id>": {"KeyMax": 1000, "Window": "1m"}}
+func (k *RateLimitKeys) Set(val string) error {
+	return json.Unmarshal([]byte(val), k)
+}
+
 type duration struct {
 	time.Duration
 }
@@ -27,3 +73,7 @@ func (d *duration) UnmarshalText(text []byte) error {
 	d.Duration, err = time.ParseDuration(string(text))
 	return err
 }
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// AccountDatabase is implemented by the PersistentDatabases to store the accounts of the API keys
+// and to list the short urls of an account
+type AccountDatabase interface {
+	// SaveAccount creates or updates the {account} (e. g. with a new KeyHash)
+	SaveAccount(account *short.Account) error
+	// DeleteAccount deletes the account, its short urls are kept
+	DeleteAccount(id *short.AccountID) error
+	FindAccount(id *short.AccountID) (*short.Account, error)
+	// FindAccountByKey returns the account with the KeyHash {keyHash} (see short.HashAPIKey)
+	FindAccountByKey(keyHash string) (*short.Account, error)
+	FindAccounts() ([]*short.Account, error)
+	// FindShortURLsByOwner returns the short urls of the {owner}, newest first.
+	// The first {offset} urls are skipped and at most {limit} urls are returned,
+	// {total} is the number of all urls of the owner.
+	FindShortURLsByOwner(owner *short.AccountID, offset, limit int) (urls []*short.ShortURL, total int, err error)
+}
+
+// MustAccounts -> the PersistentDatabase has to store accounts
+func MustAccounts(database PersistentDatabase) AccountDatabase {
+	accounts, ok := database.(AccountDatabase)
+	if !ok {
+		log.Fatalln("🚨 The persistent-database", database.ServiceName(), "doesn't support accounts")
+		return nil
+	}
+	return accounts
+}
+
+// ownerOf returns the Owner of a JSON encoded ShortURL
+func ownerOf(data []byte) short.AccountID {
+	var u struct {
+		Owner short.AccountID `json:"owner"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &u) != nil {
+		return ""
+	}
+	return u.Owner
+}
+
+// pageShortURLs sorts the {urls} newest first (like FindShortURLsByOwner) and returns the page.
+// Used by the backends which can't sort and page themselves.
+func pageShortURLs(urls []*short.ShortURL, offset, limit int) ([]*short.ShortURL, int) {
+	sort.Slice(urls, func(i, j int) bool {
+		if !urls[i].CreationDate.Equal(urls[j].CreationDate) {
+			return urls[i].CreationDate.After(urls[j].CreationDate)
+		}
+		return urls[i].ID > urls[j].ID
+	})
+	total := len(urls)
+	if offset >= total {
+		return []*short.ShortURL{}, total
+	}
+	urls = urls[offset:]
+	if limit < len(urls) {
+		urls = urls[:limit]
+	}
+	return urls, total
+}
@@ -26,8 +26,10 @@ type SharedCache struct {
 
 // NewSharedCache creates a new SharedCache object and returns it
 func NewSharedCache(pubSub PubSub) *SharedCache {
+	// AlwaysTrue accepts the first id, so there is no error
+	nodeID, _ := short.NewRandomIDGenerator(6).Generate(short.AlwaysTrue)
 	return &SharedCache{
-		NodeID: string(short.GenerateID(6, short.AlwaysTrue, 0)),
+		NodeID: string(nodeID),
 		pubSub: pubSub,
 		local:  NewLocalCache(),
 	}
@@ -2,12 +2,16 @@ package db
 
 import (
 	"context"
+	"errors"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
 	"log"
 	"time"
 )
 
+// ErrNotFound is returned by backends which don't have their own "not found" error
+var ErrNotFound = errors.New("not found")
+
 // PersistentDatabase functions
 type PersistentDatabase interface {
 	// HealthChecked
@@ -16,12 +20,38 @@ type PersistentDatabase interface {
 
 	// PersistentDatabase Functions
 	// ShortURL
+	// SaveShortenedURL saves a new ShortURL and resets its click counter
 	SaveShortenedURL(*short.ShortURL) error
 	DeleteShortenedURL(*short.ShortID) error
+	// UpdateShortenedURL saves an existing ShortURL (keeping its click counter) and removes it from the (shared) cache
+	UpdateShortenedURL(*short.ShortURL) error
 	FindShortenedURL(*short.ShortID) (*short.ShortURL, error)
 	ShortURLAvailable(*short.ShortID) bool
 
+	// Deduplication
+	// FindDuplicate returns the ShortURL with the DedupHash {hash} (reverse index).
+	// Only deduplicable urls are indexed, ErrNotFound (or the "not found" error of the backend) otherwise.
+	FindDuplicate(hash string) (*short.ShortURL, error)
+
+	// Click limit
+	// ConsumeClick atomically counts a redirect of a ShortURL with MaxClicks (even across multiple nodes).
+	// ok is false if the ShortURL was already followed {max} times.
+	ConsumeClick(id *short.ShortID, max uint64) (ok bool, err error)
+
+	// Counter
+	// IncrementCounter atomically increments the counter {name} (even across multiple nodes)
+	// and returns the new value, starting with 1. Used by the sequential and hashids id generators.
+	IncrementCounter(name string) (uint64, error)
+	// DecrementCounter atomically decreases the counter {name} by {n}, but not below 0.
+	// Used to give back values which were not used, e.g. the quota of short urls which could not be saved.
+	DecrementCounter(name string, n uint64) error
+	// ExpireCounter removes the counter {name} after {at}. Expired counters may be removed with a delay,
+	// so the counter must not be used after {at}. Used for counters of a single day, e.g. the quota of an account.
+	ExpireCounter(name string, at time.Time) error
+
 	// Expiration
+	// FindExpiredURLs returns all expired ShortURLs (see short.ShortURL.IsExpired) and all ShortURLs which reached their MaxClicks.
+	// The ActivationDate doesn't matter, pending urls are found once their ExpirationDate has passed
 	FindExpiredURLs() ([]*short.ShortURL, error)
 	GetLastExpirationCheck() *LastExpirationCheckMeta
 	UpdateLastExpirationCheck(time.Time)
@@ -35,6 +65,25 @@ type PersistentDatabase interface {
 	SavePool(*short.Pool) error
 }
 
+// BulkPersistentDatabase may be implemented by a PersistentDatabase to save many ShortURLs in one round trip
+type BulkPersistentDatabase interface {
+	SaveShortenedURLs([]*short.ShortURL) error
+}
+
+// SaveShortenedURLs saves the {urls} in one round trip if the {database} is a BulkPersistentDatabase,
+// otherwise one by one
+func SaveShortenedURLs(database PersistentDatabase, urls []*short.ShortURL) (err error) {
+	if bulk, ok := database.(BulkPersistentDatabase); ok {
+		return bulk.SaveShortenedURLs(urls)
+	}
+	for _, u := range urls {
+		if err = database.SaveShortenedURL(u); err != nil {
+			return
+		}
+	}
+	return
+}
+
 // StatsDatabase functions
 type StatsDatabase interface {
 	// HealthChecked
@@ -43,8 +92,19 @@ type StatsDatabase interface {
 
 	// StatsDatabase Functions
 	FindStats(*short.ShortID) (*short.Stats, error)
-	AddStats(*short.ShortID) error
+	AddStats(*short.ShortID, *short.Click) error
 	DeleteStats(*short.ShortID) error
+
+	// Time series
+	// FindTimeSeries returns the calls per bucket between from and to (inclusive).
+	// Buckets older than the StatsRetention are not returned.
+	FindTimeSeries(id *short.ShortID, g short.Granularity, from, to time.Time) (*short.TimeSeries, error)
+
+	// Rate limit
+	// IncrementRateLimit atomically counts a request of the rate limit {key} in the fixed window
+	// [start, start + window) (even across multiple nodes) and returns the number of requests in the window.
+	// Counters of ended windows are removed by the backend.
+	IncrementRateLimit(key string, start time.Time, window time.Duration) (uint64, error)
 }
 
 type PubSub interface {
@@ -1,17 +1,22 @@
 package db
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
 	"go.etcd.io/bbolt"
 	"log"
+	"os"
+	"sync"
 	"time"
 )
 
 // PersistentDatabase
+// StatsDatabase
 type bboltDatabase struct {
 	database              *bbolt.DB
 	cache                 DBCache
@@ -19,27 +24,94 @@ type bboltDatabase struct {
 	metaBucketName        []byte
 	tplBucketName         []byte
 	poolBucketName        []byte
+	statsBucketName       []byte
+	clicksBucketName      []byte
+	dedupBucketName       []byte
+	accountBucketName     []byte
+	rateLimitBucketName   []byte
+	rateLimitSweep        rateLimitSweeper
+	counterSweep          rateLimitSweeper
+	retention             *StatsRetention
 }
 
-// NewBBoltDatabase -> Create new BBoltDatabase
-func NewBBoltDatabase(cfg *config.BBoltConfig, cache DBCache) (bbdb PersistentDatabase, err error) {
+var (
+	bboltFilesMu sync.Mutex
+	bboltFiles   = make(map[string]*bbolt.DB)
+)
+
+// openBBolt opens every file only once, since bbolt locks the file
+// and the persistent and stats backend may use the same file
+func openBBolt(path string, mode os.FileMode) (db *bbolt.DB, err error) {
+	bboltFilesMu.Lock()
+	defer bboltFilesMu.Unlock()
+	if db, ok := bboltFiles[path]; ok {
+		return db, nil
+	}
 	// Open file {path} with permission-mode 0666
 	// 0666 = All users can read/write, but cannot execute
 	// 666 = 110 (u) 110 (g) 110 (o)
 	//       rwx     rwx     rwx
-	db, err := bbolt.Open(cfg.Path, cfg.FileMode, nil)
+	if db, err = bbolt.Open(path, mode, nil); err != nil {
+		return nil, err
+	}
+	bboltFiles[path] = db
+	return
+}
+
+func newBBoltDatabase(cfg *config.BBoltConfig, cache DBCache) (*bboltDatabase, error) {
+	db, err := openBBolt(cfg.Path, cfg.FileMode)
 	if err != nil {
 		return nil, err
 	}
-	bbdb = &bboltDatabase{
+	statsBucketName := cfg.StatsBucketName
+	if statsBucketName == "" {
+		statsBucketName = "stats"
+	}
+	clicksBucketName := cfg.ClicksBucketName
+	if clicksBucketName == "" {
+		clicksBucketName = "clicks"
+	}
+	dedupBucketName := cfg.DedupBucketName
+	if dedupBucketName == "" {
+		dedupBucketName = "dedup"
+	}
+	accountBucketName := cfg.AccountBucketName
+	if accountBucketName == "" {
+		accountBucketName = "accounts"
+	}
+	rateLimitBucketName := cfg.RateLimitBucketName
+	if rateLimitBucketName == "" {
+		rateLimitBucketName = "ratelimit"
+	}
+	return &bboltDatabase{
 		database:              db,
 		cache:                 cache,
 		shortedURLsBucketName: []byte(cfg.ShortedURLsBucketName),
 		metaBucketName:        []byte(cfg.MetaBucketName),
 		tplBucketName:         []byte(cfg.TplBucketName),
 		poolBucketName:        []byte(cfg.PoolBucketName),
+		statsBucketName:       []byte(statsBucketName),
+		clicksBucketName:      []byte(clicksBucketName),
+		dedupBucketName:       []byte(dedupBucketName),
+		accountBucketName:     []byte(accountBucketName),
+		rateLimitBucketName:   []byte(rateLimitBucketName),
+		retention:             NewStatsRetention(nil),
+	}, nil
+}
+
+// NewBBoltDatabase -> Create new BBoltDatabase
+func NewBBoltDatabase(cfg *config.BBoltConfig, cache DBCache) (PersistentDatabase, error) {
+	return newBBoltDatabase(cfg, cache)
+}
+
+// NewBBoltStats -> Use BBolt as stats backend
+func NewBBoltStats(cfg *config.BBoltConfig, stats *config.StatsConfig) (StatsDatabase, error) {
+	bdb, err := newBBoltDatabase(cfg, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	bdb.retention = NewStatsRetention(stats)
+	return bdb, nil
 }
 
 /*
@@ -70,7 +142,14 @@ func (bdb *bboltDatabase) HealthCheck(context.Context) (err error) {
  * ==================================================================================================
  */
 
-func (bdb *bboltDatabase) SaveShortenedURL(short *short.ShortURL) (err error) {
+// SaveShortenedURL saves a new {short}. The click counter is reset,
+// the id may belong to an expired url which was not removed yet.
+func (bdb *bboltDatabase) SaveShortenedURL(short *short.ShortURL) error {
+	return bdb.saveShortenedURL(short, true)
+}
+
+// saveShortenedURL stores {short} and resets its click counter if {resetClicks} is true
+func (bdb *bboltDatabase) saveShortenedURL(short *short.ShortURL, resetClicks bool) (err error) {
 	var shortAsJson []byte
 	shortAsJson, err = json.Marshal(short)
 	if err != nil {
@@ -81,8 +160,13 @@ func (bdb *bboltDatabase) SaveShortenedURL(short *short.ShortURL) (err error) {
 		if bucket, err = tx.CreateBucketIfNotExists(bdb.shortedURLsBucketName); err != nil {
 			return
 		}
-		err = bucket.Put(short.ID.Bytes(), shortAsJson)
-		log.Println("Saving Short URL", short, ":", err)
+		if err = bucket.Put(short.ID.Bytes(), shortAsJson); err == nil {
+			err = bdb.putDedup(tx, short)
+		}
+		if err == nil && resetClicks {
+			err = bdb.resetClicks(tx, &short.ID)
+		}
+		log.Println("Saving Short URL", short.ID, ":", err)
 		return
 	})
 	if err == nil {
@@ -91,13 +175,92 @@ func (bdb *bboltDatabase) SaveShortenedURL(short *short.ShortURL) (err error) {
 	return
 }
 
+// putDedup adds {short} to the reverse index if it is deduplicable
+func (bdb *bboltDatabase) putDedup(tx *bbolt.Tx, short *short.ShortURL) (err error) {
+	hash := short.DedupHash()
+	if hash == "" {
+		return
+	}
+	var bucket *bbolt.Bucket
+	if bucket, err = tx.CreateBucketIfNotExists(bdb.dedupBucketName); err != nil {
+		return
+	}
+	return bucket.Put([]byte(hash), short.ID.Bytes())
+}
+
+func (bdb *bboltDatabase) FindDuplicate(hash string) (res *short.ShortURL, err error) {
+	var id short.ShortID
+	err = bdb.database.View(func(tx *bbolt.Tx) error {
+		if bucket := tx.Bucket(bdb.dedupBucketName); bucket != nil {
+			id = short.ShortID(bucket.Get([]byte(hash)))
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if id.IsEmpty() {
+		return nil, ErrNotFound
+	}
+	return findDuplicate(bdb, id, hash)
+}
+
+// SaveShortenedURLs -> BulkPersistentDatabase, all urls are saved in one transaction
+func (bdb *bboltDatabase) SaveShortenedURLs(urls []*short.ShortURL) (err error) {
+	data := make([][]byte, len(urls))
+	for i, u := range urls {
+		if data[i], err = json.Marshal(u); err != nil {
+			return
+		}
+	}
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		var bucket *bbolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(bdb.shortedURLsBucketName); err != nil {
+			return
+		}
+		for i, u := range urls {
+			if err = bucket.Put(u.ID.Bytes(), data[i]); err != nil {
+				return
+			}
+			if err = bdb.putDedup(tx, u); err != nil {
+				return
+			}
+			if err = bdb.resetClicks(tx, &u.ID); err != nil {
+				return
+			}
+		}
+		log.Println("Saving", len(urls), "Short URLs")
+		return
+	})
+	if err != nil {
+		return
+	}
+	for _, u := range urls {
+		if err = bdb.cache.UpdateCache(u); err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (bdb *bboltDatabase) DeleteShortenedURL(id *short.ShortID) (err error) {
 	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
 		var bucket *bbolt.Bucket
 		if bucket, err = tx.CreateBucketIfNotExists(bdb.shortedURLsBucketName); err != nil {
 			return
 		}
-		err = bucket.Delete(id.Bytes())
+		// remove the reverse index entry of the url
+		if dedup := tx.Bucket(bdb.dedupBucketName); dedup != nil {
+			if hash := dedupHashOf(bucket.Get(id.Bytes())); hash != "" && string(dedup.Get([]byte(hash))) == id.String() {
+				if err = dedup.Delete([]byte(hash)); err != nil {
+					return
+				}
+			}
+		}
+		if err = bucket.Delete(id.Bytes()); err != nil {
+			return
+		}
+		err = bdb.resetClicks(tx, id)
 		log.Println("Deleting Short URL #", id, ":", err)
 		return
 	})
@@ -107,6 +270,23 @@ func (bdb *bboltDatabase) DeleteShortenedURL(id *short.ShortID) (err error) {
 	return
 }
 
+// resetClicks removes the click counter of {id}
+func (bdb *bboltDatabase) resetClicks(tx *bbolt.Tx, id *short.ShortID) (err error) {
+	if clicks := tx.Bucket(bdb.clicksBucketName); clicks != nil {
+		err = clicks.Delete(id.Bytes())
+	}
+	return
+}
+
+// UpdateShortenedURL saves the changed {short} (keeping its clicks) and breaks the cache of all nodes
+func (bdb *bboltDatabase) UpdateShortenedURL(short *short.ShortURL) (err error) {
+	if err = bdb.saveShortenedURL(short, false); err != nil {
+		return
+	}
+	err = bdb.cache.BreakCache(&short.ID)
+	return
+}
+
 func (bdb *bboltDatabase) FindShortenedURL(id *short.ShortID) (res *short.ShortURL, err error) {
 	// check cache
 	if u := bdb.cache.GetShortURL(id); u != nil {
@@ -119,18 +299,23 @@ func (bdb *bboltDatabase) FindShortenedURL(id *short.ShortID) (res *short.ShortU
 		if bucket == nil {
 			return
 		}
-		content = bucket.Get(id.Bytes())
-		log.Println("BBolt :: Content =", string(content))
+		// the value is only valid during the transaction
+		if v := bucket.Get(id.Bytes()); v != nil {
+			content = append([]byte{}, v...)
+		}
 		return
 	})
 	if err != nil {
 		return
 	}
+	if content == nil {
+		return nil, ErrNotFound
+	}
 
-	err = json.Unmarshal(content, &res)
-	if err == nil {
-		err = bdb.cache.UpdateCache(res)
+	if err = json.Unmarshal(content, &res); err != nil {
+		return nil, err
 	}
+	err = bdb.cache.UpdateCache(res)
 
 	return
 }
@@ -148,14 +333,128 @@ func (bdb *bboltDatabase) ShortURLAvailable(id *short.ShortID) bool {
  * ==================================================================================================
  */
 
+// bboltClicks returns the click counter of {id}
+func bboltClicks(bucket *bbolt.Bucket, id *short.ShortID) uint64 {
+	if v := bucket.Get(id.Bytes()); len(v) == 8 {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (bdb *bboltDatabase) ConsumeClick(id *short.ShortID, max uint64) (ok bool, err error) {
+	// bbolt only allows one writer at a time, so the update is atomic
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		var bucket *bbolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(bdb.clicksBucketName); err != nil {
+			return
+		}
+		clicks := bboltClicks(bucket, id)
+		if clicks >= max {
+			return
+		}
+		ok = true
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, clicks+1)
+		err = bucket.Put(id.Bytes(), v)
+		return
+	})
+	return
+}
+
+func (bdb *bboltDatabase) IncrementCounter(name string) (n uint64, err error) {
+	// bbolt only allows one writer at a time, so the update is atomic
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		var bucket *bbolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(bdb.metaBucketName); err != nil {
+			return
+		}
+		if err = bdb.sweepCounters(bucket, time.Now()); err != nil {
+			return
+		}
+		key := []byte("counter::" + name)
+		if v := bucket.Get(key); len(v) == 8 {
+			n = binary.BigEndian.Uint64(v)
+		}
+		n++
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, n)
+		return bucket.Put(key, v)
+	})
+	return
+}
+
+func (bdb *bboltDatabase) DecrementCounter(name string, n uint64) error {
+	return bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.metaBucketName)
+		if bucket == nil {
+			return
+		}
+		key := []byte("counter::" + name)
+		v := bucket.Get(key)
+		if len(v) != 8 {
+			return
+		}
+		value := binary.BigEndian.Uint64(v)
+		if value < n {
+			n = value
+		}
+		v = make([]byte, 8)
+		binary.BigEndian.PutUint64(v, value-n)
+		return bucket.Put(key, v)
+	})
+}
+
+// bboltCounterExpirationPrefix -> prefix of the expiration dates of the counters in the meta bucket
+const bboltCounterExpirationPrefix = "counter-expiration::"
+
+func (bdb *bboltDatabase) ExpireCounter(name string, at time.Time) error {
+	return bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.metaBucketName)
+		if bucket == nil || bucket.Get([]byte("counter::"+name)) == nil {
+			return
+		}
+		if err = bdb.sweepCounters(bucket, time.Now()); err != nil {
+			return
+		}
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, uint64(at.UnixNano()))
+		return bucket.Put([]byte(bboltCounterExpirationPrefix+name), v)
+	})
+}
+
+// sweepCounters removes the expired counters of the meta bucket, at most once per rateLimitSweepInterval
+func (bdb *bboltDatabase) sweepCounters(bucket *bbolt.Bucket, now time.Time) (err error) {
+	if !bdb.counterSweep.due(now) {
+		return
+	}
+	// keys can't be deleted while iterating
+	var expired []string
+	prefix := []byte(bboltCounterExpirationPrefix)
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if len(v) == 8 && int64(binary.BigEndian.Uint64(v)) <= now.UnixNano() {
+			expired = append(expired, string(k[len(prefix):]))
+		}
+	}
+	for _, name := range expired {
+		if err = bucket.Delete([]byte("counter::" + name)); err != nil {
+			return
+		}
+		if err = bucket.Delete([]byte(bboltCounterExpirationPrefix + name)); err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (bdb *bboltDatabase) FindExpiredURLs() (res []*short.ShortURL, err error) {
-	now := time.Now()
 	err = bdb.database.View(func(tx *bbolt.Tx) (err error) {
 		bucket := tx.Bucket(bdb.shortedURLsBucketName)
 		if bucket == nil {
 			return
 		}
 
+		clicks := tx.Bucket(bdb.clicksBucketName)
 		err = bucket.ForEach(func(_, v []byte) (err error) {
 			var sh *short.ShortURL
 			err = json.Unmarshal(v, &sh)
@@ -163,9 +462,12 @@ func (bdb *bboltDatabase) FindExpiredURLs() (res []*short.ShortURL, err error) {
 				return
 			}
 			// check if expired
-			if sh.ExpirationDate != nil && sh.ExpirationDate.Before(now) {
+			if sh.IsExpired() {
 				// add to result
 				res = append(res, sh)
+			} else if sh.IsClickLimited() && clicks != nil && bboltClicks(clicks, &sh.ID) >= sh.MaxClicks {
+				// exhausted
+				res = append(res, sh)
 			}
 			return
 		})
@@ -184,7 +486,7 @@ func (bdb *bboltDatabase) GetLastExpirationCheck() (m *LastExpirationCheckMeta)
 		if bucket == nil {
 			return
 		}
-		content = bucket.Get([]byte("last_expired"))
+		content = append([]byte{}, bucket.Get([]byte("last_expired"))...)
 		return
 	}); err != nil {
 		return
@@ -280,6 +582,9 @@ func (bdb *bboltDatabase) FindPool(id *short.PoolID) (pool *short.Pool, err erro
 		err = json.Unmarshal(res, pool)
 		return
 	})
+	if err == nil && pool == nil {
+		err = ErrNotFound
+	}
 	return
 }
 
@@ -299,3 +604,232 @@ func (bdb *bboltDatabase) SavePool(pool *short.Pool) (err error) {
 	})
 	return
 }
+
+/*
+ * ==================================================================================================
+ *                          A C C O U N T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (bdb *bboltDatabase) SaveAccount(account *short.Account) (err error) {
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		var bucket *bbolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(bdb.accountBucketName); err != nil {
+			return
+		}
+		var val []byte
+		if val, err = json.Marshal(account); err != nil {
+			return
+		}
+		err = bucket.Put(account.ID.Bytes(), val)
+		return
+	})
+	return
+}
+
+func (bdb *bboltDatabase) DeleteAccount(id *short.AccountID) (err error) {
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.accountBucketName)
+		if bucket == nil {
+			return
+		}
+		err = bucket.Delete(id.Bytes())
+		return
+	})
+	return
+}
+
+func (bdb *bboltDatabase) FindAccount(id *short.AccountID) (account *short.Account, err error) {
+	err = bdb.database.View(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.accountBucketName)
+		if bucket == nil {
+			return
+		}
+		res := bucket.Get(id.Bytes())
+		if res == nil {
+			return
+		}
+		err = json.Unmarshal(res, &account)
+		return
+	})
+	if err == nil && account == nil {
+		err = ErrNotFound
+	}
+	return
+}
+
+// FindAccountByKey scans all accounts, there are only a few accounts per instance
+func (bdb *bboltDatabase) FindAccountByKey(keyHash string) (*short.Account, error) {
+	accounts, err := bdb.FindAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		if a.KeyHash == keyHash {
+			return a, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (bdb *bboltDatabase) FindAccounts() (accounts []*short.Account, err error) {
+	err = bdb.database.View(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.accountBucketName)
+		if bucket == nil {
+			return
+		}
+		err = bucket.ForEach(func(_, v []byte) (err error) {
+			var a *short.Account
+			if err = json.Unmarshal(v, &a); err != nil {
+				return
+			}
+			accounts = append(accounts, a)
+			return
+		})
+		return
+	})
+	return
+}
+
+// FindShortURLsByOwner scans all short urls (like FindExpiredURLs), only the owner is decoded of foreign urls
+func (bdb *bboltDatabase) FindShortURLsByOwner(owner *short.AccountID, offset, limit int) (urls []*short.ShortURL,
+	total int, err error) {
+	err = bdb.database.View(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.shortedURLsBucketName)
+		if bucket == nil {
+			return
+		}
+		err = bucket.ForEach(func(_, v []byte) (err error) {
+			if ownerOf(v) != *owner {
+				return
+			}
+			var u *short.ShortURL
+			if err = json.Unmarshal(v, &u); err != nil {
+				return
+			}
+			urls = append(urls, u)
+			return
+		})
+		return
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	urls, total = pageShortURLs(urls, offset, limit)
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            S T A T S   D A T A B A S E
+ * ==================================================================================================
+ */
+
+func (bdb *bboltDatabase) findStatsRecord(id *short.ShortID) (record *statsRecord, err error) {
+	record = new(statsRecord)
+	err = bdb.database.View(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.statsBucketName)
+		if bucket == nil {
+			return
+		}
+		if v := bucket.Get(id.Bytes()); v != nil {
+			err = json.Unmarshal(v, record)
+		}
+		return
+	})
+	return
+}
+
+func (bdb *bboltDatabase) FindStats(id *short.ShortID) (stats *short.Stats, err error) {
+	var record *statsRecord
+	if record, err = bdb.findStatsRecord(id); err != nil {
+		return
+	}
+	stats = record.stats(time.Now())
+	return
+}
+
+func (bdb *bboltDatabase) FindTimeSeries(id *short.ShortID, g short.Granularity,
+	from, to time.Time) (ts *short.TimeSeries, err error) {
+	var record *statsRecord
+	if record, err = bdb.findStatsRecord(id); err != nil {
+		return
+	}
+	record.prune(time.Now(), bdb.retention)
+	ts = record.timeSeries(g, from, to, bdb.retention)
+	return
+}
+
+func (bdb *bboltDatabase) AddStats(id *short.ShortID, click *short.Click) (err error) {
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		var bucket *bbolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(bdb.statsBucketName); err != nil {
+			return
+		}
+		record := new(statsRecord)
+		if v := bucket.Get(id.Bytes()); v != nil {
+			if err = json.Unmarshal(v, record); err != nil {
+				return
+			}
+		}
+		record.add(click, bdb.retention)
+		var data []byte
+		if data, err = json.Marshal(record); err != nil {
+			return
+		}
+		err = bucket.Put(id.Bytes(), data)
+		return
+	})
+	return
+}
+
+func (bdb *bboltDatabase) DeleteStats(id *short.ShortID) (err error) {
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		bucket := tx.Bucket(bdb.statsBucketName)
+		if bucket == nil {
+			return
+		}
+		err = bucket.Delete(id.Bytes())
+		return
+	})
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            R A T E   L I M I T
+ * ==================================================================================================
+ */
+
+func (bdb *bboltDatabase) IncrementRateLimit(key string, start time.Time, window time.Duration) (hits uint64, err error) {
+	// bbolt is only used by a single node, the update transaction makes the increment atomic
+	err = bdb.database.Update(func(tx *bbolt.Tx) (err error) {
+		var bucket *bbolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(bdb.rateLimitBucketName); err != nil {
+			return
+		}
+		if now := time.Now(); bdb.rateLimitSweep.due(now) {
+			// keys can't be deleted while iterating
+			var ended [][]byte
+			err = bucket.ForEach(func(k, v []byte) error {
+				if w := parseRateLimitWindow(v); w.ended(now) {
+					ended = append(ended, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return
+			}
+			for _, k := range ended {
+				if err = bucket.Delete(k); err != nil {
+					return
+				}
+			}
+		}
+		w := parseRateLimitWindow(bucket.Get([]byte(key)))
+		hits = w.hit(start, window)
+		err = bucket.Put([]byte(key), w.bytes())
+		return
+	})
+	return
+}
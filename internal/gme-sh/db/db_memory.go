@@ -0,0 +1,592 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
+)
+
+// PersistentDatabase
+// StatsDatabase
+// PubSub
+//
+// memoryDB keeps everything in the memory of the running process.
+// Objects are stored JSON encoded, so modifying a returned object does not modify the stored one.
+// Nothing survives a restart, so it should only be used for development, demos and tests.
+type memoryDB struct {
+	cache     DBCache
+	retention *StatsRetention
+
+	mu                  sync.RWMutex
+	shortURLs           map[string][]byte
+	clicks              map[string]uint64
+	counters            map[string]uint64
+	counterExpirations  map[string]time.Time
+	counterSweep        rateLimitSweeper
+	dedup               map[string]string
+	templates           map[string][]byte
+	pools               map[string][]byte
+	accounts            map[string][]byte
+	stats               map[string]*statsRecord
+	rateLimits          map[string]*rateLimitWindow
+	rateLimitSweep      rateLimitSweeper
+	lastExpirationCheck *LastExpirationCheckMeta
+
+	subMu       sync.RWMutex
+	subscribers map[*memorySubscriber]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+type memorySubscriber struct {
+	channels map[string]struct{}
+	messages chan [2]string
+}
+
+func newMemoryDB(cache DBCache) *memoryDB {
+	return &memoryDB{
+		cache:              cache,
+		retention:          NewStatsRetention(nil),
+		shortURLs:          make(map[string][]byte),
+		clicks:             make(map[string]uint64),
+		counters:           make(map[string]uint64),
+		counterExpirations: make(map[string]time.Time),
+		dedup:              make(map[string]string),
+		templates:          make(map[string][]byte),
+		pools:              make(map[string][]byte),
+		accounts:           make(map[string][]byte),
+		stats:              make(map[string]*statsRecord),
+		rateLimits:         make(map[string]*rateLimitWindow),
+		subscribers:        make(map[*memorySubscriber]struct{}),
+		closed:             make(chan struct{}),
+	}
+}
+
+// NewMemoryDatabase -> Use the memory of the running process as persistent backend
+func NewMemoryDatabase(cache DBCache) (PersistentDatabase, error) {
+	return newMemoryDB(cache), nil
+}
+
+// NewMemoryStats -> Use the memory of the running process as stats backend
+func NewMemoryStats(cfg *config.StatsConfig) (StatsDatabase, error) {
+	mem := newMemoryDB(nil)
+	mem.retention = NewStatsRetention(cfg)
+	return mem, nil
+}
+
+// NewMemoryPubSub -> Use the memory of the running process as pubsub backend.
+// Messages are only delivered to subscribers within the same process.
+func NewMemoryPubSub() (PubSub, error) {
+	return newMemoryDB(nil), nil
+}
+
+/*
+ * ==================================================================================================
+ *                          D E F A U L T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (*memoryDB) ServiceName() string {
+	return "Memory"
+}
+
+func (*memoryDB) HealthCheck(context.Context) error {
+	return nil
+}
+
+/*
+ * ==================================================================================================
+ *                            P E R M A N E N T  D A T A B A S E
+ * ==================================================================================================
+ */
+
+// SaveShortenedURL saves a new {short}. The click counter is reset,
+// the id may belong to an expired url which was not removed yet.
+func (mem *memoryDB) SaveShortenedURL(short *short.ShortURL) error {
+	return mem.saveShortenedURL(short, true)
+}
+
+// saveShortenedURL stores {short} and resets its click counter if {resetClicks} is true
+func (mem *memoryDB) saveShortenedURL(short *short.ShortURL, resetClicks bool) (err error) {
+	var data []byte
+	if data, err = json.Marshal(short); err != nil {
+		return
+	}
+	mem.mu.Lock()
+	mem.shortURLs[short.ID.String()] = data
+	if resetClicks {
+		delete(mem.clicks, short.ID.String())
+	}
+	if hash := short.DedupHash(); hash != "" {
+		mem.dedup[hash] = short.ID.String()
+	}
+	mem.mu.Unlock()
+	if mem.cache != nil {
+		err = mem.cache.UpdateCache(short)
+	}
+	return
+}
+
+// SaveShortenedURLs -> BulkPersistentDatabase
+func (mem *memoryDB) SaveShortenedURLs(urls []*short.ShortURL) (err error) {
+	data := make([][]byte, len(urls))
+	for i, u := range urls {
+		if data[i], err = json.Marshal(u); err != nil {
+			return
+		}
+	}
+	mem.mu.Lock()
+	for i, u := range urls {
+		mem.shortURLs[u.ID.String()] = data[i]
+		delete(mem.clicks, u.ID.String())
+		if hash := u.DedupHash(); hash != "" {
+			mem.dedup[hash] = u.ID.String()
+		}
+	}
+	mem.mu.Unlock()
+	if mem.cache != nil {
+		for _, u := range urls {
+			if err = mem.cache.UpdateCache(u); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (mem *memoryDB) DeleteShortenedURL(id *short.ShortID) (err error) {
+	mem.mu.Lock()
+	if hash := dedupHashOf(mem.shortURLs[id.String()]); mem.dedup[hash] == id.String() {
+		delete(mem.dedup, hash)
+	}
+	delete(mem.shortURLs, id.String())
+	delete(mem.clicks, id.String())
+	mem.mu.Unlock()
+	if mem.cache != nil {
+		err = mem.cache.BreakCache(id)
+	}
+	return
+}
+
+// UpdateShortenedURL saves the changed {short} (keeping its clicks) and breaks the cache of all nodes
+func (mem *memoryDB) UpdateShortenedURL(short *short.ShortURL) (err error) {
+	if err = mem.saveShortenedURL(short, false); err != nil {
+		return
+	}
+	if mem.cache != nil {
+		err = mem.cache.BreakCache(&short.ID)
+	}
+	return
+}
+
+func (mem *memoryDB) FindDuplicate(hash string) (res *short.ShortURL, err error) {
+	mem.mu.RLock()
+	id, ok := mem.dedup[hash]
+	mem.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return findDuplicate(mem, short.ShortID(id), hash)
+}
+
+func (mem *memoryDB) FindShortenedURL(id *short.ShortID) (res *short.ShortURL, err error) {
+	if mem.cache != nil {
+		if u := mem.cache.GetShortURL(id); u != nil {
+			return u, nil
+		}
+	}
+	mem.mu.RLock()
+	data, ok := mem.shortURLs[id.String()]
+	mem.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	if mem.cache != nil {
+		err = mem.cache.UpdateCache(res)
+	}
+	return
+}
+
+func (mem *memoryDB) ShortURLAvailable(id *short.ShortID) bool {
+	return shortURLAvailable(mem, id)
+}
+
+/*
+ * ==================================================================================================
+ *                          E X P I R A T I O N   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) ConsumeClick(id *short.ShortID, max uint64) (ok bool, err error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if mem.clicks[id.String()] >= max {
+		return false, nil
+	}
+	mem.clicks[id.String()]++
+	return true, nil
+}
+
+func (mem *memoryDB) IncrementCounter(name string) (uint64, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.sweepCounters(time.Now())
+	mem.counters[name]++
+	return mem.counters[name], nil
+}
+
+func (mem *memoryDB) DecrementCounter(name string, n uint64) error {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if _, ok := mem.counters[name]; !ok {
+		return nil
+	}
+	if mem.counters[name] < n {
+		n = mem.counters[name]
+	}
+	mem.counters[name] -= n
+	return nil
+}
+
+func (mem *memoryDB) ExpireCounter(name string, at time.Time) error {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.sweepCounters(time.Now())
+	if _, ok := mem.counters[name]; ok {
+		mem.counterExpirations[name] = at
+	}
+	return nil
+}
+
+// sweepCounters removes the expired counters, at most once per rateLimitSweepInterval.
+// mem.mu must be locked.
+func (mem *memoryDB) sweepCounters(now time.Time) {
+	if !mem.counterSweep.due(now) {
+		return
+	}
+	for name, at := range mem.counterExpirations {
+		if !at.After(now) {
+			delete(mem.counters, name)
+			delete(mem.counterExpirations, name)
+		}
+	}
+}
+
+func (mem *memoryDB) FindExpiredURLs() (res []*short.ShortURL, err error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, data := range mem.shortURLs {
+		var sh *short.ShortURL
+		if err = json.Unmarshal(data, &sh); err != nil {
+			return nil, err
+		}
+		if sh.IsExpired() || (sh.IsClickLimited() && mem.clicks[sh.ID.String()] >= sh.MaxClicks) {
+			res = append(res, sh)
+		}
+	}
+	return
+}
+
+func (mem *memoryDB) GetLastExpirationCheck() *LastExpirationCheckMeta {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	if mem.lastExpirationCheck == nil {
+		return &LastExpirationCheckMeta{
+			LastCheck: time.Unix(5, 0),
+		}
+	}
+	return &LastExpirationCheckMeta{
+		LastCheck: mem.lastExpirationCheck.LastCheck,
+	}
+}
+
+func (mem *memoryDB) UpdateLastExpirationCheck(t time.Time) {
+	mem.mu.Lock()
+	mem.lastExpirationCheck = &LastExpirationCheckMeta{
+		LastCheck: t,
+	}
+	mem.mu.Unlock()
+}
+
+/*
+ * ==================================================================================================
+ *                          T E M P L A T E   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) FindTemplates() (templates []*tpl.Template, err error) {
+	templates = []*tpl.Template{}
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, data := range mem.templates {
+		t := new(tpl.Template)
+		if err = json.Unmarshal(data, t); err != nil {
+			return
+		}
+		templates = append(templates, t)
+	}
+	return
+}
+
+func (mem *memoryDB) SaveTemplate(t *tpl.Template) (err error) {
+	var data []byte
+	if data, err = json.Marshal(t); err != nil {
+		return
+	}
+	mem.mu.Lock()
+	mem.templates[t.TemplateURL] = data
+	mem.mu.Unlock()
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                             P O O L   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) FindPool(id *short.PoolID) (pool *short.Pool, err error) {
+	mem.mu.RLock()
+	data, ok := mem.pools[id.String()]
+	mem.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	pool = new(short.Pool)
+	if err = json.Unmarshal(data, pool); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (mem *memoryDB) SavePool(pool *short.Pool) (err error) {
+	var data []byte
+	if data, err = json.Marshal(pool); err != nil {
+		return
+	}
+	mem.mu.Lock()
+	mem.pools[pool.ID.String()] = data
+	mem.mu.Unlock()
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                          A C C O U N T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) SaveAccount(account *short.Account) (err error) {
+	var data []byte
+	if data, err = json.Marshal(account); err != nil {
+		return
+	}
+	mem.mu.Lock()
+	mem.accounts[account.ID.String()] = data
+	mem.mu.Unlock()
+	return
+}
+
+func (mem *memoryDB) DeleteAccount(id *short.AccountID) (err error) {
+	mem.mu.Lock()
+	delete(mem.accounts, id.String())
+	mem.mu.Unlock()
+	return
+}
+
+func (mem *memoryDB) FindAccount(id *short.AccountID) (account *short.Account, err error) {
+	mem.mu.RLock()
+	data, ok := mem.accounts[id.String()]
+	mem.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	err = json.Unmarshal(data, &account)
+	return
+}
+
+func (mem *memoryDB) FindAccountByKey(keyHash string) (*short.Account, error) {
+	accounts, err := mem.FindAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		if a.KeyHash == keyHash {
+			return a, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (mem *memoryDB) FindAccounts() (accounts []*short.Account, err error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	for _, data := range mem.accounts {
+		var a *short.Account
+		if err = json.Unmarshal(data, &a); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return
+}
+
+func (mem *memoryDB) FindShortURLsByOwner(owner *short.AccountID, offset, limit int) (urls []*short.ShortURL,
+	total int, err error) {
+	mem.mu.RLock()
+	for _, data := range mem.shortURLs {
+		if ownerOf(data) != *owner {
+			continue
+		}
+		var u *short.ShortURL
+		if err = json.Unmarshal(data, &u); err != nil {
+			mem.mu.RUnlock()
+			return nil, 0, err
+		}
+		urls = append(urls, u)
+	}
+	mem.mu.RUnlock()
+	urls, total = pageShortURLs(urls, offset, limit)
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            S T A T S   D A T A B A S E
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) FindStats(id *short.ShortID) (stats *short.Stats, err error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	record, ok := mem.stats[id.String()]
+	if !ok {
+		record = new(statsRecord)
+	}
+	stats = record.stats(time.Now())
+	return
+}
+
+func (mem *memoryDB) AddStats(id *short.ShortID, click *short.Click) (err error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	record, ok := mem.stats[id.String()]
+	if !ok {
+		record = new(statsRecord)
+		mem.stats[id.String()] = record
+	}
+	record.add(click, mem.retention)
+	return
+}
+
+func (mem *memoryDB) FindTimeSeries(id *short.ShortID, g short.Granularity,
+	from, to time.Time) (ts *short.TimeSeries, err error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	record, ok := mem.stats[id.String()]
+	if !ok {
+		record = new(statsRecord)
+	}
+	record.prune(time.Now(), mem.retention)
+	ts = record.timeSeries(g, from, to, mem.retention)
+	return
+}
+
+func (mem *memoryDB) DeleteStats(id *short.ShortID) (err error) {
+	mem.mu.Lock()
+	delete(mem.stats, id.String())
+	mem.mu.Unlock()
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            R A T E   L I M I T
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) IncrementRateLimit(key string, start time.Time, window time.Duration) (uint64, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	if now := time.Now(); mem.rateLimitSweep.due(now) {
+		for k, w := range mem.rateLimits {
+			if w.ended(now) {
+				delete(mem.rateLimits, k)
+			}
+		}
+	}
+	w, ok := mem.rateLimits[key]
+	if !ok {
+		w = new(rateLimitWindow)
+		mem.rateLimits[key] = w
+	}
+	return w.hit(start, window), nil
+}
+
+/*
+ * ==================================================================================================
+ *                                       P U B S U B
+ * ==================================================================================================
+ */
+
+func (mem *memoryDB) Publish(channel, msg string) (err error) {
+	mem.subMu.RLock()
+	defer mem.subMu.RUnlock()
+	for sub := range mem.subscribers {
+		if _, ok := sub.channels[channel]; !ok {
+			continue
+		}
+		select {
+		case sub.messages <- [2]string{channel, msg}:
+		case <-mem.closed:
+			return
+		}
+	}
+	return
+}
+
+// Subscribe blocks and calls {c} for every message published to one of the {channels}
+// until the PubSub is closed
+func (mem *memoryDB) Subscribe(c func(channel, payload string), channels ...string) (err error) {
+	sub := &memorySubscriber{
+		channels: make(map[string]struct{}),
+		messages: make(chan [2]string, 64),
+	}
+	for _, ch := range channels {
+		sub.channels[ch] = struct{}{}
+	}
+
+	mem.subMu.Lock()
+	mem.subscribers[sub] = struct{}{}
+	mem.subMu.Unlock()
+
+	defer func() {
+		mem.subMu.Lock()
+		delete(mem.subscribers, sub)
+		mem.subMu.Unlock()
+	}()
+
+	for {
+		select {
+		case msg := <-sub.messages:
+			c(msg[0], msg[1])
+		case <-mem.closed:
+			return
+		}
+	}
+}
+
+func (mem *memoryDB) Close() (err error) {
+	mem.closeOnce.Do(func() {
+		close(mem.closed)
+	})
+	return
+}
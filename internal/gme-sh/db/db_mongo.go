@@ -8,26 +8,32 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // PersistentDatabase
+// StatsDatabase
 type mongoDatabase struct {
-	client             *mongo.Client
-	context            context.Context
-	cache              DBCache
-	database           string
-	shortURLCollection string
-	metaCollection     string
-	tplCollection      string
-	poolCollection     string
+	client              *mongo.Client
+	context             context.Context
+	cache               DBCache
+	database            string
+	shortURLCollection  string
+	metaCollection      string
+	tplCollection       string
+	poolCollection      string
+	statsCollection     string
+	dedupCollection     string
+	accountCollection   string
+	rateLimitCollection string
+	retention           *StatsRetention
 }
 
 var updateOptions = options.Update().SetUpsert(true)
 
-// NewMongoDatabase -> Creates a new implementation of PersistentDatabase (mongodb),
-// connects, and returns it
-func NewMongoDatabase(cfg *config.MongoConfig, cache DBCache) (db PersistentDatabase, err error) {
+func newMongoDatabase(cfg *config.MongoConfig, cache DBCache) (*mongoDatabase, error) {
 	// create client
 	opts := options.Client().ApplyURI(cfg.ApplyURI)
 	client, err := mongo.NewClient(opts)
@@ -39,21 +45,88 @@ func NewMongoDatabase(cfg *config.MongoConfig, cache DBCache) (db PersistentData
 	ctx := context.TODO()
 	err = client.Connect(ctx)
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	statsCollection := cfg.StatsCollection
+	if statsCollection == "" {
+		statsCollection = "stats"
+	}
+
+	dedupCollection := cfg.DedupCollection
+	if dedupCollection == "" {
+		dedupCollection = "dedup"
+	}
+
+	accountCollection := cfg.AccountCollection
+	if accountCollection == "" {
+		accountCollection = "accounts"
+	}
+
+	rateLimitCollection := cfg.RateLimitCollection
+	if rateLimitCollection == "" {
+		rateLimitCollection = "ratelimit"
 	}
 
 	return &mongoDatabase{
-		client:             client,
-		context:            ctx,
-		database:           cfg.Database,
-		shortURLCollection: cfg.ShortURLCollection,
-		metaCollection:     cfg.MetaCollection,
-		tplCollection:      cfg.TplCollection,
-		poolCollection:     cfg.PoolCollection,
-		cache:              cache,
+		client:              client,
+		context:             ctx,
+		database:            cfg.Database,
+		shortURLCollection:  cfg.ShortURLCollection,
+		metaCollection:      cfg.MetaCollection,
+		tplCollection:       cfg.TplCollection,
+		poolCollection:      cfg.PoolCollection,
+		statsCollection:     statsCollection,
+		dedupCollection:     dedupCollection,
+		accountCollection:   accountCollection,
+		rateLimitCollection: rateLimitCollection,
+		retention:           NewStatsRetention(nil),
+		cache:               cache,
 	}, nil
 }
 
+// NewMongoDatabase -> Creates a new implementation of PersistentDatabase (mongodb),
+// connects, and returns it
+func NewMongoDatabase(cfg *config.MongoConfig, cache DBCache) (PersistentDatabase, error) {
+	mdb, err := newMongoDatabase(cfg, cache)
+	if err != nil {
+		return nil, err
+	}
+	// accounts are found by the hash of their API key, so two accounts must never share one
+	if _, err = mdb.accounts().Indexes().CreateOne(mdb.context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return nil, err
+	}
+	// expired counters are removed by mongodb
+	if _, err = mdb.meta().Indexes().CreateOne(mdb.context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}); err != nil {
+		return nil, err
+	}
+	return mdb, nil
+}
+
+// NewMongoStats -> Creates a new implementation of StatsDatabase (mongodb),
+// connects, and returns it
+func NewMongoStats(cfg *config.MongoConfig, stats *config.StatsConfig) (StatsDatabase, error) {
+	mdb, err := newMongoDatabase(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	mdb.retention = NewStatsRetention(stats)
+	// ended rate limit windows are removed by mongodb
+	if _, err = mdb.rateLimits().Indexes().CreateOne(mdb.context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}); err != nil {
+		return nil, err
+	}
+	return mdb, nil
+}
+
 ////
 
 func (mdb *mongoDatabase) shortURLs() *mongo.Collection {
@@ -71,6 +144,23 @@ func (mdb *mongoDatabase) pool() *mongo.Collection {
 	return mdb.client.Database(mdb.database).Collection(mdb.poolCollection)
 }
 
+func (mdb *mongoDatabase) stats() *mongo.Collection {
+	return mdb.client.Database(mdb.database).Collection(mdb.statsCollection)
+}
+
+// dedup holds the reverse index: {_id: DedupHash, id: ShortID}
+func (mdb *mongoDatabase) dedup() *mongo.Collection {
+	return mdb.client.Database(mdb.database).Collection(mdb.dedupCollection)
+}
+
+func (mdb *mongoDatabase) accounts() *mongo.Collection {
+	return mdb.client.Database(mdb.database).Collection(mdb.accountCollection)
+}
+
+func (mdb *mongoDatabase) rateLimits() *mongo.Collection {
+	return mdb.client.Database(mdb.database).Collection(mdb.rateLimitCollection)
+}
+
 /*
  * ==================================================================================================
  *                          D E F A U L T   I M P L E M E N T A T I O N S
@@ -92,13 +182,30 @@ func (mdb *mongoDatabase) HealthCheck(ctx context.Context) (err error) {
  * ==================================================================================================
  */
 
-func (mdb *mongoDatabase) SaveShortenedURL(short *short.ShortURL) (err error) {
+// SaveShortenedURL saves a new {short}. The click counter is reset,
+// the id may belong to an expired url which was not removed yet.
+func (mdb *mongoDatabase) SaveShortenedURL(short *short.ShortURL) error {
+	return mdb.saveShortenedURL(short, mongoNewShortURLUpdate(short))
+}
+
+// mongoNewShortURLUpdate sets {short} and removes the click counter from its document
+func mongoNewShortURLUpdate(short *short.ShortURL) bson.M {
+	update := short.BsonUpdate()
+	update["$unset"] = bson.M{"clicks": ""}
+	return update
+}
+
+// saveShortenedURL upserts {short} with the {update}
+func (mdb *mongoDatabase) saveShortenedURL(short *short.ShortURL, update bson.M) (err error) {
 	_, err = mdb.shortURLs().UpdateOne(
 		mdb.context,
 		short.ID.BsonFilter(),
-		short.BsonUpdate(),
+		update,
 		updateOptions,
 	)
+	if hash := short.DedupHash(); err == nil && hash != "" {
+		_, err = mdb.dedup().UpdateOne(mdb.context, bson.M{"_id": hash}, mongoDedupUpdate(short), updateOptions)
+	}
 	// Now we save/replace the object to the cache to spare the Mongo database.
 	if err == nil {
 		err = mdb.cache.UpdateCache(short)
@@ -106,9 +213,48 @@ func (mdb *mongoDatabase) SaveShortenedURL(short *short.ShortURL) (err error) {
 	return
 }
 
+// SaveShortenedURLs -> BulkPersistentDatabase, all urls are saved with one bulk write
+func (mdb *mongoDatabase) SaveShortenedURLs(urls []*short.ShortURL) (err error) {
+	if len(urls) == 0 {
+		return
+	}
+	models := make([]mongo.WriteModel, len(urls))
+	var dedup []mongo.WriteModel
+	for i, u := range urls {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(u.ID.BsonFilter()).
+			SetUpdate(mongoNewShortURLUpdate(u)).
+			SetUpsert(true)
+		if hash := u.DedupHash(); hash != "" {
+			dedup = append(dedup, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": hash}).
+				SetUpdate(mongoDedupUpdate(u)).
+				SetUpsert(true))
+		}
+	}
+	if _, err = mdb.shortURLs().BulkWrite(mdb.context, models); err != nil {
+		return
+	}
+	if len(dedup) > 0 {
+		if _, err = mdb.dedup().BulkWrite(mdb.context, dedup); err != nil {
+			return
+		}
+	}
+	for _, u := range urls {
+		if err = mdb.cache.UpdateCache(u); err != nil {
+			return
+		}
+	}
+	return
+}
+
 func (mdb *mongoDatabase) DeleteShortenedURL(id *short.ShortID) (err error) {
 	// (Hopefully) deletes the object from the Mongo database
 	_, err = mdb.shortURLs().DeleteOne(mdb.context, id.BsonFilter())
+	// and the reverse index entries of the url
+	if err == nil {
+		_, err = mdb.dedup().DeleteMany(mdb.context, id.BsonFilter())
+	}
 	// Also remove the object from the cache
 	if err == nil {
 		// remove from cache
@@ -117,6 +263,30 @@ func (mdb *mongoDatabase) DeleteShortenedURL(id *short.ShortID) (err error) {
 	return
 }
 
+// UpdateShortenedURL saves the changed {short} (keeping its clicks) and breaks the cache of all nodes
+func (mdb *mongoDatabase) UpdateShortenedURL(short *short.ShortURL) (err error) {
+	if err = mdb.saveShortenedURL(short, short.BsonUpdate()); err != nil {
+		return
+	}
+	err = mdb.cache.BreakCache(&short.ID)
+	return
+}
+
+// mongoDedupUpdate points the reverse index entry to {short}
+func mongoDedupUpdate(short *short.ShortURL) bson.M {
+	return bson.M{"$set": bson.M{"id": short.ID.String()}}
+}
+
+func (mdb *mongoDatabase) FindDuplicate(hash string) (res *short.ShortURL, err error) {
+	var entry struct {
+		ID short.ShortID `bson:"id"`
+	}
+	if err = mdb.dedup().FindOne(mdb.context, bson.M{"_id": hash}).Decode(&entry); err != nil {
+		return
+	}
+	return findDuplicate(mdb, entry.ID, hash)
+}
+
 func (mdb *mongoDatabase) FindShortenedURL(id *short.ShortID) (shortURL *short.ShortURL, err error) {
 	// At first, try to load the object from the cache
 	if u := mdb.cache.GetShortURL(id); u != nil {
@@ -152,12 +322,80 @@ func (mdb *mongoDatabase) ShortURLAvailable(id *short.ShortID) bool {
  * ==================================================================================================
  */
 
+func (mdb *mongoDatabase) ConsumeClick(id *short.ShortID, max uint64) (ok bool, err error) {
+	// the counter is saved in the short url document (but not in short.ShortURL),
+	// the conditional update is atomic, even with multiple nodes
+	filter := id.BsonFilter()
+	filter["$or"] = []bson.M{
+		{"clicks": bson.M{"$exists": false}},
+		{"clicks": bson.M{"$lt": int64(max)}},
+	}
+	var res *mongo.UpdateResult
+	if res, err = mdb.shortURLs().UpdateOne(mdb.context, filter, bson.M{
+		"$inc": bson.M{"clicks": int64(1)},
+	}); err != nil {
+		return
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// mongoCounter -> document of a counter in the meta collection
+type mongoCounter struct {
+	Value int64 `bson:"value"`
+}
+
+func (mdb *mongoDatabase) IncrementCounter(name string) (n uint64, err error) {
+	// the counter is saved in the meta collection, $inc is atomic, even with multiple nodes.
+	// it is keyed by _id, so concurrent upserts can't create the same counter twice
+	var counter mongoCounter
+	if err = mdb.meta().FindOneAndUpdate(
+		mdb.context,
+		bson.M{"_id": "counter::" + name},
+		bson.M{"$inc": bson.M{"value": int64(1)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter); err != nil {
+		return
+	}
+	return uint64(counter.Value), nil
+}
+
+func (mdb *mongoDatabase) DecrementCounter(name string, n uint64) (err error) {
+	var res *mongo.UpdateResult
+	if res, err = mdb.meta().UpdateOne(mdb.context,
+		bson.M{"_id": "counter::" + name, "value": bson.M{"$gte": int64(n)}},
+		bson.M{"$inc": bson.M{"value": -int64(n)}}); err != nil || res.MatchedCount == 1 {
+		return
+	}
+	// the counter is smaller than {n}
+	_, err = mdb.meta().UpdateOne(mdb.context,
+		bson.M{"_id": "counter::" + name, "value": bson.M{"$lt": int64(n)}},
+		bson.M{"$set": bson.M{"value": int64(0)}})
+	return
+}
+
+func (mdb *mongoDatabase) ExpireCounter(name string, at time.Time) (err error) {
+	// the document is removed by the TTL index of the meta collection
+	_, err = mdb.meta().UpdateOne(mdb.context,
+		bson.M{"_id": "counter::" + name},
+		bson.M{"$set": bson.M{"expire_at": at}})
+	return
+}
+
 func (mdb *mongoDatabase) FindExpiredURLs() (res []*short.ShortURL, err error) {
 	filter := bson.M{
-		"$and": []bson.M{
-			{"expiration_date": bson.M{"$exists": true}},
-			{"expiration_date": bson.M{"$ne": nil}},
-			{"expiration_date": bson.M{"$lt": time.Now()}},
+		"$or": []bson.M{
+			{
+				"$and": []bson.M{
+					{"expiration_date": bson.M{"$exists": true}},
+					{"expiration_date": bson.M{"$ne": nil}},
+					{"expiration_date": bson.M{"$lt": time.Now()}},
+				},
+			},
+			// exhausted
+			{
+				"max_clicks": bson.M{"$gt": 0},
+				"$expr":      bson.M{"$gte": bson.A{"$clicks", "$max_clicks"}},
+			},
 		},
 	}
 
@@ -273,3 +511,332 @@ func (mdb *mongoDatabase) SavePool(pool *short.Pool) (err error) {
 		options.Update().SetUpsert(true))
 	return
 }
+
+/*
+ * ==================================================================================================
+ *                          A C C O U N T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (mdb *mongoDatabase) SaveAccount(account *short.Account) (err error) {
+	_, err = mdb.accounts().ReplaceOne(mdb.context,
+		bson.M{"_id": account.ID.String()},
+		account,
+		options.Replace().SetUpsert(true))
+	return
+}
+
+func (mdb *mongoDatabase) DeleteAccount(id *short.AccountID) (err error) {
+	_, err = mdb.accounts().DeleteOne(mdb.context, bson.M{"_id": id.String()})
+	return
+}
+
+func (mdb *mongoDatabase) FindAccount(id *short.AccountID) (account *short.Account, err error) {
+	err = mdb.accounts().FindOne(mdb.context, bson.M{"_id": id.String()}).Decode(&account)
+	return
+}
+
+func (mdb *mongoDatabase) FindAccountByKey(keyHash string) (account *short.Account, err error) {
+	err = mdb.accounts().FindOne(mdb.context, bson.M{"key_hash": keyHash}).Decode(&account)
+	return
+}
+
+func (mdb *mongoDatabase) FindAccounts() (accounts []*short.Account, err error) {
+	var cursor *mongo.Cursor
+	if cursor, err = mdb.accounts().Find(mdb.context, bson.M{}); err != nil {
+		return
+	}
+	defer cursor.Close(mdb.context)
+	for cursor.Next(mdb.context) {
+		var a *short.Account
+		if err = cursor.Decode(&a); err != nil {
+			return
+		}
+		accounts = append(accounts, a)
+	}
+	err = cursor.Err()
+	return
+}
+
+func (mdb *mongoDatabase) FindShortURLsByOwner(owner *short.AccountID, offset, limit int) (urls []*short.ShortURL,
+	total int, err error) {
+	filter := bson.M{"owner": owner.String()}
+	var count int64
+	if count, err = mdb.shortURLs().CountDocuments(mdb.context, filter); err != nil {
+		return
+	}
+	total = int(count)
+	urls = []*short.ShortURL{}
+	if offset >= total || limit <= 0 {
+		return
+	}
+	var cursor *mongo.Cursor
+	if cursor, err = mdb.shortURLs().Find(mdb.context, filter, options.Find().
+		SetSort(bson.D{{Key: "creation_date", Value: -1}, {Key: "id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))); err != nil {
+		return
+	}
+	defer cursor.Close(mdb.context)
+	for cursor.Next(mdb.context) {
+		var u *short.ShortURL
+		if err = cursor.Decode(&u); err != nil {
+			return nil, 0, err
+		}
+		urls = append(urls, u)
+	}
+	err = cursor.Err()
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            S T A T S   D A T A B A S E
+ * ==================================================================================================
+ */
+
+// mongoStats is the document saved in the stats collection.
+// Minutes maps the unix minute to the calls in that minute,
+// Hours and Days hold the time series buckets (see short.Granularity.Bucket).
+// The keys are strings, since bson keys must be strings.
+// Breakdowns holds the calls per dimension and value (escaped with mongoEscapeKey).
+type mongoStats struct {
+	ID         string                       `bson:"_id"`
+	Calls      uint64                       `bson:"calls"`
+	Minutes    map[string]uint64            `bson:"minutes"`
+	Hours      map[string]uint64            `bson:"hours"`
+	Days       map[string]uint64            `bson:"days"`
+	Breakdowns map[string]map[string]uint64 `bson:"breakdowns"`
+	// registers of the HyperLogLogs (see hyperLogLog), so they can be updated atomically with $max
+	Visitors      map[string]int32            `bson:"visitors"`
+	DailyVisitors map[string]map[string]int32 `bson:"daily_visitors"`
+	// VisitorsDay -> newest day in DailyVisitors, older days are removed when a new day starts
+	VisitorsDay int64 `bson:"visitors_day"`
+}
+
+var (
+	mongoKeyEscaper   = strings.NewReplacer("%", "%25", ".", "%2E", "$", "%24")
+	mongoKeyUnescaper = strings.NewReplacer("%2E", ".", "%24", "$", "%25", "%")
+)
+
+// mongoEscapeKey escapes dots and dollar signs, since they are not allowed in keys (e. g. referrer hosts)
+func mongoEscapeKey(key string) string {
+	return mongoKeyEscaper.Replace(key)
+}
+
+func mongoUnescapeKey(key string) string {
+	return mongoKeyUnescaper.Replace(key)
+}
+
+// staleBuckets returns the keys of all {buckets} older than {oldest}
+func staleBuckets(buckets map[string]uint64, oldest int64) (res []string) {
+	for k := range buckets {
+		if b, err := strconv.ParseInt(k, 10, 64); err != nil || b < oldest {
+			res = append(res, k)
+		}
+	}
+	return
+}
+
+// stale returns the paths of all buckets which can be removed
+func (m *mongoStats) stale(now time.Time, retention *StatsRetention) (res []string) {
+	for _, k := range staleBuckets(m.Minutes, now.Add(-time.Hour).Unix()/60+1) {
+		res = append(res, "minutes."+k)
+	}
+	for _, k := range staleBuckets(m.Hours, retention.oldestBucket(short.GranularityHour, now)) {
+		res = append(res, "hours."+k)
+	}
+	for _, k := range staleBuckets(m.Days, retention.oldestBucket(short.GranularityDay, now)) {
+		res = append(res, "days."+k)
+	}
+	return
+}
+
+// mongoHyperLogLog creates a hyperLogLog from the stored {registers}
+func mongoHyperLogLog(registers map[string]int32) hyperLogLog {
+	if len(registers) == 0 {
+		return nil
+	}
+	h := newHyperLogLog()
+	for k, rank := range registers {
+		if r, err := strconv.Atoi(k); err == nil && r >= 0 && r < len(h) {
+			h[r] = uint8(rank)
+		}
+	}
+	return h
+}
+
+func (m *mongoStats) stats(now time.Time) *short.Stats {
+	oldest := now.Add(-time.Hour).Unix() / 60
+	var calls60 uint64
+	for k, c := range m.Minutes {
+		if minute, err := strconv.ParseInt(k, 10, 64); err == nil && minute > oldest {
+			calls60 += c
+		}
+	}
+	breakdowns := make(map[string]map[string]uint64)
+	for dim, values := range m.Breakdowns {
+		breakdowns[dim] = make(map[string]uint64)
+		for k, c := range values {
+			breakdowns[dim][mongoUnescapeKey(k)] = c
+		}
+	}
+	today := strconv.FormatInt(short.GranularityDay.Bucket(now), 10)
+	return &short.Stats{
+		Calls:         m.Calls,
+		Calls60:       calls60,
+		Visitors:      mongoHyperLogLog(m.Visitors).count(),
+		VisitorsToday: mongoHyperLogLog(m.DailyVisitors[today]).count(),
+		Breakdowns:    topBreakdowns(breakdowns),
+	}
+}
+
+// mongoStatsFilter -> the stats of a short url are keyed by _id,
+// so concurrent upserts of the first clicks can't create the document twice
+func mongoStatsFilter(id *short.ShortID) bson.M {
+	return bson.M{"_id": id.String()}
+}
+
+func (mdb *mongoDatabase) findStats(id *short.ShortID) (m *mongoStats, err error) {
+	m = new(mongoStats)
+	if err = mdb.stats().FindOne(mdb.context, mongoStatsFilter(id)).Decode(m); err == mongo.ErrNoDocuments {
+		err = nil
+	}
+	return
+}
+
+func (mdb *mongoDatabase) FindStats(id *short.ShortID) (stats *short.Stats, err error) {
+	var m *mongoStats
+	if m, err = mdb.findStats(id); err != nil {
+		return
+	}
+	stats = m.stats(time.Now())
+	return
+}
+
+func (mdb *mongoDatabase) FindTimeSeries(id *short.ShortID, g short.Granularity,
+	from, to time.Time) (ts *short.TimeSeries, err error) {
+	var m *mongoStats
+	if m, err = mdb.findStats(id); err != nil {
+		return
+	}
+	buckets := m.Hours
+	if g == short.GranularityDay {
+		buckets = m.Days
+	}
+	ts = mdb.retention.timeSeries(g, from, to, func(bucket int64) uint64 {
+		return buckets[strconv.FormatInt(bucket, 10)]
+	})
+	return
+}
+
+func (mdb *mongoDatabase) AddStats(id *short.ShortID, click *short.Click) (err error) {
+	now := clickTime(click)
+	minute := strconv.FormatInt(now.Unix()/60, 10)
+	hour := strconv.FormatInt(short.GranularityHour.Bucket(now), 10)
+	today := short.GranularityDay.Bucket(now)
+	day := strconv.FormatInt(today, 10)
+	inc := bson.M{
+		"calls":             1,
+		"minutes." + minute: 1,
+		"hours." + hour:     1,
+		"days." + day:       1,
+	}
+	dimensions := clickDimensions(click)
+	for dim, value := range dimensions {
+		inc["breakdowns."+dim+"."+mongoEscapeKey(value)] = 1
+	}
+	// increment atomically, so multiple nodes can count at the same time.
+	// only the fields needed to find stale buckets are returned, not the registers of the visitors
+	projection := bson.M{"minutes": 1, "hours": 1, "days": 1, "visitors_day": 1}
+	for dim := range dimensions {
+		projection["breakdowns."+dim] = 1
+	}
+	m := new(mongoStats)
+	if err = mdb.stats().FindOneAndUpdate(mdb.context, mongoStatsFilter(id), bson.M{"$inc": inc},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After).
+			SetProjection(projection)).Decode(m); err != nil {
+		return
+	}
+	// stale buckets are never incremented again, so they can be removed safely
+	unset := bson.M{}
+	for _, k := range m.stale(now, mdb.retention) {
+		unset[k] = ""
+	}
+	// new values of dimensions with too many values are moved to "other"
+	inc = bson.M{}
+	for dim, value := range dimensions {
+		values, key := m.Breakdowns[dim], mongoEscapeKey(value)
+		if values[key] == 1 && breakdownValue(value, false, len(values)-1) != value {
+			unset["breakdowns."+dim+"."+key] = ""
+			inc["breakdowns."+dim+"."+breakdownOther] = 1
+		}
+	}
+	// the registers are updated with $max, so they don't have to be read
+	registers := bson.M{}
+	if visitor := clickVisitor(click); visitor != "" {
+		register, rank := hllRegister(visitor)
+		r := strconv.Itoa(register)
+		registers["visitors."+r] = int32(rank)
+		// only the registers of the current day are kept
+		if today >= m.VisitorsDay {
+			registers["daily_visitors."+day+"."+r] = int32(rank)
+			registers["visitors_day"] = today
+			if m.VisitorsDay != 0 && m.VisitorsDay < today {
+				unset["daily_visitors."+strconv.FormatInt(m.VisitorsDay, 10)] = ""
+			}
+		}
+	}
+	if len(unset) == 0 && len(registers) == 0 {
+		return
+	}
+	update := bson.M{}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(inc) > 0 {
+		update["$inc"] = inc
+	}
+	if len(registers) > 0 {
+		update["$max"] = registers
+	}
+	_, err = mdb.stats().UpdateOne(mdb.context, mongoStatsFilter(id), update)
+	return
+}
+
+func (mdb *mongoDatabase) DeleteStats(id *short.ShortID) (err error) {
+	_, err = mdb.stats().DeleteOne(mdb.context, mongoStatsFilter(id))
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            R A T E   L I M I T
+ * ==================================================================================================
+ */
+
+// mongoRateLimit -> document of a rate limit window in the ratelimit collection
+type mongoRateLimit struct {
+	Key   string    `bson:"key"`
+	Start time.Time `bson:"start"`
+	Hits  int64     `bson:"hits"`
+	// ExpireAt -> end of the window, the document is removed by the TTL index afterwards
+	ExpireAt time.Time `bson:"expire_at"`
+}
+
+func (mdb *mongoDatabase) IncrementRateLimit(key string, start time.Time, window time.Duration) (hits uint64, err error) {
+	// every window has its own document, $inc is atomic, even with multiple nodes
+	var w mongoRateLimit
+	if err = mdb.rateLimits().FindOneAndUpdate(
+		mdb.context,
+		bson.M{"_id": key + "::" + strconv.FormatInt(start.UnixNano(), 10)},
+		bson.M{
+			"$inc":         bson.M{"hits": int64(1)},
+			"$setOnInsert": bson.M{"key": key, "start": start, "expire_at": start.Add(window)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&w); err != nil {
+		return
+	}
+	return uint64(w.Hits), nil
+}
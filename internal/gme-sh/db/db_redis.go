@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
@@ -15,9 +16,10 @@ import (
 // PersistentDatabase
 // StatsDatabase
 type redisDB struct {
-	client  *redis.Client
-	context context.Context
-	ps      *redis.PubSub
+	client    *redis.Client
+	context   context.Context
+	ps        *redis.PubSub
+	retention *StatsRetention
 }
 
 func newRedisDB(cfg *config.RedisConfig) (*redisDB, error) {
@@ -34,8 +36,9 @@ func newRedisDB(cfg *config.RedisConfig) (*redisDB, error) {
 	}
 
 	return &redisDB{
-		client:  client,
-		context: ctx,
+		client:    client,
+		context:   ctx,
+		retention: NewStatsRetention(nil),
 	}, nil
 }
 
@@ -48,8 +51,13 @@ func NewRedisPubSub(cfg *config.RedisConfig) (PubSub, error) {
 	return newRedisDB(cfg)
 }
 
-func NewRedisStats(cfg *config.RedisConfig) (StatsDatabase, error) {
-	return newRedisDB(cfg)
+func NewRedisStats(cfg *config.RedisConfig, stats *config.StatsConfig) (StatsDatabase, error) {
+	rdb, err := newRedisDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rdb.retention = NewStatsRetention(stats)
+	return rdb, nil
 }
 
 /*
@@ -73,27 +81,156 @@ func (rdb *redisDB) HealthCheck(ctx context.Context) (err error) {
  * ==================================================================================================
  */
 
-func (rdb *redisDB) SaveShortenedURL(short *short.ShortURL) (err error) {
+// redisKeyExpirations is a sorted set of all temporary short urls (score = expiration date)
+const redisKeyExpirations = "gme::expirations"
+
+// redisKeyExhausted is a set of all short urls which reached their MaxClicks
+const redisKeyExhausted = "gme::exhausted"
+
+// redisClicksKey returns gme::short::{id}::clicks (click counter of urls with MaxClicks)
+func redisClicksKey(id *short.ShortID) string {
+	return id.RedisKeyf("clicks")
+}
+
+// redisDedupKey returns gme::dedup::{hash} (reverse index, id of the url with the DedupHash)
+func redisDedupKey(hash string) string {
+	return "gme::dedup::" + hash
+}
+
+// redisDelIfEqual deletes KEYS[1] if its value is ARGV[1]
+var redisDelIfEqual = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// redisOwnerKey returns gme::owner::{id}, a sorted set of the short urls of the account (score = creation date)
+func redisOwnerKey(owner short.AccountID) string {
+	return "gme::owner::" + owner.String()
+}
+
+// redisKeyAccounts is a set of all account ids
+const redisKeyAccounts = "gme::accounts"
+
+// redisAccountKey returns gme::account::{id} (json)
+func redisAccountKey(id *short.AccountID) string {
+	return "gme::account::" + id.String()
+}
+
+// redisAccountKeyHashKey returns gme::account-key::{hash} (id of the account with the KeyHash)
+func redisAccountKeyHashKey(hash string) string {
+	return "gme::account-key::" + hash
+}
+
+// redisCounterKey returns gme::counter::{name}
+func redisCounterKey(name string) string {
+	return "gme::counter::" + name
+}
+
+// redisRateLimitKey returns gme::ratelimit::{key}::{start of the window in unix ms}
+func redisRateLimitKey(key string, start time.Time) string {
+	return "gme::ratelimit::" + key + "::" + strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// redisKeyLastExpirationCheck holds the LastExpirationCheckMeta (json)
+const redisKeyLastExpirationCheck = "gme::meta::last_expired"
+
+// SaveShortenedURL saves a new {short}. The click counter is reset,
+// the id may belong to an expired url which was not removed yet.
+func (rdb *redisDB) SaveShortenedURL(short *short.ShortURL) error {
+	return rdb.saveShortenedURL(short, true)
+}
+
+// saveShortenedURL stores {short} and resets its click counter if {resetClicks} is true
+func (rdb *redisDB) saveShortenedURL(short *short.ShortURL, resetClicks bool) (err error) {
+	pipe := rdb.client.TxPipeline()
+	if err = rdb.queueSave(pipe, short, resetClicks); err != nil {
+		return
+	}
+	_, err = pipe.Exec(rdb.context)
+	return
+}
+
+// SaveShortenedURLs -> BulkPersistentDatabase, all urls are saved in one transaction
+func (rdb *redisDB) SaveShortenedURLs(urls []*short.ShortURL) (err error) {
+	pipe := rdb.client.TxPipeline()
+	for _, u := range urls {
+		if err = rdb.queueSave(pipe, u, true); err != nil {
+			return
+		}
+	}
+	_, err = pipe.Exec(rdb.context)
+	return
+}
+
+// queueSave adds the commands which save {short} to the {pipe}.
+// If {resetClicks} is true, the click counter and the exhausted mark are removed.
+func (rdb *redisDB) queueSave(pipe redis.Pipeliner, short *short.ShortURL, resetClicks bool) (err error) {
 	var data []byte
-	data, err = json.Marshal(short)
-	if err != nil {
+	if data, err = json.Marshal(short); err != nil {
 		return
 	}
-	var exp time.Duration
+	// expired urls are removed by the ExpirationCheck, just like with every other backend,
+	// so the expiration is tracked in a sorted set instead of a ttl
+	pipe.Set(rdb.context, short.ID.RedisKey(), string(data), 0)
+	if resetClicks {
+		pipe.Del(rdb.context, redisClicksKey(&short.ID))
+		pipe.SRem(rdb.context, redisKeyExhausted, short.ID.String())
+	}
+	if hash := short.DedupHash(); hash != "" {
+		pipe.Set(rdb.context, redisDedupKey(hash), short.ID.String(), 0)
+	}
+	if short.Owner != "" {
+		pipe.ZAdd(rdb.context, redisOwnerKey(short.Owner), &redis.Z{
+			Score:  float64(short.CreationDate.UnixNano() / int64(time.Millisecond)),
+			Member: short.ID.String(),
+		})
+	}
 	if short.ExpirationDate != nil {
-		exp = short.ExpirationDate.Sub(time.Now())
+		pipe.ZAdd(rdb.context, redisKeyExpirations, &redis.Z{
+			Score:  float64(short.ExpirationDate.Unix()),
+			Member: short.ID.String(),
+		})
 	} else {
-		exp = redis.KeepTTL
+		pipe.ZRem(rdb.context, redisKeyExpirations, short.ID.String())
 	}
-	err = rdb.client.Set(rdb.context, short.ID.RedisKey(), string(data), exp).Err()
 	return
 }
 
+func (rdb *redisDB) FindDuplicate(hash string) (res *short.ShortURL, err error) {
+	var id string
+	if id, err = rdb.client.Get(rdb.context, redisDedupKey(hash)).Result(); err != nil {
+		return
+	}
+	return findDuplicate(rdb, short.ShortID(id), hash)
+}
+
 func (rdb *redisDB) DeleteShortenedURL(id *short.ShortID) (err error) {
-	err = rdb.client.Del(rdb.context, id.RedisKey()).Err()
+	// the reverse index entry is removed if it still belongs to the url
+	data, err := rdb.client.Get(rdb.context, id.RedisKey()).Bytes()
+	if err != nil && err != redis.Nil {
+		return
+	}
+	if hash := dedupHashOf(data); hash != "" {
+		if err = redisDelIfEqual.Run(rdb.context, rdb.client, []string{redisDedupKey(hash)}, id.String()).Err(); err != nil && err != redis.Nil {
+			return
+		}
+	}
+	pipe := rdb.client.TxPipeline()
+	pipe.Del(rdb.context, id.RedisKey(), redisClicksKey(id))
+	pipe.ZRem(rdb.context, redisKeyExpirations, id.String())
+	pipe.SRem(rdb.context, redisKeyExhausted, id.String())
+	if owner := ownerOf(data); owner != "" {
+		pipe.ZRem(rdb.context, redisOwnerKey(owner), id.String())
+	}
+	_, err = pipe.Exec(rdb.context)
 	return
 }
 
+// UpdateShortenedURL saves the changed {short} (keeping its clicks). Redis is not cached, so every node sees the change.
+func (rdb *redisDB) UpdateShortenedURL(short *short.ShortURL) error {
+	return rdb.saveShortenedURL(short, false)
+}
+
 func (rdb *redisDB) FindShortenedURL(id *short.ShortID) (res *short.ShortURL, err error) {
 	data := rdb.client.Get(rdb.context, id.RedisKey())
 	err = data.Err()
@@ -114,20 +251,102 @@ func (rdb *redisDB) ShortURLAvailable(id *short.ShortID) bool {
  * ==================================================================================================
  */
 
-func (*redisDB) FindExpiredURLs() (res []*short.ShortURL, err error) {
-	return []*short.ShortURL{}, nil
+func (rdb *redisDB) ConsumeClick(id *short.ShortID, max uint64) (ok bool, err error) {
+	// INCR is atomic, so no more than {max} clicks are allowed, even with multiple nodes
+	var clicks int64
+	if clicks, err = rdb.client.Incr(rdb.context, redisClicksKey(id)).Result(); err != nil {
+		return
+	}
+	if uint64(clicks) >= max {
+		if err = rdb.client.SAdd(rdb.context, redisKeyExhausted, id.String()).Err(); err != nil {
+			return
+		}
+	}
+	return uint64(clicks) <= max, nil
 }
 
-func (*redisDB) GetLastExpirationCheck() *LastExpirationCheckMeta {
-	return &LastExpirationCheckMeta{
-		LastCheck: time.Now(),
+func (rdb *redisDB) IncrementCounter(name string) (n uint64, err error) {
+	var value int64
+	if value, err = rdb.client.Incr(rdb.context, redisCounterKey(name)).Result(); err != nil {
+		return
+	}
+	return uint64(value), nil
+}
+
+// redisDecrementCounter decrements KEYS[1] by ARGV[1], but not below 0
+var redisDecrementCounter = redis.NewScript(`local value = tonumber(redis.call("GET", KEYS[1]) or "0")
+if value <= 0 then
+	return 0
+end
+return redis.call("DECRBY", KEYS[1], math.min(value, tonumber(ARGV[1])))`)
+
+func (rdb *redisDB) DecrementCounter(name string, n uint64) (err error) {
+	err = redisDecrementCounter.Run(rdb.context, rdb.client, []string{redisCounterKey(name)}, n).Err()
+	return
+}
+
+func (rdb *redisDB) ExpireCounter(name string, at time.Time) (err error) {
+	// redis removes the key itself
+	err = rdb.client.PExpireAt(rdb.context, redisCounterKey(name), at).Err()
+	return
+}
+
+func (rdb *redisDB) FindExpiredURLs() (res []*short.ShortURL, err error) {
+	var ids []string
+	if ids, err = rdb.client.ZRangeByScore(rdb.context, redisKeyExpirations, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result(); err != nil {
+		return
+	}
+	var exhausted []string
+	if exhausted, err = rdb.client.SMembers(rdb.context, redisKeyExhausted).Result(); err != nil {
+		return
 	}
+	found := make(map[string]bool)
+	for _, i := range append(ids, exhausted...) {
+		if found[i] {
+			continue
+		}
+		found[i] = true
+		id := short.ShortID(i)
+		var u *short.ShortURL
+		if u, err = rdb.FindShortenedURL(&id); err == redis.Nil {
+			// short url was removed without updating the expirations
+			pipe := rdb.client.TxPipeline()
+			pipe.ZRem(rdb.context, redisKeyExpirations, i)
+			pipe.SRem(rdb.context, redisKeyExhausted, i)
+			_, err = pipe.Exec(rdb.context)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return
 }
 
-func (*redisDB) UpdateLastExpirationCheck(t time.Time) {
+func (rdb *redisDB) GetLastExpirationCheck() (m *LastExpirationCheckMeta) {
+	m = &LastExpirationCheckMeta{
+		LastCheck: time.Unix(5, 0),
+	}
+	data, err := rdb.client.Get(rdb.context, redisKeyLastExpirationCheck).Result()
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal([]byte(data), m)
 	return
 }
 
+func (rdb *redisDB) UpdateLastExpirationCheck(t time.Time) {
+	data, err := json.Marshal(&LastExpirationCheckMeta{LastCheck: t})
+	if err != nil {
+		return
+	}
+	_ = rdb.client.Set(rdb.context, redisKeyLastExpirationCheck, string(data), 0).Err()
+}
+
 /*
  * ==================================================================================================
  *                          T E M P L A T E   I M P L E M E N T A T I O N S
@@ -170,54 +389,186 @@ func (rdb *redisDB) SaveTemplate(t *tpl.Template) (err error) {
  * ==================================================================================================
  */
 
+// redisBreakdownKey returns gme::short::{id}::bd:{dimension} (sorted set, score = calls)
+func redisBreakdownKey(id *short.ShortID, dimension string) string {
+	return id.RedisKeyf("bd:" + dimension)
+}
+
+// redisVisitorsKey returns gme::short::{id}::visitors (HyperLogLog of all time)
+// and redisDailyVisitorsKey gme::short::{id}::visitors:{day} (HyperLogLog of a day)
+func redisVisitorsKey(id *short.ShortID) string {
+	return id.RedisKeyf("visitors")
+}
+
+func redisDailyVisitorsKey(id *short.ShortID, day int64) string {
+	return id.RedisKeyf("visitors:" + strconv.FormatInt(day, 10))
+}
+
 func (rdb *redisDB) FindStats(id *short.ShortID) (stats *short.Stats, err error) {
+	// missing counters (redis.Nil) mean the short url was not called (in the last 60 minutes)
 	var calls, calls60 uint64
 	calls, err = rdb.client.Get(rdb.context, id.RedisKeyf(short.RedisKeyCountGlobal)).Uint64()
-	if err != nil {
+	if err != nil && err != redis.Nil {
 		return
 	}
 	calls60, err = rdb.client.Get(rdb.context, id.RedisKeyf(short.RedisKeyCount60)).Uint64()
-	if err != nil {
+	if err != nil && err != redis.Nil {
+		return
+	}
+	var visitors, visitorsToday int64
+	if visitors, err = rdb.client.PFCount(rdb.context, redisVisitorsKey(id)).Result(); err != nil {
+		return
+	}
+	today := short.GranularityDay.Bucket(time.Now())
+	if visitorsToday, err = rdb.client.PFCount(rdb.context, redisDailyVisitorsKey(id, today)).Result(); err != nil {
 		return
 	}
 	stats = &short.Stats{
-		Calls:   calls,
-		Calls60: calls60,
+		Calls:         calls,
+		Calls60:       calls60,
+		Visitors:      uint64(visitors),
+		VisitorsToday: uint64(visitorsToday),
+		Breakdowns:    make(map[string][]*short.StatsEntry),
+	}
+	for _, dim := range short.Breakdowns {
+		// the sets are capped (see breakdownValue), so we can sort them like the other backends
+		var values []redis.Z
+		if values, err = rdb.client.ZRangeWithScores(rdb.context, redisBreakdownKey(id, dim),
+			0, -1).Result(); err != nil {
+			return
+		}
+		counts := make(map[string]uint64, len(values))
+		for _, z := range values {
+			counts[z.Member.(string)] = uint64(z.Score)
+		}
+		stats.Breakdowns[dim] = short.TopStatsEntries(counts, StatsBreakdownTop)
 	}
 	return
 }
 
-func (rdb *redisDB) AddStats(id *short.ShortID) (err error) {
+// redisIncrBreakdown counts ARGV[1] in the breakdown KEYS[1], or ARGV[3] (other) if ARGV[1] is new
+// and the breakdown already holds ARGV[2] values (see breakdownValue).
+// The script is atomic, so concurrent clicks can't exceed the limit.
+var redisIncrBreakdown = redis.NewScript(`local value = ARGV[1]
+if not redis.call("ZSCORE", KEYS[1], value) and redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[2]) then
+	value = ARGV[3]
+end
+return redis.call("ZINCRBY", KEYS[1], 1, value)`)
+
+func (rdb *redisDB) AddStats(id *short.ShortID, click *short.Click) (err error) {
 	err = rdb.client.Incr(rdb.context, id.RedisKeyf(short.RedisKeyCountGlobal)).Err()
 	if err != nil {
 		return
 	}
 	count60Key := id.RedisKeyf(short.RedisKeyCount60)
-	resultExists := rdb.client.Exists(rdb.context, count60Key)
-	err = resultExists.Err()
-	expire := resultExists.Val() == 0
-	if err != nil {
+	var count60 int64
+	if count60, err = rdb.client.Incr(rdb.context, count60Key).Result(); err != nil {
 		return
 	}
-	err = rdb.client.Incr(rdb.context, count60Key).Err()
-	if err != nil {
-		return
+	// the counter was just created -> reset it in 60 minutes
+	if count60 == 1 {
+		if err = rdb.client.Expire(rdb.context, count60Key, time.Hour).Err(); err != nil {
+			return
+		}
+	}
+	// breakdowns
+	for dim, value := range clickDimensions(click) {
+		if err = redisIncrBreakdown.Run(rdb.context, rdb.client, []string{redisBreakdownKey(id, dim)},
+			value, maxBreakdownValues, breakdownOther).Err(); err != nil {
+			return
+		}
+	}
+	// time series buckets are removed by redis after the retention
+	now := clickTime(click)
+	pipe := rdb.client.TxPipeline()
+	for _, g := range []short.Granularity{short.GranularityHour, short.GranularityDay} {
+		key := redisTimeSeriesKey(id, g, g.Bucket(now))
+		pipe.Incr(rdb.context, key)
+		pipe.Expire(rdb.context, key, rdb.retention.Of(g)+g.Duration())
 	}
-	if !expire {
-		err = rdb.client.Expire(rdb.context, count60Key, time.Hour).Err()
+	// unique visitors, only the visitors of the current day are kept
+	if visitor := clickVisitor(click); visitor != "" {
+		dailyKey := redisDailyVisitorsKey(id, short.GranularityDay.Bucket(now))
+		pipe.PFAdd(rdb.context, redisVisitorsKey(id), visitor)
+		pipe.PFAdd(rdb.context, dailyKey, visitor)
+		pipe.Expire(rdb.context, dailyKey, 2*short.GranularityDay.Duration())
 	}
+	_, err = pipe.Exec(rdb.context)
+	return
+}
+
+// redisTimeSeriesKey returns gme::short::{id}::ts:{granularity}:{bucket}
+func redisTimeSeriesKey(id *short.ShortID, g short.Granularity, bucket int64) string {
+	return id.RedisKeyf("ts:" + string(g) + ":" + strconv.FormatInt(bucket, 10))
+}
+
+func (rdb *redisDB) FindTimeSeries(id *short.ShortID, g short.Granularity,
+	from, to time.Time) (ts *short.TimeSeries, err error) {
+	buckets := rdb.retention.buckets(g, from, to, time.Now())
+	calls := make(map[int64]uint64)
+	if len(buckets) > 0 {
+		keys := make([]string, len(buckets))
+		for i, b := range buckets {
+			keys[i] = redisTimeSeriesKey(id, g, b)
+		}
+		var values []interface{}
+		if values, err = rdb.client.MGet(rdb.context, keys...).Result(); err != nil {
+			return
+		}
+		for i, v := range values {
+			// missing buckets are nil
+			if str, ok := v.(string); ok {
+				calls[buckets[i]], _ = strconv.ParseUint(str, 10, 64)
+			}
+		}
+	}
+	ts = rdb.retention.timeSeries(g, from, to, func(bucket int64) uint64 {
+		return calls[bucket]
+	})
 	return
 }
 
 func (rdb *redisDB) DeleteStats(id *short.ShortID) (err error) {
-	err = rdb.client.Del(
-		rdb.context,
+	keys := []string{
 		id.RedisKeyf(short.RedisKeyCountGlobal),
 		id.RedisKeyf(short.RedisKeyCount60),
-	).Err()
+		redisVisitorsKey(id),
+	}
+	for _, dim := range short.Breakdowns {
+		keys = append(keys, redisBreakdownKey(id, dim))
+	}
+	// all time series buckets which may still exist
+	now := time.Now()
+	for _, g := range []short.Granularity{short.GranularityHour, short.GranularityDay} {
+		for _, b := range rdb.retention.buckets(g, now.Add(-rdb.retention.Of(g)), now, now) {
+			keys = append(keys, redisTimeSeriesKey(id, g, b))
+		}
+	}
+	today := short.GranularityDay.Bucket(now)
+	keys = append(keys, redisDailyVisitorsKey(id, today), redisDailyVisitorsKey(id, today-1))
+	err = rdb.client.Del(rdb.context, keys...).Err()
 	return
 }
 
+/*
+ * ==================================================================================================
+ *                            R A T E   L I M I T
+ * ==================================================================================================
+ */
+
+func (rdb *redisDB) IncrementRateLimit(key string, start time.Time, window time.Duration) (hits uint64, err error) {
+	// every window has its own key, which expires at the end of the window.
+	// INCR is atomic, so all nodes share the counter.
+	k := redisRateLimitKey(key, start)
+	pipe := rdb.client.TxPipeline()
+	incr := pipe.Incr(rdb.context, k)
+	pipe.PExpireAt(rdb.context, k, start.Add(window))
+	if _, err = pipe.Exec(rdb.context); err != nil {
+		return
+	}
+	return uint64(incr.Val()), nil
+}
+
 /*
  * ==================================================================================================
  *                                       P U B S U B
@@ -264,7 +615,9 @@ func (rdb *redisDB) FindPool(id *short.PoolID) (pool *short.Pool, err error) {
 		return nil, cmd.Err()
 	}
 	pool = new(short.Pool)
-	err = json.Unmarshal([]byte(cmd.String()), pool)
+	if err = json.Unmarshal([]byte(cmd.Val()), pool); err != nil {
+		return nil, err
+	}
 	return
 }
 
@@ -276,3 +629,120 @@ func (rdb *redisDB) SavePool(pool *short.Pool) (err error) {
 	err = rdb.client.Set(rdb.context, "pool::"+pool.ID.String(), string(data), redis.KeepTTL).Err()
 	return
 }
+
+/*
+ * ==================================================================================================
+ *                          A C C O U N T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (rdb *redisDB) SaveAccount(account *short.Account) (err error) {
+	var data []byte
+	if data, err = json.Marshal(account); err != nil {
+		return
+	}
+	// the index entry of the old key is removed (e. g. after the key was rotated)
+	old, err := rdb.FindAccount(&account.ID)
+	if err != nil && err != redis.Nil {
+		return
+	}
+	pipe := rdb.client.TxPipeline()
+	if old != nil && old.KeyHash != account.KeyHash {
+		pipe.Del(rdb.context, redisAccountKeyHashKey(old.KeyHash))
+	}
+	pipe.Set(rdb.context, redisAccountKey(&account.ID), string(data), 0)
+	pipe.Set(rdb.context, redisAccountKeyHashKey(account.KeyHash), account.ID.String(), 0)
+	pipe.SAdd(rdb.context, redisKeyAccounts, account.ID.String())
+	_, err = pipe.Exec(rdb.context)
+	return
+}
+
+func (rdb *redisDB) DeleteAccount(id *short.AccountID) (err error) {
+	old, err := rdb.FindAccount(id)
+	if err != nil && err != redis.Nil {
+		return
+	}
+	pipe := rdb.client.TxPipeline()
+	if old != nil {
+		pipe.Del(rdb.context, redisAccountKeyHashKey(old.KeyHash))
+	}
+	pipe.Del(rdb.context, redisAccountKey(id))
+	pipe.SRem(rdb.context, redisKeyAccounts, id.String())
+	_, err = pipe.Exec(rdb.context)
+	return
+}
+
+func (rdb *redisDB) FindAccount(id *short.AccountID) (account *short.Account, err error) {
+	var data []byte
+	if data, err = rdb.client.Get(rdb.context, redisAccountKey(id)).Bytes(); err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &account)
+	return
+}
+
+func (rdb *redisDB) FindAccountByKey(keyHash string) (account *short.Account, err error) {
+	var id string
+	if id, err = rdb.client.Get(rdb.context, redisAccountKeyHashKey(keyHash)).Result(); err != nil {
+		return
+	}
+	accountID := short.AccountID(id)
+	return rdb.FindAccount(&accountID)
+}
+
+func (rdb *redisDB) FindAccounts() (accounts []*short.Account, err error) {
+	var ids []string
+	if ids, err = rdb.client.SMembers(rdb.context, redisKeyAccounts).Result(); err != nil {
+		return
+	}
+	for _, i := range ids {
+		id := short.AccountID(i)
+		var a *short.Account
+		if a, err = rdb.FindAccount(&id); err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+func (rdb *redisDB) FindShortURLsByOwner(owner *short.AccountID, offset, limit int) (urls []*short.ShortURL,
+	total int, err error) {
+	var count int64
+	if count, err = rdb.client.ZCard(rdb.context, redisOwnerKey(*owner)).Result(); err != nil {
+		return
+	}
+	total = int(count)
+	urls = []*short.ShortURL{}
+	if offset >= total || limit <= 0 {
+		return
+	}
+	var ids []string
+	if ids, err = rdb.client.ZRevRange(rdb.context, redisOwnerKey(*owner),
+		int64(offset), int64(offset+limit-1)).Result(); err != nil {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		shortID := short.ShortID(id)
+		keys[i] = shortID.RedisKey()
+	}
+	var values []interface{}
+	if values, err = rdb.client.MGet(rdb.context, keys...).Result(); err != nil {
+		return
+	}
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var u *short.ShortURL
+		if err = json.Unmarshal([]byte(data), &u); err != nil {
+			return nil, 0, err
+		}
+		urls = append(urls, u)
+	}
+	return
+}
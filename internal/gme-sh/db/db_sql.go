@@ -0,0 +1,602 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
+
+	_ "github.com/go-sql-driver/mysql" // MariaDB / MySQL driver
+	_ "github.com/lib/pq"              // PostgreSQL driver
+	_ "modernc.org/sqlite"             // SQLite driver (pure Go, no cgo needed)
+)
+
+// PersistentDatabase
+type sqlDatabase struct {
+	db      *sql.DB
+	dialect *sqlDialect
+	cache   DBCache
+	prefix  string
+	// counterSweep -> decides when expired counters are removed
+	counterSweep rateLimitSweeper
+}
+
+// NewSQLDatabase -> Creates a new implementation of PersistentDatabase (MariaDB/MySQL, PostgreSQL or SQLite),
+// connects, applies all missing migrations and returns it
+func NewSQLDatabase(cfg *config.SQLConfig, cache DBCache) (PersistentDatabase, error) {
+	dialect, err := findSQLDialect(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	database, err := sql.Open(dialect.driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only allows one writer at a time
+	if dialect == sqlDialectSQLite {
+		database.SetMaxOpenConns(1)
+	}
+	if err = database.Ping(); err != nil {
+		return nil, err
+	}
+	sdb := &sqlDatabase{
+		db:      database,
+		dialect: dialect,
+		cache:   cache,
+		prefix:  cfg.TablePrefix,
+	}
+	if err = sdb.migrate(); err != nil {
+		return nil, err
+	}
+	return sdb, nil
+}
+
+// query replaces {prefix} with the table prefix and rebinds the placeholders for the current dialect
+func (sdb *sqlDatabase) query(q string) string {
+	return sdb.dialect.rebind(strings.ReplaceAll(q, "{prefix}", sdb.prefix))
+}
+
+// table returns the name of the table {name} including the table prefix
+func (sdb *sqlDatabase) table(name string) string {
+	return sdb.prefix + name
+}
+
+// migrate applies all migrations from sqlMigrations which were not applied yet.
+// The lock of the dialect is held by the session, so everything runs on one connection.
+func (sdb *sqlDatabase) migrate() (err error) {
+	ctx := context.Background()
+	var conn *sql.Conn
+	if conn, err = sdb.db.Conn(ctx); err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// only one node migrates at a time
+	lock := sdb.table("schema_migrations")
+	if sdb.dialect.lockQuery != "" {
+		var locked sql.NullInt64
+		if err = conn.QueryRowContext(ctx, sdb.dialect.rebind(sdb.dialect.lockQuery), lock).
+			Scan(&locked); err != nil {
+			return
+		}
+		if locked.Int64 != 1 {
+			return errors.New("timeout waiting for the migration lock")
+		}
+		defer conn.ExecContext(ctx, sdb.dialect.rebind(sdb.dialect.unlockQuery), lock)
+	}
+
+	if _, err = conn.ExecContext(ctx, sdb.query(`CREATE TABLE IF NOT EXISTS {prefix}schema_migrations (
+		version INTEGER NOT NULL PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)); err != nil {
+		return
+	}
+	var current sql.NullInt64
+	if err = conn.QueryRowContext(ctx, sdb.query(`SELECT MAX(version) FROM {prefix}schema_migrations`)).
+		Scan(&current); err != nil {
+		return
+	}
+	for i := int(current.Int64); i < len(sqlMigrations); i++ {
+		var tx *sql.Tx
+		if tx, err = conn.BeginTx(ctx, nil); err != nil {
+			return
+		}
+		for _, stmt := range sqlMigrations[i] {
+			if err = sdb.applyMigration(ctx, tx, stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+		}
+		if _, err = tx.Exec(sdb.query(`INSERT INTO {prefix}schema_migrations (version, applied_at) VALUES (?, ?)`),
+			i+1, time.Now().Unix()); err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// applyMigration executes the migration statement {stmt},
+// unless it was already applied by a migration which failed midway
+func (sdb *sqlDatabase) applyMigration(ctx context.Context, tx *sql.Tx, stmt string) (err error) {
+	stmt, exists, args := sdb.dialect.migration(strings.ReplaceAll(stmt, "{prefix}", sdb.prefix))
+	if stmt == "" {
+		return
+	}
+	if exists != "" {
+		var n int
+		if err = tx.QueryRowContext(ctx, exists, args...).Scan(&n); err != nil || n > 0 {
+			return
+		}
+	}
+	_, err = tx.ExecContext(ctx, sdb.dialect.rebind(stmt))
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                          D E F A U L T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (sdb *sqlDatabase) ServiceName() string {
+	return "SQL (" + sdb.dialect.driver + ")"
+}
+
+func (sdb *sqlDatabase) HealthCheck(ctx context.Context) (err error) {
+	err = sdb.db.PingContext(ctx)
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                            P E R M A N E N T  D A T A B A S E
+ * ==================================================================================================
+ */
+
+// SaveShortenedURL saves a new {short}. The click counter is reset,
+// the id may belong to an expired url which was not removed yet.
+func (sdb *sqlDatabase) SaveShortenedURL(short *short.ShortURL) error {
+	return sdb.saveShortenedURL(short, true)
+}
+
+// saveShortenedURL upserts {short} and resets its click counter if {resetClicks} is true
+func (sdb *sqlDatabase) saveShortenedURL(short *short.ShortURL, resetClicks bool) (err error) {
+	var args []interface{}
+	if args, err = sqlShortURLArgs(short); err != nil {
+		return
+	}
+	columns := sqlShortURLColumns
+	if resetClicks {
+		columns = sqlNewShortURLColumns
+		args = append(args, int64(0))
+	}
+	_, err = sdb.db.Exec(sdb.dialect.upsert(sdb.table("short_urls"), "id", columns...), args...)
+	if hash := short.DedupHash(); err == nil && hash != "" {
+		_, err = sdb.db.Exec(sdb.dialect.upsert(sdb.table("dedup"), "hash", "id"), hash, short.ID.String())
+	}
+	if err == nil {
+		err = sdb.cache.UpdateCache(short)
+	}
+	return
+}
+
+// SaveShortenedURLs -> BulkPersistentDatabase, all urls are saved in one transaction
+func (sdb *sqlDatabase) SaveShortenedURLs(urls []*short.ShortURL) (err error) {
+	var tx *sql.Tx
+	if tx, err = sdb.db.Begin(); err != nil {
+		return
+	}
+	var stmt *sql.Stmt
+	if stmt, err = tx.Prepare(sdb.dialect.upsert(sdb.table("short_urls"), "id", sqlNewShortURLColumns...)); err != nil {
+		_ = tx.Rollback()
+		return
+	}
+	defer stmt.Close()
+	var dedupStmt *sql.Stmt
+	if dedupStmt, err = tx.Prepare(sdb.dialect.upsert(sdb.table("dedup"), "hash", "id")); err != nil {
+		_ = tx.Rollback()
+		return
+	}
+	defer dedupStmt.Close()
+	for _, u := range urls {
+		var args []interface{}
+		if args, err = sqlShortURLArgs(u); err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if _, err = stmt.Exec(append(args, int64(0))...); err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if hash := u.DedupHash(); hash != "" {
+			if _, err = dedupStmt.Exec(hash, u.ID.String()); err != nil {
+				_ = tx.Rollback()
+				return
+			}
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	for _, u := range urls {
+		if err = sdb.cache.UpdateCache(u); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// sqlShortURLColumns -> upsert columns of short urls besides the id (the click counter is not changed)
+var sqlShortURLColumns = []string{"expiration_date", "max_clicks", "owner", "created", "data"}
+
+// sqlNewShortURLColumns -> upsert columns of new short urls, sqlShortURLColumns and the click counter
+var sqlNewShortURLColumns = append(append([]string{}, sqlShortURLColumns...), "clicks")
+
+// sqlShortURLArgs returns the values of the upsert columns id and sqlShortURLColumns
+func sqlShortURLArgs(short *short.ShortURL) (args []interface{}, err error) {
+	var data []byte
+	if data, err = json.Marshal(short); err != nil {
+		return
+	}
+	var expiration sql.NullInt64
+	if short.ExpirationDate != nil {
+		expiration = sql.NullInt64{Int64: short.ExpirationDate.Unix(), Valid: true}
+	}
+	owner := sql.NullString{String: short.Owner.String(), Valid: short.Owner != ""}
+	created := short.CreationDate.UnixNano() / int64(time.Millisecond)
+	return []interface{}{short.ID.String(), expiration, int64(short.MaxClicks), owner, created, string(data)}, nil
+}
+
+func (sdb *sqlDatabase) DeleteShortenedURL(id *short.ShortID) (err error) {
+	_, err = sdb.db.Exec(sdb.query(`DELETE FROM {prefix}short_urls WHERE id = ?`), id.String())
+	if err == nil {
+		_, err = sdb.db.Exec(sdb.query(`DELETE FROM {prefix}dedup WHERE id = ?`), id.String())
+	}
+	if err == nil {
+		err = sdb.cache.BreakCache(id)
+	}
+	return
+}
+
+// UpdateShortenedURL saves the changed {short} (keeping its clicks) and breaks the cache of all nodes
+func (sdb *sqlDatabase) UpdateShortenedURL(short *short.ShortURL) (err error) {
+	if err = sdb.saveShortenedURL(short, false); err != nil {
+		return
+	}
+	err = sdb.cache.BreakCache(&short.ID)
+	return
+}
+
+func (sdb *sqlDatabase) FindDuplicate(hash string) (res *short.ShortURL, err error) {
+	var id string
+	if err = sdb.db.QueryRow(sdb.query(`SELECT id FROM {prefix}dedup WHERE hash = ?`), hash).
+		Scan(&id); err != nil {
+		return
+	}
+	return findDuplicate(sdb, short.ShortID(id), hash)
+}
+
+func (sdb *sqlDatabase) FindShortenedURL(id *short.ShortID) (res *short.ShortURL, err error) {
+	// check cache
+	if u := sdb.cache.GetShortURL(id); u != nil {
+		return u, nil
+	}
+	var data string
+	if err = sdb.db.QueryRow(sdb.query(`SELECT data FROM {prefix}short_urls WHERE id = ?`), id.String()).
+		Scan(&data); err != nil {
+		return
+	}
+	if err = json.Unmarshal([]byte(data), &res); err != nil {
+		return nil, err
+	}
+	err = sdb.cache.UpdateCache(res)
+	return
+}
+
+func (sdb *sqlDatabase) ShortURLAvailable(id *short.ShortID) bool {
+	if u := sdb.cache.GetShortURL(id); u != nil {
+		return false
+	}
+	return shortURLAvailable(sdb, id)
+}
+
+/*
+ * ==================================================================================================
+ *                          E X P I R A T I O N   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (sdb *sqlDatabase) ConsumeClick(id *short.ShortID, max uint64) (ok bool, err error) {
+	// the conditional update is atomic, even with multiple nodes
+	var res sql.Result
+	if res, err = sdb.db.Exec(sdb.query(`UPDATE {prefix}short_urls SET clicks = clicks + 1
+		WHERE id = ? AND clicks < ?`), id.String(), int64(max)); err != nil {
+		return
+	}
+	var n int64
+	if n, err = res.RowsAffected(); err != nil {
+		return
+	}
+	return n == 1, nil
+}
+
+func (sdb *sqlDatabase) DecrementCounter(name string, n uint64) (err error) {
+	_, err = sdb.db.Exec(sdb.query(`UPDATE {prefix}counters
+		SET value = CASE WHEN value > ? THEN value - ? ELSE 0 END WHERE name = ?`), n, n, name)
+	return
+}
+
+func (sdb *sqlDatabase) IncrementCounter(name string) (n uint64, err error) {
+	if err = sdb.sweepCounters(time.Now()); err != nil {
+		return
+	}
+	if n, err = sdb.incrementCounter(name); err != nil {
+		// another node may have inserted the counter first, the UPDATE succeeds now
+		n, err = sdb.incrementCounter(name)
+	}
+	return
+}
+
+// incrementCounter increments the counter in a transaction.
+// The row is locked by the UPDATE until the end of the transaction, so the value is read atomically.
+func (sdb *sqlDatabase) incrementCounter(name string) (n uint64, err error) {
+	var tx *sql.Tx
+	if tx, err = sdb.db.Begin(); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var res sql.Result
+	if res, err = tx.Exec(sdb.query(`UPDATE {prefix}counters SET value = value + 1 WHERE name = ?`), name); err != nil {
+		return
+	}
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return
+	}
+	if affected == 0 {
+		if _, err = tx.Exec(sdb.query(`INSERT INTO {prefix}counters (name, value) VALUES (?, 1)`), name); err != nil {
+			return
+		}
+	}
+	var value int64
+	if err = tx.QueryRow(sdb.query(`SELECT value FROM {prefix}counters WHERE name = ?`), name).
+		Scan(&value); err != nil {
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	return uint64(value), nil
+}
+
+func (sdb *sqlDatabase) ExpireCounter(name string, at time.Time) (err error) {
+	_, err = sdb.db.Exec(sdb.query(`UPDATE {prefix}counters SET expires_at = ? WHERE name = ?`), at.Unix(), name)
+	return
+}
+
+// sweepCounters removes the expired counters, at most once per rateLimitSweepInterval
+func (sdb *sqlDatabase) sweepCounters(now time.Time) (err error) {
+	if !sdb.counterSweep.due(now) {
+		return
+	}
+	_, err = sdb.db.Exec(sdb.query(`DELETE FROM {prefix}counters WHERE expires_at IS NOT NULL AND expires_at <= ?`),
+		now.Unix())
+	return
+}
+
+func (sdb *sqlDatabase) FindExpiredURLs() (res []*short.ShortURL, err error) {
+	var rows *sql.Rows
+	if rows, err = sdb.db.Query(sdb.query(`SELECT data FROM {prefix}short_urls
+		WHERE (expiration_date IS NOT NULL AND expiration_date < ?)
+		OR (max_clicks > 0 AND clicks >= max_clicks)`), time.Now().Unix()); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var u *short.ShortURL
+		if err = json.Unmarshal([]byte(data), &u); err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	err = rows.Err()
+	return
+}
+
+func (sdb *sqlDatabase) GetLastExpirationCheck() (m *LastExpirationCheckMeta) {
+	m = &LastExpirationCheckMeta{
+		LastCheck: time.Unix(5, 0),
+	}
+	var data string
+	if err := sdb.db.QueryRow(sdb.query(`SELECT meta_value FROM {prefix}meta WHERE meta_key = ?`), "last_expired").
+		Scan(&data); err != nil {
+		return
+	}
+	_ = json.Unmarshal([]byte(data), m)
+	return
+}
+
+func (sdb *sqlDatabase) UpdateLastExpirationCheck(t time.Time) {
+	data, err := json.Marshal(&LastExpirationCheckMeta{LastCheck: t})
+	if err != nil {
+		return
+	}
+	_, _ = sdb.db.Exec(sdb.dialect.upsert(sdb.table("meta"), "meta_key", "meta_value"),
+		"last_expired", string(data))
+}
+
+/*
+ * ==================================================================================================
+ *                          T E M P L A T E   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (sdb *sqlDatabase) FindTemplates() (templates []*tpl.Template, err error) {
+	templates = []*tpl.Template{}
+	var rows *sql.Rows
+	if rows, err = sdb.db.Query(sdb.query(`SELECT data FROM {prefix}templates`)); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return
+		}
+		t := new(tpl.Template)
+		if err = json.Unmarshal([]byte(data), t); err != nil {
+			return
+		}
+		templates = append(templates, t)
+	}
+	err = rows.Err()
+	return
+}
+
+func (sdb *sqlDatabase) SaveTemplate(t *tpl.Template) (err error) {
+	var data []byte
+	if data, err = json.Marshal(t); err != nil {
+		return
+	}
+	_, err = sdb.db.Exec(sdb.dialect.upsert(sdb.table("templates"), "template_url", "data"),
+		t.TemplateURL, string(data))
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                             P O O L   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (sdb *sqlDatabase) FindPool(id *short.PoolID) (pool *short.Pool, err error) {
+	var data string
+	if err = sdb.db.QueryRow(sdb.query(`SELECT data FROM {prefix}pools WHERE id = ?`), id.String()).
+		Scan(&data); err != nil {
+		return
+	}
+	pool = new(short.Pool)
+	if err = json.Unmarshal([]byte(data), pool); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (sdb *sqlDatabase) SavePool(pool *short.Pool) (err error) {
+	var data []byte
+	if data, err = json.Marshal(pool); err != nil {
+		return
+	}
+	_, err = sdb.db.Exec(sdb.dialect.upsert(sdb.table("pools"), "id", "data"),
+		pool.ID.String(), string(data))
+	return
+}
+
+/*
+ * ==================================================================================================
+ *                          A C C O U N T   I M P L E M E N T A T I O N S
+ * ==================================================================================================
+ */
+
+func (sdb *sqlDatabase) SaveAccount(account *short.Account) (err error) {
+	var data []byte
+	if data, err = json.Marshal(account); err != nil {
+		return
+	}
+	_, err = sdb.db.Exec(sdb.dialect.upsert(sdb.table("accounts"), "id", "key_hash", "data"),
+		account.ID.String(), account.KeyHash, string(data))
+	return
+}
+
+func (sdb *sqlDatabase) DeleteAccount(id *short.AccountID) (err error) {
+	_, err = sdb.db.Exec(sdb.query(`DELETE FROM {prefix}accounts WHERE id = ?`), id.String())
+	return
+}
+
+func (sdb *sqlDatabase) FindAccount(id *short.AccountID) (*short.Account, error) {
+	return sdb.findAccount(`SELECT data FROM {prefix}accounts WHERE id = ?`, id.String())
+}
+
+func (sdb *sqlDatabase) FindAccountByKey(keyHash string) (*short.Account, error) {
+	return sdb.findAccount(`SELECT data FROM {prefix}accounts WHERE key_hash = ?`, keyHash)
+}
+
+func (sdb *sqlDatabase) findAccount(query string, args ...interface{}) (account *short.Account, err error) {
+	var data string
+	if err = sdb.db.QueryRow(sdb.query(query), args...).Scan(&data); err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(data), &account)
+	return
+}
+
+func (sdb *sqlDatabase) FindAccounts() (accounts []*short.Account, err error) {
+	var rows *sql.Rows
+	if rows, err = sdb.db.Query(sdb.query(`SELECT data FROM {prefix}accounts`)); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return
+		}
+		var a *short.Account
+		if err = json.Unmarshal([]byte(data), &a); err != nil {
+			return
+		}
+		accounts = append(accounts, a)
+	}
+	err = rows.Err()
+	return
+}
+
+func (sdb *sqlDatabase) FindShortURLsByOwner(owner *short.AccountID, offset, limit int) (urls []*short.ShortURL,
+	total int, err error) {
+	if err = sdb.db.QueryRow(sdb.query(`SELECT COUNT(*) FROM {prefix}short_urls WHERE owner = ?`), owner.String()).
+		Scan(&total); err != nil {
+		return
+	}
+	urls = []*short.ShortURL{}
+	if offset >= total || limit <= 0 {
+		return
+	}
+	var rows *sql.Rows
+	if rows, err = sdb.db.Query(sdb.query(`SELECT data FROM {prefix}short_urls WHERE owner = ?
+		ORDER BY created DESC, id DESC LIMIT ? OFFSET ?`), owner.String(), limit, offset); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return nil, 0, err
+		}
+		var u *short.ShortURL
+		if err = json.Unmarshal([]byte(data), &u); err != nil {
+			return nil, 0, err
+		}
+		urls = append(urls, u)
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sqlDialect holds everything that differs between the supported SQL databases
+type sqlDialect struct {
+	// driver is the name the driver is registered with in database/sql
+	driver string
+	// dollarPlaceholders -> $1, $2, ... instead of ?
+	dollarPlaceholders bool
+	// onDuplicateKey -> MySQL style upsert instead of ON CONFLICT
+	onDuplicateKey bool
+	// indexExistsQuery -> counts the index (args: table, index) if CREATE INDEX IF NOT EXISTS is not supported
+	indexExistsQuery string
+	// columnExistsQuery -> counts the column (args: table, column) if ADD COLUMN IF NOT EXISTS is not supported
+	columnExistsQuery string
+	// lockQuery and unlockQuery take and release a session lock (arg: name) around the migrations,
+	// lockQuery returns 1 once the lock was taken. SQLite locks the whole database file anyway.
+	lockQuery   string
+	unlockQuery string
+}
+
+var (
+	sqlDialectMySQL = &sqlDialect{
+		driver:         "mysql",
+		onDuplicateKey: true,
+		indexExistsQuery: `SELECT COUNT(*) FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?`,
+		columnExistsQuery: `SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`,
+		lockQuery:   `SELECT GET_LOCK(?, 60)`,
+		unlockQuery: `SELECT RELEASE_LOCK(?)`,
+	}
+	sqlDialectPostgres = &sqlDialect{
+		driver:             "postgres",
+		dollarPlaceholders: true,
+		lockQuery:          `SELECT 1 FROM pg_advisory_lock(hashtext(?))`,
+		unlockQuery:        `SELECT pg_advisory_unlock(hashtext(?))`,
+	}
+	sqlDialectSQLite = &sqlDialect{
+		driver:            "sqlite",
+		columnExistsQuery: `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`,
+	}
+)
+
+// findSQLDialect returns the dialect for the driver name given in the config
+func findSQLDialect(driver string) (*sqlDialect, error) {
+	switch strings.ToLower(driver) {
+	case "mysql", "mariadb", "maria":
+		return sqlDialectMySQL, nil
+	case "postgres", "postgresql", "pg":
+		return sqlDialectPostgres, nil
+	case "sqlite", "sqlite3", "":
+		return sqlDialectSQLite, nil
+	}
+	return nil, fmt.Errorf("unknown sql driver: %s", driver)
+}
+
+// rebind replaces the ? placeholders of a query with the placeholders of the dialect
+func (d *sqlDialect) rebind(query string) string {
+	if !d.dollarPlaceholders {
+		return query
+	}
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// upsert builds an INSERT statement which updates all {columns} except the {key} column
+// if a row with the same {key} already exists
+func (d *sqlDialect) upsert(table, key string, columns ...string) string {
+	all := append([]string{key}, columns...)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(all)), ", ")
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(all, ", "), placeholders))
+	if d.onDuplicateKey {
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, c := range columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(fmt.Sprintf("%s = VALUES(%s)", c, c))
+		}
+	} else {
+		b.WriteString(fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET ", key))
+		for i, c := range columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(fmt.Sprintf("%s = excluded.%s", c, c))
+		}
+	}
+	return d.rebind(b.String())
+}
+
+var (
+	sqlCreateIndexRegex = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX IF NOT EXISTS (\S+) ON (\S+)`)
+	sqlAddColumnRegex   = regexp.MustCompile(`^ALTER TABLE (\S+) ADD COLUMN IF NOT EXISTS (\S+)`)
+)
+
+// sqlMySQLOnly -> prefix of migration statements which are only applied to MySQL / MariaDB
+const sqlMySQLOnly = "{mysql} "
+
+// migration returns the migration statement {stmt} in a form the dialect understands,
+// or an empty {res} if the statement is not meant for the dialect (see sqlMySQLOnly).
+// If the dialect doesn't support the IF NOT EXISTS of {stmt}, it is removed and {exists} (with {args})
+// counts the index or column, so the caller can skip the statement if it was already applied.
+func (d *sqlDialect) migration(stmt string) (res, exists string, args []interface{}) {
+	if strings.HasPrefix(stmt, sqlMySQLOnly) {
+		if d != sqlDialectMySQL {
+			return "", "", nil
+		}
+		stmt = strings.TrimPrefix(stmt, sqlMySQLOnly)
+	}
+	if m := sqlCreateIndexRegex.FindStringSubmatch(stmt); m != nil && d.indexExistsQuery != "" {
+		return strings.Replace(stmt, " IF NOT EXISTS", "", 1), d.rebind(d.indexExistsQuery), []interface{}{m[3], m[2]}
+	}
+	if m := sqlAddColumnRegex.FindStringSubmatch(stmt); m != nil && d.columnExistsQuery != "" {
+		return strings.Replace(stmt, " IF NOT EXISTS", "", 1), d.rebind(d.columnExistsQuery), []interface{}{m[1], m[2]}
+	}
+	return stmt, "", nil
+}
+
+/*
+ * ==================================================================================================
+ *                                    M I G R A T I O N S
+ * ==================================================================================================
+ */
+
+// sqlMigrations are applied in order and only once per database.
+// {prefix} is replaced with the configured table prefix, statements starting with {mysql} are only applied to MySQL.
+// MySQL commits DDL implicitly, so a failed migration may be partially applied:
+// every statement has to be idempotent (IF NOT EXISTS, see sqlDialect.migration).
+// NEVER change an existing migration, always append a new one.
+var sqlMigrations = [][]string{
+	// 1: initial schema
+	{
+		`CREATE TABLE IF NOT EXISTS {prefix}short_urls (
+			id VARCHAR(64) NOT NULL PRIMARY KEY,
+			expiration_date BIGINT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}short_urls_expiration ON {prefix}short_urls (expiration_date)`,
+		`CREATE TABLE IF NOT EXISTS {prefix}meta (
+			meta_key VARCHAR(64) NOT NULL PRIMARY KEY,
+			meta_value TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS {prefix}templates (
+			template_url VARCHAR(255) NOT NULL PRIMARY KEY,
+			data TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS {prefix}pools (
+			id VARCHAR(64) NOT NULL PRIMARY KEY,
+			data TEXT NOT NULL
+		)`,
+	},
+	// 2: click limit
+	{
+		`ALTER TABLE {prefix}short_urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE {prefix}short_urls ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0`,
+	},
+	// 3: reverse index for deduplication (ShortURL.DedupHash -> id)
+	{
+		`CREATE TABLE IF NOT EXISTS {prefix}dedup (
+			hash VARCHAR(64) NOT NULL PRIMARY KEY,
+			id VARCHAR(64) NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {prefix}dedup_id ON {prefix}dedup (id)`,
+	},
+	// 4: counters of the id generators
+	{
+		`CREATE TABLE IF NOT EXISTS {prefix}counters (
+			name VARCHAR(64) NOT NULL PRIMARY KEY,
+			value BIGINT NOT NULL
+		)`,
+	},
+	// 5: accounts and the owners of short urls (created = creation date in unix milliseconds)
+	{
+		`CREATE TABLE IF NOT EXISTS {prefix}accounts (
+			id VARCHAR(64) NOT NULL PRIMARY KEY,
+			key_hash VARCHAR(64) NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS {prefix}accounts_key_hash ON {prefix}accounts (key_hash)`,
+		`ALTER TABLE {prefix}short_urls ADD COLUMN IF NOT EXISTS owner VARCHAR(64) NULL`,
+		`ALTER TABLE {prefix}short_urls ADD COLUMN IF NOT EXISTS created BIGINT NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS {prefix}short_urls_owner ON {prefix}short_urls (owner, created)`,
+	},
+	// 6: expiration of counters (unix seconds), e.g. of the daily quota
+	{
+		`ALTER TABLE {prefix}counters ADD COLUMN IF NOT EXISTS expires_at BIGINT NULL`,
+		`CREATE INDEX IF NOT EXISTS {prefix}counters_expiration ON {prefix}counters (expires_at)`,
+	},
+	// 7: case-sensitive ids, the default collation of MySQL / MariaDB ignores the case (MODIFY is idempotent)
+	{
+		`{mysql} ALTER TABLE {prefix}short_urls
+			MODIFY id VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+			MODIFY owner VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NULL`,
+		`{mysql} ALTER TABLE {prefix}dedup
+			MODIFY hash VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+			MODIFY id VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL`,
+		`{mysql} ALTER TABLE {prefix}pools
+			MODIFY id VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL`,
+		`{mysql} ALTER TABLE {prefix}accounts
+			MODIFY id VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL`,
+	},
+}
@@ -0,0 +1,179 @@
+package db_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db/dbtest"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db/dbtest/minimongo"
+)
+
+// The MongoDB tests run against minimongo, unless GME_TEST_MONGO_URI
+// points to a (disposable) MongoDB server
+const mongoURIEnv = "GME_TEST_MONGO_URI"
+
+func newRedisConfig(t *testing.T) *config.RedisConfig {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal("starting miniredis:", err)
+	}
+	t.Cleanup(mr.Close)
+	return &config.RedisConfig{
+		Addr: mr.Addr(),
+	}
+}
+
+func newBBoltConfig(t *testing.T) *config.BBoltConfig {
+	return &config.BBoltConfig{
+		Path:                  filepath.Join(t.TempDir(), "bbolt.db"),
+		FileMode:              0600,
+		ShortedURLsBucketName: "short-urls",
+		MetaBucketName:        "meta",
+		TplBucketName:         "tpl",
+		PoolBucketName:        "pool",
+		StatsBucketName:       "stats",
+	}
+}
+
+func newMongoConfig(t *testing.T) *config.MongoConfig {
+	uri := os.Getenv(mongoURIEnv)
+	if uri == "" {
+		mm, err := minimongo.Run()
+		if err != nil {
+			t.Fatal("starting minimongo:", err)
+		}
+		t.Cleanup(mm.Close)
+		uri = mm.URI()
+	}
+	return &config.MongoConfig{
+		ApplyURI:           uri,
+		Database:           "gme-test-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		ShortURLCollection: "short-urls",
+		MetaCollection:     "meta",
+		TplCollection:      "tpl",
+		PoolCollection:     "pool",
+		StatsCollection:    "stats",
+	}
+}
+
+// mustPersistent fails the test if the PersistentDatabase could not be created
+func mustPersistent(t *testing.T) func(db.PersistentDatabase, error) db.PersistentDatabase {
+	return func(database db.PersistentDatabase, err error) db.PersistentDatabase {
+		if err != nil {
+			t.Fatal("creating persistent database:", err)
+		}
+		return database
+	}
+}
+
+// mustStats fails the test if the StatsDatabase could not be created
+func mustStats(t *testing.T) func(db.StatsDatabase, error) db.StatsDatabase {
+	return func(database db.StatsDatabase, err error) db.StatsDatabase {
+		if err != nil {
+			t.Fatal("creating stats database:", err)
+		}
+		return database
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                            P E R M A N E N T  D A T A B A S E
+ * ==================================================================================================
+ */
+
+func TestMemoryDatabase(t *testing.T) {
+	dbtest.RunPersistentDatabaseSuite(t, func(t *testing.T) db.PersistentDatabase {
+		return mustPersistent(t)(db.NewMemoryDatabase(db.NewLocalCache()))
+	})
+}
+
+func TestBBoltDatabase(t *testing.T) {
+	dbtest.RunPersistentDatabaseSuite(t, func(t *testing.T) db.PersistentDatabase {
+		return mustPersistent(t)(db.NewBBoltDatabase(newBBoltConfig(t), db.NewLocalCache()))
+	})
+}
+
+func TestSQLiteDatabase(t *testing.T) {
+	dbtest.RunPersistentDatabaseSuite(t, func(t *testing.T) db.PersistentDatabase {
+		return mustPersistent(t)(db.NewSQLDatabase(&config.SQLConfig{
+			Driver:      "sqlite3",
+			DSN:         "file:" + filepath.Join(t.TempDir(), "gme.db"),
+			TablePrefix: "gme_",
+		}, db.NewLocalCache()))
+	})
+}
+
+// TestSQLiteMigrationsIdempotent -> migrations which were applied, but not recorded, can be applied again
+func TestSQLiteMigrationsIdempotent(t *testing.T) {
+	cfg := &config.SQLConfig{
+		Driver:      "sqlite3",
+		DSN:         "file:" + filepath.Join(t.TempDir(), "gme.db"),
+		TablePrefix: "gme_",
+	}
+	mustPersistent(t)(db.NewSQLDatabase(cfg, db.NewLocalCache()))
+
+	raw, err := sql.Open("sqlite", cfg.DSN)
+	if err != nil {
+		t.Fatal("opening sqlite:", err)
+	}
+	defer raw.Close()
+	if _, err = raw.Exec(`DELETE FROM gme_schema_migrations`); err != nil {
+		t.Fatal("forgetting migrations:", err)
+	}
+
+	database := mustPersistent(t)(db.NewSQLDatabase(cfg, db.NewLocalCache()))
+	u := dbtest.NewShortURL("migrated", nil)
+	if err = database.SaveShortenedURL(u); err != nil {
+		t.Fatalf("saving %s: %v", u.ID, err)
+	}
+}
+
+func TestRedisDatabase(t *testing.T) {
+	dbtest.RunPersistentDatabaseSuite(t, func(t *testing.T) db.PersistentDatabase {
+		return mustPersistent(t)(db.NewRedisDatabase(newRedisConfig(t)))
+	})
+}
+
+func TestMongoDatabase(t *testing.T) {
+	dbtest.RunPersistentDatabaseSuite(t, func(t *testing.T) db.PersistentDatabase {
+		return mustPersistent(t)(db.NewMongoDatabase(newMongoConfig(t), db.NewLocalCache()))
+	})
+}
+
+/*
+ * ==================================================================================================
+ *                            S T A T S   D A T A B A S E
+ * ==================================================================================================
+ */
+
+func TestMemoryStats(t *testing.T) {
+	dbtest.RunStatsDatabaseSuite(t, func(t *testing.T) db.StatsDatabase {
+		return mustStats(t)(db.NewMemoryStats(nil))
+	})
+}
+
+func TestRedisStats(t *testing.T) {
+	dbtest.RunStatsDatabaseSuite(t, func(t *testing.T) db.StatsDatabase {
+		return mustStats(t)(db.NewRedisStats(newRedisConfig(t), nil))
+	})
+}
+
+func TestBBoltStats(t *testing.T) {
+	dbtest.RunStatsDatabaseSuite(t, func(t *testing.T) db.StatsDatabase {
+		return mustStats(t)(db.NewBBoltStats(newBBoltConfig(t), nil))
+	})
+}
+
+func TestMongoStats(t *testing.T) {
+	dbtest.RunStatsDatabaseSuite(t, func(t *testing.T) db.StatsDatabase {
+		return mustStats(t)(db.NewMongoStats(newMongoConfig(t), nil))
+	})
+}
@@ -0,0 +1,162 @@
+package dbtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// mustAccounts returns the AccountDatabase of the {database}, every PersistentDatabase has to implement it
+func mustAccounts(t *testing.T, database db.PersistentDatabase) db.AccountDatabase {
+	t.Helper()
+	accounts, ok := database.(db.AccountDatabase)
+	if !ok {
+		t.Fatalf("%s doesn't implement db.AccountDatabase", database.ServiceName())
+	}
+	return accounts
+}
+
+func assertAccount(t *testing.T, expected, actual *short.Account) {
+	t.Helper()
+	if actual == nil {
+		t.Fatalf("expected account %s, got nil", expected.ID)
+	}
+	if actual.ID != expected.ID || actual.Name != expected.Name || actual.KeyHash != expected.KeyHash ||
+		actual.DailyQuota != expected.DailyQuota || !sameTime(actual.Created, expected.Created) {
+		t.Errorf("expected account %+v, got %+v", *expected, *actual)
+	}
+}
+
+func testAccounts(t *testing.T, database db.PersistentDatabase) {
+	accounts := mustAccounts(t, database)
+	if a, err := accounts.FindAccount(&[]short.AccountID{"missing"}[0]); err == nil && a != nil {
+		t.Errorf("expected no account, got %+v", a)
+	}
+	if a, err := accounts.FindAccountByKey(short.HashAPIKey("missing")); err == nil && a != nil {
+		t.Errorf("expected no account for an unknown key, got %+v", a)
+	}
+
+	alice := &short.Account{
+		ID:         "alice",
+		Name:       "Alice",
+		Created:    time.Now(),
+		KeyHash:    short.HashAPIKey("key-alice"),
+		DailyQuota: 10,
+	}
+	bob := &short.Account{
+		ID:      "bob",
+		Name:    "Bob",
+		Created: time.Now(),
+		KeyHash: short.HashAPIKey("key-bob"),
+	}
+	for _, a := range []*short.Account{alice, bob} {
+		if err := accounts.SaveAccount(a); err != nil {
+			t.Fatalf("saving account %s: %v", a.ID, err)
+		}
+	}
+
+	found, err := accounts.FindAccount(&alice.ID)
+	if err != nil {
+		t.Fatalf("finding account: %v", err)
+	}
+	assertAccount(t, alice, found)
+	if found, err = accounts.FindAccountByKey(bob.KeyHash); err != nil {
+		t.Fatalf("finding account by key: %v", err)
+	}
+	assertAccount(t, bob, found)
+
+	all, err := accounts.FindAccounts()
+	if err != nil {
+		t.Fatalf("finding accounts: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected 2 accounts, got %d", len(all))
+	}
+
+	// a new key replaces the old one
+	alice.KeyHash = short.HashAPIKey("key-alice-2")
+	if err = accounts.SaveAccount(alice); err != nil {
+		t.Fatalf("saving account: %v", err)
+	}
+	if a, err := accounts.FindAccountByKey(short.HashAPIKey("key-alice")); err == nil && a != nil {
+		t.Errorf("expected the old key to be invalid, got %+v", a)
+	}
+	if found, err = accounts.FindAccountByKey(alice.KeyHash); err != nil {
+		t.Fatalf("finding account by new key: %v", err)
+	}
+	assertAccount(t, alice, found)
+
+	// delete
+	if err = accounts.DeleteAccount(&bob.ID); err != nil {
+		t.Fatalf("deleting account: %v", err)
+	}
+	if a, err := accounts.FindAccount(&bob.ID); err == nil && a != nil {
+		t.Errorf("expected account to be deleted, got %+v", a)
+	}
+	if a, err := accounts.FindAccountByKey(bob.KeyHash); err == nil && a != nil {
+		t.Errorf("expected the key of the deleted account to be invalid, got %+v", a)
+	}
+}
+
+func testShortURLsByOwner(t *testing.T, database db.PersistentDatabase) {
+	accounts := mustAccounts(t, database)
+	owner := short.AccountID("alice")
+
+	// 5 urls of alice (newest last), 1 of bob and 1 anonymous url
+	start := time.Now().Add(-time.Hour)
+	var urls []*short.ShortURL
+	for _, id := range []string{"own-1", "own-2", "own-3", "own-4", "own-5"} {
+		u := NewShortURL(id, nil)
+		u.Owner = owner
+		u.CreationDate = start.Add(time.Duration(len(urls)) * time.Minute)
+		mustSave(t, database, u)
+		urls = append(urls, u)
+	}
+	other := NewShortURL("other", nil)
+	other.Owner = "bob"
+	mustSave(t, database, other)
+	mustSave(t, database, NewShortURL("anonymous", nil))
+
+	page, total, err := accounts.FindShortURLsByOwner(&owner, 0, 2)
+	if err != nil {
+		t.Fatalf("finding urls by owner: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("expected 5 urls in total, got %d", total)
+	}
+	if len(page) != 2 {
+		t.Fatalf("expected 2 urls, got %d", len(page))
+	}
+	assertShortURL(t, urls[4], page[0])
+	assertShortURL(t, urls[3], page[1])
+
+	if page, _, err = accounts.FindShortURLsByOwner(&owner, 4, 2); err != nil {
+		t.Fatalf("finding urls by owner: %v", err)
+	}
+	if len(page) != 1 {
+		t.Fatalf("expected 1 url on the last page, got %d", len(page))
+	}
+	assertShortURL(t, urls[0], page[0])
+
+	if page, total, err = accounts.FindShortURLsByOwner(&owner, 10, 2); err != nil || len(page) != 0 || total != 5 {
+		t.Errorf("expected an empty page after the end, got %d urls, total %d, %v", len(page), total, err)
+	}
+
+	// deleted urls are not listed
+	if err = database.DeleteShortenedURL(&urls[4].ID); err != nil {
+		t.Fatalf("deleting: %v", err)
+	}
+	if page, total, err = accounts.FindShortURLsByOwner(&owner, 0, 10); err != nil {
+		t.Fatalf("finding urls by owner: %v", err)
+	}
+	if total != 4 || len(page) != 4 {
+		t.Errorf("expected 4 urls after deletion, got %d of %d", len(page), total)
+	}
+
+	nobody := short.AccountID("nobody")
+	if page, total, err = accounts.FindShortURLsByOwner(&nobody, 0, 10); err != nil || len(page) != 0 || total != 0 {
+		t.Errorf("expected no urls, got %d urls, total %d, %v", len(page), total, err)
+	}
+}
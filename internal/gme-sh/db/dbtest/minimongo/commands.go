@@ -0,0 +1,591 @@
+package minimongo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	codeDuplicateKey    = 11000
+	codeCommandNotFound = 59
+	codeBadValue        = 2
+)
+
+// index -> unique index, the _id index always exists
+type index struct {
+	name string
+	keys []string
+}
+
+type collection struct {
+	docs    []bson.D
+	indexes []index
+}
+
+// commandError is sent back as {ok: 0, code, errmsg}
+type commandError struct {
+	code int32
+	msg  string
+}
+
+func (e *commandError) Error() string {
+	return e.msg
+}
+
+func badValue(err error) *commandError {
+	return &commandError{code: codeBadValue, msg: err.Error()}
+}
+
+func (s *Server) collection(db, name string, create bool) *collection {
+	colls, ok := s.databases[db]
+	if !ok {
+		if !create {
+			return nil
+		}
+		colls = make(map[string]*collection)
+		s.databases[db] = colls
+	}
+	c, ok := colls[name]
+	if !ok && create {
+		c = &collection{indexes: []index{{name: "_id_", keys: []string{"_id"}}}}
+		colls[name] = c
+	}
+	return c
+}
+
+// duplicate returns an error if doc (at position pos, -1 for new documents) violates a unique index
+func (c *collection) duplicate(doc bson.D, pos int) *commandError {
+	for _, idx := range c.indexes {
+		for i, other := range c.docs {
+			if i == pos {
+				continue
+			}
+			same := true
+			for _, k := range idx.keys {
+				a, _ := getPath(doc, k)
+				b, _ := getPath(other, k)
+				if compare(a, b) != 0 {
+					same = false
+					break
+				}
+			}
+			if same {
+				return &commandError{
+					code: codeDuplicateKey,
+					msg:  fmt.Sprintf("E11000 duplicate key error index: %s", idx.name),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// execute runs a command and returns the reply document
+func (s *Server) execute(db string, cmd bson.D) bson.D {
+	if len(cmd) == 0 {
+		return errorReply(&commandError{code: codeBadValue, msg: "empty command"})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := cmd[0].Key
+	coll, _ := cmd[0].Value.(string)
+	var (
+		reply bson.D
+		err   *commandError
+	)
+	switch strings.ToLower(name) {
+	case "ismaster", "hello":
+		reply = bson.D{
+			{Key: "ismaster", Value: true},
+			{Key: "maxBsonObjectSize", Value: int32(16 * 1024 * 1024)},
+			{Key: "maxMessageSizeBytes", Value: int32(48000000)},
+			{Key: "maxWriteBatchSize", Value: int32(100000)},
+			{Key: "localTime", Value: primitive.NewDateTimeFromTime(time.Now())},
+			{Key: "minWireVersion", Value: int32(0)},
+			{Key: "maxWireVersion", Value: int32(maxWireVersion)},
+		}
+	case "ping", "endsessions", "killcursors":
+	case "buildinfo":
+		reply = bson.D{{Key: "version", Value: "4.2.0"}}
+	case "insert":
+		reply, err = s.insert(db, coll, cmd)
+	case "update":
+		reply, err = s.update(db, coll, cmd)
+	case "delete":
+		reply, err = s.delete(db, coll, cmd)
+	case "findandmodify":
+		reply, err = s.findAndModify(db, coll, cmd)
+	case "find":
+		reply, err = s.find(db, coll, cmd)
+	case "aggregate":
+		reply, err = s.aggregate(db, coll, cmd)
+	case "createindexes":
+		reply, err = s.createIndexes(db, coll, cmd)
+	case "drop":
+		if colls, ok := s.databases[db]; ok {
+			delete(colls, coll)
+		}
+	case "dropdatabase":
+		delete(s.databases, db)
+	default:
+		err = &commandError{code: codeCommandNotFound, msg: "no such command: '" + name + "'"}
+	}
+	if err != nil {
+		return errorReply(err)
+	}
+	return append(reply, bson.E{Key: "ok", Value: 1.0})
+}
+
+func errorReply(err *commandError) bson.D {
+	return bson.D{
+		{Key: "ok", Value: 0.0},
+		{Key: "errmsg", Value: err.msg},
+		{Key: "code", Value: err.code},
+	}
+}
+
+func writeError(i int, err *commandError) bson.D {
+	return bson.D{
+		{Key: "index", Value: int32(i)},
+		{Key: "code", Value: err.code},
+		{Key: "errmsg", Value: err.msg},
+	}
+}
+
+func documents(cmd bson.D, key string) (docs []bson.D, err *commandError) {
+	arr, _ := get(cmd, key).(bson.A)
+	for _, v := range arr {
+		d, ok := v.(bson.D)
+		if !ok {
+			return nil, &commandError{code: codeBadValue, msg: key + " entries need to be documents"}
+		}
+		docs = append(docs, d)
+	}
+	return
+}
+
+func document(cmd bson.D, key string) bson.D {
+	d, _ := get(cmd, key).(bson.D)
+	return d
+}
+
+/*
+ * ==================================================================================================
+ *                            W R I T E S
+ * ==================================================================================================
+ */
+
+func (s *Server) insert(db, name string, cmd bson.D) (bson.D, *commandError) {
+	docs, err := documents(cmd, "documents")
+	if err != nil {
+		return nil, err
+	}
+	c := s.collection(db, name, true)
+	var (
+		n      int32
+		errors bson.A
+	)
+	for i, d := range docs {
+		d = clone(d)
+		if _, ok := lookup(d, "_id"); !ok {
+			d = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, d...)
+		}
+		if err := c.duplicate(d, -1); err != nil {
+			errors = append(errors, writeError(i, err))
+			break
+		}
+		c.docs = append(c.docs, d)
+		n++
+	}
+	reply := bson.D{{Key: "n", Value: n}}
+	if len(errors) > 0 {
+		reply = append(reply, bson.E{Key: "writeErrors", Value: errors})
+	}
+	return reply, nil
+}
+
+// upsert inserts a new document for an update without matches
+func (c *collection) upsert(filter, update bson.D) (bson.D, *commandError) {
+	doc, err := applyUpdate(upsertBase(filter), update, true)
+	if err != nil {
+		return nil, badValue(err)
+	}
+	if _, ok := lookup(doc, "_id"); !ok {
+		if id, ok := getPath(upsertBase(filter), "_id"); ok {
+			doc = append(bson.D{{Key: "_id", Value: id}}, doc...)
+		} else {
+			doc = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...)
+		}
+	}
+	if err := c.duplicate(doc, -1); err != nil {
+		return nil, err
+	}
+	c.docs = append(c.docs, doc)
+	return doc, nil
+}
+
+// modify applies an update to the document at position i
+func (c *collection) modify(i int, update bson.D) (modified bool, err *commandError) {
+	old := c.docs[i]
+	doc, e := applyUpdate(clone(old), update, false)
+	if e != nil {
+		return false, badValue(e)
+	}
+	if a, b := get(old, "_id"), get(doc, "_id"); compare(a, b) != 0 {
+		return false, &commandError{code: 66, msg: "the (immutable) field '_id' was found to have been altered"}
+	}
+	if err := c.duplicate(doc, i); err != nil {
+		return false, err
+	}
+	c.docs[i] = doc
+	return compare(old, doc) != 0, nil
+}
+
+func (s *Server) update(db, name string, cmd bson.D) (bson.D, *commandError) {
+	updates, err := documents(cmd, "updates")
+	if err != nil {
+		return nil, err
+	}
+	c := s.collection(db, name, true)
+	var (
+		matched, modified int32
+		upserted, errors  bson.A
+	)
+updates:
+	for i, u := range updates {
+		filter, update := document(u, "q"), document(u, "u")
+		multi, _ := get(u, "multi").(bool)
+		doUpsert, _ := get(u, "upsert").(bool)
+
+		found := false
+		for pos, d := range c.docs {
+			ok, e := matches(d, filter)
+			if e != nil {
+				errors = append(errors, writeError(i, badValue(e)))
+				break updates
+			}
+			if !ok {
+				continue
+			}
+			found = true
+			matched++
+			changed, err := c.modify(pos, update)
+			if err != nil {
+				errors = append(errors, writeError(i, err))
+				break updates
+			}
+			if changed {
+				modified++
+			}
+			if !multi {
+				break
+			}
+		}
+		if !found && doUpsert {
+			doc, err := c.upsert(filter, update)
+			if err != nil {
+				errors = append(errors, writeError(i, err))
+				break
+			}
+			matched++
+			upserted = append(upserted, bson.D{
+				{Key: "index", Value: int32(i)},
+				{Key: "_id", Value: get(doc, "_id")},
+			})
+		}
+	}
+	reply := bson.D{
+		{Key: "n", Value: matched},
+		{Key: "nModified", Value: modified},
+	}
+	if len(upserted) > 0 {
+		reply = append(reply, bson.E{Key: "upserted", Value: upserted})
+	}
+	if len(errors) > 0 {
+		reply = append(reply, bson.E{Key: "writeErrors", Value: errors})
+	}
+	return reply, nil
+}
+
+func (s *Server) delete(db, name string, cmd bson.D) (bson.D, *commandError) {
+	deletes, err := documents(cmd, "deletes")
+	if err != nil {
+		return nil, err
+	}
+	c := s.collection(db, name, false)
+	var n int32
+	for _, d := range deletes {
+		if c == nil {
+			break
+		}
+		filter := document(d, "q")
+		limit := toInt(get(d, "limit"))
+		kept := c.docs[:0]
+		for _, doc := range c.docs {
+			ok, e := matches(doc, filter)
+			if e != nil {
+				return nil, badValue(e)
+			}
+			if ok && (limit == 0 || int64(n) < limit) {
+				n++
+				continue
+			}
+			kept = append(kept, doc)
+		}
+		c.docs = kept
+	}
+	return bson.D{{Key: "n", Value: n}}, nil
+}
+
+func (s *Server) findAndModify(db, name string, cmd bson.D) (bson.D, *commandError) {
+	c := s.collection(db, name, true)
+	filter, update := document(cmd, "query"), document(cmd, "update")
+	remove, _ := get(cmd, "remove").(bool)
+	returnNew, _ := get(cmd, "new").(bool)
+	doUpsert, _ := get(cmd, "upsert").(bool)
+
+	docs, err := c.filter(filter, document(cmd, "sort"))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		value     interface{}
+		lastError = bson.D{{Key: "n", Value: int32(0)}}
+	)
+	switch {
+	case len(docs) > 0 && remove:
+		pos := docs[0]
+		value = clone(c.docs[pos])
+		c.docs = append(c.docs[:pos:pos], c.docs[pos+1:]...)
+		lastError = bson.D{{Key: "n", Value: int32(1)}}
+	case len(docs) > 0:
+		pos := docs[0]
+		old := clone(c.docs[pos])
+		if _, err := c.modify(pos, update); err != nil {
+			return nil, err
+		}
+		value = old
+		if returnNew {
+			value = clone(c.docs[pos])
+		}
+		lastError = bson.D{{Key: "n", Value: int32(1)}, {Key: "updatedExisting", Value: true}}
+	case doUpsert && !remove:
+		doc, err := c.upsert(filter, update)
+		if err != nil {
+			return nil, err
+		}
+		if returnNew {
+			value = clone(doc)
+		}
+		lastError = bson.D{
+			{Key: "n", Value: int32(1)},
+			{Key: "updatedExisting", Value: false},
+			{Key: "upserted", Value: get(doc, "_id")},
+		}
+	}
+	if d, ok := value.(bson.D); ok {
+		value = project(d, document(cmd, "fields"))
+	}
+	return bson.D{
+		{Key: "lastErrorObject", Value: lastError},
+		{Key: "value", Value: value},
+	}, nil
+}
+
+func (s *Server) createIndexes(db, name string, cmd bson.D) (bson.D, *commandError) {
+	specs, err := documents(cmd, "indexes")
+	if err != nil {
+		return nil, err
+	}
+	c := s.collection(db, name, true)
+	before := int32(len(c.indexes))
+	for _, spec := range specs {
+		if unique, _ := get(spec, "unique").(bool); !unique {
+			continue
+		}
+		idx := index{name: fmt.Sprint(get(spec, "name"))}
+		for _, k := range document(spec, "key") {
+			idx.keys = append(idx.keys, k.Key)
+		}
+		exists := false
+		for _, other := range c.indexes {
+			if other.name == idx.name {
+				exists = true
+			}
+		}
+		if exists {
+			continue
+		}
+		probe := &collection{indexes: []index{idx}}
+		for _, d := range c.docs {
+			if err := probe.duplicate(d, -1); err != nil {
+				return nil, err
+			}
+			probe.docs = append(probe.docs, d)
+		}
+		c.indexes = append(c.indexes, idx)
+	}
+	return bson.D{
+		{Key: "numIndexesBefore", Value: before},
+		{Key: "numIndexesAfter", Value: int32(len(c.indexes))},
+	}, nil
+}
+
+/*
+ * ==================================================================================================
+ *                            R E A D S
+ * ==================================================================================================
+ */
+
+// filter returns the positions of all matching documents in sort order
+func (c *collection) filter(filter, sortSpec bson.D) (positions []int, err *commandError) {
+	for i, d := range c.docs {
+		ok, e := matches(d, filter)
+		if e != nil {
+			return nil, badValue(e)
+		}
+		if ok {
+			positions = append(positions, i)
+		}
+	}
+	if len(sortSpec) > 0 {
+		sort.SliceStable(positions, func(i, j int) bool {
+			a, b := c.docs[positions[i]], c.docs[positions[j]]
+			for _, k := range sortSpec {
+				x, _ := getPath(a, k.Key)
+				y, _ := getPath(b, k.Key)
+				r := compare(x, y)
+				if toFloat(k.Value) < 0 {
+					r = -r
+				}
+				if r != 0 {
+					return r < 0
+				}
+			}
+			return false
+		})
+	}
+	return
+}
+
+func cursor(db, name string, batch bson.A) bson.D {
+	if batch == nil {
+		batch = bson.A{}
+	}
+	return bson.D{{Key: "cursor", Value: bson.D{
+		{Key: "firstBatch", Value: batch},
+		{Key: "id", Value: int64(0)},
+		{Key: "ns", Value: db + "." + name},
+	}}}
+}
+
+// window applies skip and limit, a negative limit is treated like its absolute value
+func window(docs []bson.D, skip, limit int64) []bson.D {
+	if skip > int64(len(docs)) {
+		skip = int64(len(docs))
+	}
+	docs = docs[skip:]
+	if limit < 0 {
+		limit = -limit
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+func (s *Server) find(db, name string, cmd bson.D) (bson.D, *commandError) {
+	c := s.collection(db, name, false)
+	if c == nil {
+		return cursor(db, name, nil), nil
+	}
+	positions, err := c.filter(document(cmd, "filter"), document(cmd, "sort"))
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]bson.D, len(positions))
+	for i, p := range positions {
+		docs[i] = c.docs[p]
+	}
+	var batch bson.A
+	for _, d := range window(docs, toInt(get(cmd, "skip")), toInt(get(cmd, "limit"))) {
+		batch = append(batch, project(clone(d), document(cmd, "projection")))
+	}
+	return cursor(db, name, batch), nil
+}
+
+// aggregate supports the stages used by CountDocuments: $match, $skip, $limit and $group with $sum
+func (s *Server) aggregate(db, name string, cmd bson.D) (bson.D, *commandError) {
+	var docs []bson.D
+	if c := s.collection(db, name, false); c != nil {
+		for _, d := range c.docs {
+			docs = append(docs, clone(d))
+		}
+	}
+	pipeline, err := documents(cmd, "pipeline")
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range pipeline {
+		if len(stage) != 1 {
+			return nil, &commandError{code: codeBadValue, msg: "a pipeline stage needs exactly one field"}
+		}
+		switch stage[0].Key {
+		case "$match":
+			filter, _ := stage[0].Value.(bson.D)
+			var out []bson.D
+			for _, d := range docs {
+				ok, e := matches(d, filter)
+				if e != nil {
+					return nil, badValue(e)
+				}
+				if ok {
+					out = append(out, d)
+				}
+			}
+			docs = out
+		case "$skip":
+			docs = window(docs, toInt(stage[0].Value), 0)
+		case "$limit":
+			docs = window(docs, 0, toInt(stage[0].Value))
+		case "$group":
+			docs = group(docs, stage[0].Value)
+		default:
+			return nil, &commandError{code: codeBadValue, msg: "unsupported pipeline stage: " + stage[0].Key}
+		}
+	}
+	var batch bson.A
+	for _, d := range docs {
+		batch = append(batch, d)
+	}
+	return cursor(db, name, batch), nil
+}
+
+// group -> $group with a constant _id and {$sum: <number>} accumulators
+func group(docs []bson.D, spec interface{}) []bson.D {
+	if len(docs) == 0 {
+		return nil
+	}
+	fields, _ := spec.(bson.D)
+	out := bson.D{{Key: "_id", Value: get(fields, "_id")}}
+	for _, f := range fields {
+		if f.Key == "_id" {
+			continue
+		}
+		acc, _ := f.Value.(bson.D)
+		var sum interface{} = int32(0)
+		if v, ok := lookup(acc, "$sum"); ok {
+			for range docs {
+				sum = add(sum, v)
+			}
+		}
+		out = append(out, bson.E{Key: f.Key, Value: sum})
+	}
+	return []bson.D{out}
+}
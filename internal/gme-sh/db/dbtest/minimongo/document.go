@@ -0,0 +1,237 @@
+package minimongo
+
+import (
+	"bytes"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// lookup returns the value of a top level key
+func lookup(doc bson.D, key string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// get returns the value of a top level key or nil
+func get(doc bson.D, key string) interface{} {
+	v, _ := lookup(doc, key)
+	return v
+}
+
+// getPath returns the value of a dotted path like "minutes.123"
+func getPath(doc bson.D, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		d, ok := current.(bson.D)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = lookup(d, part); !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets the value of a dotted path and creates missing sub documents
+func setPath(doc bson.D, path string, value interface{}) bson.D {
+	parts := strings.SplitN(path, ".", 2)
+	for i, e := range doc {
+		if e.Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			doc[i].Value = value
+		} else {
+			sub, _ := e.Value.(bson.D)
+			doc[i].Value = setPath(sub, parts[1], value)
+		}
+		return doc
+	}
+	if len(parts) == 1 {
+		return append(doc, bson.E{Key: parts[0], Value: value})
+	}
+	return append(doc, bson.E{Key: parts[0], Value: setPath(nil, parts[1], value)})
+}
+
+// unsetPath removes a dotted path
+func unsetPath(doc bson.D, path string) bson.D {
+	parts := strings.SplitN(path, ".", 2)
+	for i, e := range doc {
+		if e.Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			return append(doc[:i:i], doc[i+1:]...)
+		}
+		if sub, ok := e.Value.(bson.D); ok {
+			doc[i].Value = unsetPath(sub, parts[1])
+		}
+		return doc
+	}
+	return doc
+}
+
+// clone returns a deep copy of a document, so stored documents can't be modified by replies
+func clone(doc bson.D) bson.D {
+	if doc == nil {
+		return nil
+	}
+	out := make(bson.D, len(doc))
+	for i, e := range doc {
+		out[i] = bson.E{Key: e.Key, Value: cloneValue(e.Value)}
+	}
+	return out
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.D:
+		return clone(t)
+	case bson.A:
+		out := make(bson.A, len(t))
+		for i, x := range t {
+			out[i] = cloneValue(x)
+		}
+		return out
+	}
+	return v
+}
+
+// isOperatorDoc returns true if v is a document like {"$gt": 1}
+func isOperatorDoc(v interface{}) bool {
+	d, ok := v.(bson.D)
+	return ok && len(d) > 0 && strings.HasPrefix(d[0].Key, "$")
+}
+
+/*
+ * ==================================================================================================
+ *                            C O M P A R I S O N
+ * ==================================================================================================
+ */
+
+// typeOrder -> BSON comparison order of types
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, int:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D, bson.M:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	}
+	return 12
+}
+
+func toFloat(v interface{}) float64 {
+	switch t := v.(type) {
+	case int32:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case int:
+		return float64(t)
+	case float64:
+		return t
+	}
+	return 0
+}
+
+// compare compares two values in BSON order
+func compare(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		if ta < tb {
+			return -1
+		}
+		return 1
+	}
+	switch x := a.(type) {
+	case int32, int64, float64, int:
+		// int64 values are compared exactly, all other combinations as floats
+		if xi, ok := x.(int64); ok {
+			if yi, ok := b.(int64); ok {
+				return compareInt(xi, yi)
+			}
+		}
+		fa, fb := toFloat(a), toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case primitive.DateTime:
+		return compareInt(int64(x), int64(b.(primitive.DateTime)))
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case primitive.Binary:
+		y := b.(primitive.Binary)
+		return bytes.Compare(x.Data, y.Data)
+	case bson.D:
+		y := b.(bson.D)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := strings.Compare(x[i].Key, y[i].Key); c != 0 {
+				return c
+			}
+			if c := compare(x[i].Value, y[i].Value); c != 0 {
+				return c
+			}
+		}
+		return compareInt(int64(len(x)), int64(len(y)))
+	case bson.A:
+		y := b.(bson.A)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInt(int64(len(x)), int64(len(y)))
+	}
+	return 0
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,248 @@
+package minimongo
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// matches returns true if the document matches the filter
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := matchElement(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchElement(doc bson.D, e bson.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or", "$nor":
+		clauses, ok := e.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", e.Key)
+		}
+		for _, c := range clauses {
+			sub, ok := c.(bson.D)
+			if !ok {
+				return false, fmt.Errorf("%s entries need to be documents", e.Key)
+			}
+			m, err := matches(doc, sub)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case e.Key == "$and" && !m:
+				return false, nil
+			case e.Key == "$or" && m:
+				return true, nil
+			case e.Key == "$nor" && m:
+				return false, nil
+			}
+		}
+		return e.Key != "$or", nil
+	case "$expr":
+		v, err := evalExpr(doc, e.Value)
+		if err != nil {
+			return false, err
+		}
+		b, _ := v.(bool)
+		return b, nil
+	}
+	if strings.HasPrefix(e.Key, "$") {
+		return false, fmt.Errorf("unknown top level operator: %s", e.Key)
+	}
+
+	value, exists := getPath(doc, e.Key)
+	if !isOperatorDoc(e.Value) {
+		return equals(value, e.Value), nil
+	}
+	for _, op := range e.Value.(bson.D) {
+		ok, err := matchOperator(value, exists, op)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// equals -> {field: value}, a missing field equals null and arrays match if any element matches
+func equals(value, expected interface{}) bool {
+	if compare(value, expected) == 0 {
+		return true
+	}
+	if arr, ok := value.(bson.A); ok {
+		for _, v := range arr {
+			if compare(v, expected) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchOperator(value interface{}, exists bool, op bson.E) (bool, error) {
+	switch op.Key {
+	case "$eq":
+		return equals(value, op.Value), nil
+	case "$ne":
+		return !equals(value, op.Value), nil
+	case "$exists":
+		want, _ := op.Value.(bool)
+		return exists == want, nil
+	case "$gt", "$gte", "$lt", "$lte":
+		// range operators only compare values of the same type bracket
+		if !exists || typeOrder(value) != typeOrder(op.Value) {
+			return false, nil
+		}
+		c := compare(value, op.Value)
+		switch op.Key {
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		}
+		return c <= 0, nil
+	case "$in", "$nin":
+		arr, ok := op.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", op.Key)
+		}
+		found := false
+		for _, v := range arr {
+			if equals(value, v) {
+				found = true
+				break
+			}
+		}
+		return found == (op.Key == "$in"), nil
+	}
+	return false, fmt.Errorf("unknown operator: %s", op.Key)
+}
+
+// evalExpr evaluates the aggregation expressions supported in $expr
+func evalExpr(doc bson.D, expr interface{}) (interface{}, error) {
+	switch t := expr.(type) {
+	case string:
+		if strings.HasPrefix(t, "$") {
+			v, _ := getPath(doc, t[1:])
+			return v, nil
+		}
+		return t, nil
+	case bson.D:
+		if !isOperatorDoc(t) {
+			return t, nil
+		}
+		if len(t) != 1 {
+			return nil, fmt.Errorf("an expression can only have one operator")
+		}
+		args, ok := t[0].Value.(bson.A)
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("%s needs two arguments", t[0].Key)
+		}
+		a, err := evalExpr(doc, args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := evalExpr(doc, args[1])
+		if err != nil {
+			return nil, err
+		}
+		c := compare(a, b)
+		switch t[0].Key {
+		case "$eq":
+			return c == 0, nil
+		case "$ne":
+			return c != 0, nil
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		case "$lte":
+			return c <= 0, nil
+		}
+		return nil, fmt.Errorf("unknown expression operator: %s", t[0].Key)
+	}
+	return expr, nil
+}
+
+// upsertBase returns the document an upsert starts with: all equality conditions of the filter
+func upsertBase(filter bson.D) (doc bson.D) {
+	for _, e := range filter {
+		switch {
+		case e.Key == "$and":
+			arr, _ := e.Value.(bson.A)
+			for _, c := range arr {
+				if sub, ok := c.(bson.D); ok {
+					for _, x := range upsertBase(sub) {
+						doc = setPath(doc, x.Key, x.Value)
+					}
+				}
+			}
+		case strings.HasPrefix(e.Key, "$"):
+		case isOperatorDoc(e.Value):
+			if v, ok := lookup(e.Value.(bson.D), "$eq"); ok {
+				doc = setPath(doc, e.Key, cloneValue(v))
+			}
+		default:
+			doc = setPath(doc, e.Key, cloneValue(e.Value))
+		}
+	}
+	return
+}
+
+// project applies an inclusion or exclusion projection
+func project(doc bson.D, projection bson.D) bson.D {
+	if len(projection) == 0 {
+		return doc
+	}
+	include := false
+	for _, p := range projection {
+		if p.Key != "_id" && truthy(p.Value) {
+			include = true
+			break
+		}
+	}
+	if !include {
+		for _, p := range projection {
+			if !truthy(p.Value) {
+				doc = unsetPath(doc, p.Key)
+			}
+		}
+		return doc
+	}
+	var out bson.D
+	if id, ok := lookup(doc, "_id"); ok {
+		if v, ok := lookup(projection, "_id"); !ok || truthy(v) {
+			out = append(out, bson.E{Key: "_id", Value: id})
+		}
+	}
+	for _, p := range projection {
+		if p.Key == "_id" || !truthy(p.Value) {
+			continue
+		}
+		if v, ok := getPath(doc, p.Key); ok {
+			out = setPath(out, p.Key, v)
+		}
+	}
+	return out
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case nil:
+		return false
+	case int32, int64, float64:
+		return toFloat(t) != 0
+	}
+	return true
+}
@@ -0,0 +1,272 @@
+// Package minimongo is a small in-process MongoDB server for tests, similar to miniredis.
+// It speaks enough of the wire protocol (OP_QUERY handshake, OP_MSG commands)
+// for the official driver and implements the commands used by package db.
+// Data is only kept in memory and every command is executed under one lock.
+package minimongo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+
+	// maxWireVersion -> MongoDB 4.2, the driver uses OP_MSG for all commands after the handshake
+	maxWireVersion = 8
+)
+
+// Server -> in-process MongoDB server
+type Server struct {
+	mu        sync.Mutex
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+	databases map[string]map[string]*collection
+	requestID int32
+	wg        sync.WaitGroup
+}
+
+// Run starts a new Server on a random local port
+func Run() (s *Server, err error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s = &Server{
+		listener:  l,
+		conns:     make(map[net.Conn]struct{}),
+		databases: make(map[string]map[string]*collection),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// URI returns a connection string for the server
+func (s *Server) URI() string {
+	return "mongodb://" + s.Addr() + "/?connect=direct"
+}
+
+// Close stops the server and closes all client connections
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = c.Close()
+	}()
+	for {
+		header := make([]byte, 16)
+		if _, err := io.ReadFull(c, header); err != nil {
+			return
+		}
+		length := int(binary.LittleEndian.Uint32(header))
+		if length < 16 {
+			return
+		}
+		requestID := int32(binary.LittleEndian.Uint32(header[4:]))
+		opCode := int32(binary.LittleEndian.Uint32(header[12:]))
+		body := make([]byte, length-16)
+		if _, err := io.ReadFull(c, body); err != nil {
+			return
+		}
+
+		var reply []byte
+		switch opCode {
+		case opQuery:
+			reply = s.handleQuery(requestID, body)
+		case opMsg:
+			reply = s.handleMsg(requestID, body)
+		default:
+			return
+		}
+		if reply == nil {
+			return
+		}
+		if _, err := c.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// handleQuery -> OP_QUERY is only used by the driver for the initial handshake
+func (s *Server) handleQuery(requestID int32, body []byte) []byte {
+	if len(body) < 4 {
+		return nil
+	}
+	body = body[4:] // flags
+	end := strings.IndexByte(string(body), 0)
+	if end < 0 {
+		return nil
+	}
+	ns := string(body[:end])
+	body = body[end+1:]
+	if len(body) < 8 {
+		return nil
+	}
+	body = body[8:] // numberToSkip, numberToReturn
+
+	var cmd bson.D
+	if err := bson.Unmarshal(body, &cmd); err != nil {
+		return nil
+	}
+	if q, ok := lookup(cmd, "$query"); ok {
+		if d, ok := q.(bson.D); ok {
+			cmd = d
+		}
+	}
+	dbName := ns
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		dbName = ns[:i]
+	}
+
+	doc, err := bson.Marshal(s.execute(dbName, cmd))
+	if err != nil {
+		return nil
+	}
+	out := s.header(opReply, requestID, 20+len(doc))
+	out = appendInt32(out, 0) // responseFlags
+	out = appendInt64(out, 0) // cursorID
+	out = appendInt32(out, 0) // startingFrom
+	out = appendInt32(out, 1) // numberReturned
+	return append(out, doc...)
+}
+
+// handleMsg -> OP_MSG, all document sequences are merged into the command document
+func (s *Server) handleMsg(requestID int32, body []byte) []byte {
+	if len(body) < 4 {
+		return nil
+	}
+	flags := binary.LittleEndian.Uint32(body)
+	body = body[4:]
+	if flags&1 != 0 && len(body) >= 4 {
+		body = body[:len(body)-4] // checksum
+	}
+
+	var cmd bson.D
+	for len(body) > 0 {
+		kind := body[0]
+		body = body[1:]
+		switch kind {
+		case 0:
+			size, err := docSize(body)
+			if err != nil {
+				return nil
+			}
+			if err := bson.Unmarshal(body[:size], &cmd); err != nil {
+				return nil
+			}
+			body = body[size:]
+		case 1:
+			size, err := docSize(body)
+			if err != nil {
+				return nil
+			}
+			section := body[4:size]
+			body = body[size:]
+			end := strings.IndexByte(string(section), 0)
+			if end < 0 {
+				return nil
+			}
+			identifier := string(section[:end])
+			section = section[end+1:]
+			var docs bson.A
+			for len(section) > 0 {
+				n, err := docSize(section)
+				if err != nil {
+					return nil
+				}
+				var d bson.D
+				if err := bson.Unmarshal(section[:n], &d); err != nil {
+					return nil
+				}
+				docs = append(docs, d)
+				section = section[n:]
+			}
+			cmd = append(cmd, bson.E{Key: identifier, Value: docs})
+		default:
+			return nil
+		}
+	}
+
+	dbName, _ := get(cmd, "$db").(string)
+	doc, err := bson.Marshal(s.execute(dbName, cmd))
+	if err != nil {
+		return nil
+	}
+	out := s.header(opMsg, requestID, 5+len(doc))
+	out = appendInt32(out, 0) // flags
+	out = append(out, 0)      // section kind 0
+	return append(out, doc...)
+}
+
+func (s *Server) header(opCode int32, responseTo int32, bodyLength int) []byte {
+	s.mu.Lock()
+	s.requestID++
+	id := s.requestID
+	s.mu.Unlock()
+	out := make([]byte, 0, 16+bodyLength)
+	out = appendInt32(out, int32(16+bodyLength))
+	out = appendInt32(out, id)
+	out = appendInt32(out, responseTo)
+	return appendInt32(out, opCode)
+}
+
+func docSize(b []byte) (int, error) {
+	if len(b) < 4 {
+		return 0, errors.New("short document")
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	if n < 5 || n > len(b) {
+		return 0, fmt.Errorf("invalid document size %d", n)
+	}
+	return n, nil
+}
+
+func appendInt32(b []byte, v int32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendInt64(b []byte, v int64) []byte {
+	return appendInt32(appendInt32(b, int32(v)), int32(v>>32))
+}
@@ -0,0 +1,87 @@
+package minimongo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// applyUpdate applies an update document (operators or a replacement) to doc
+func applyUpdate(doc bson.D, update bson.D, insert bool) (bson.D, error) {
+	if len(update) == 0 || !strings.HasPrefix(update[0].Key, "$") {
+		// replacement, the _id is kept
+		out := clone(update)
+		if id, ok := lookup(doc, "_id"); ok {
+			out = append(bson.D{{Key: "_id", Value: id}}, unsetPath(out, "_id")...)
+		}
+		return out, nil
+	}
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a document", op.Key)
+		}
+		for _, f := range fields {
+			current, exists := getPath(doc, f.Key)
+			switch op.Key {
+			case "$set":
+				doc = setPath(doc, f.Key, cloneValue(f.Value))
+			case "$setOnInsert":
+				if insert {
+					doc = setPath(doc, f.Key, cloneValue(f.Value))
+				}
+			case "$unset":
+				doc = unsetPath(doc, f.Key)
+			case "$inc":
+				if exists && typeOrder(current) != typeOrder(f.Value) {
+					return nil, fmt.Errorf("cannot apply $inc to a value of non-numeric type")
+				}
+				doc = setPath(doc, f.Key, add(current, f.Value))
+			case "$max":
+				if !exists || compare(f.Value, current) > 0 {
+					doc = setPath(doc, f.Key, cloneValue(f.Value))
+				}
+			case "$min":
+				if !exists || compare(f.Value, current) < 0 {
+					doc = setPath(doc, f.Key, cloneValue(f.Value))
+				}
+			default:
+				return nil, fmt.Errorf("unknown update operator: %s", op.Key)
+			}
+		}
+	}
+	return doc, nil
+}
+
+// add adds two numbers and keeps the smallest type that holds the result
+func add(a, b interface{}) interface{} {
+	if a == nil {
+		return b
+	}
+	_, af := a.(float64)
+	_, bf := b.(float64)
+	if af || bf {
+		return toFloat(a) + toFloat(b)
+	}
+	sum := toInt(a) + toInt(b)
+	_, a32 := a.(int32)
+	_, b32 := b.(int32)
+	if a32 && b32 && sum >= math.MinInt32 && sum <= math.MaxInt32 {
+		return int32(sum)
+	}
+	return sum
+}
+
+func toInt(v interface{}) int64 {
+	switch t := v.(type) {
+	case int32:
+		return int64(t)
+	case int64:
+		return t
+	case int:
+		return int64(t)
+	}
+	return 0
+}
@@ -0,0 +1,716 @@
+// Package dbtest contains a conformance test suite for the database implementations in package db.
+// Every implementation of db.PersistentDatabase and db.StatsDatabase should pass these tests,
+// so the web server behaves the same, no matter which backend is configured.
+package dbtest
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/tpl"
+)
+
+// PersistentFactory returns a new, empty PersistentDatabase.
+// It is called once per test, so tests don't influence each other.
+type PersistentFactory func(t *testing.T) db.PersistentDatabase
+
+// RunPersistentDatabaseSuite runs all conformance tests for a PersistentDatabase implementation
+func RunPersistentDatabaseSuite(t *testing.T, factory PersistentFactory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, db.PersistentDatabase)
+	}{
+		{"SaveFind", testSaveFind},
+		{"SaveOverwrites", testSaveOverwrites},
+		{"SaveBulk", testSaveBulk},
+		{"Update", testUpdate},
+		{"FindMissing", testFindMissing},
+		{"Delete", testDelete},
+		{"Availability", testAvailability},
+		{"Dedup", testDedup},
+		{"FindExpired", testFindExpired},
+		{"ClickLimit", testClickLimit},
+		{"ClickLimitReuse", testClickLimitReuse},
+		{"Counter", testCounter},
+		{"LastExpirationCheck", testLastExpirationCheck},
+		{"Templates", testTemplates},
+		{"Pools", testPools},
+		{"Accounts", testAccounts},
+		{"ShortURLsByOwner", testShortURLsByOwner},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, factory(t))
+		})
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                                       H E L P E R S
+ * ==================================================================================================
+ */
+
+// timeTolerance is the precision every backend has to keep (e.g. MongoDB stores milliseconds)
+const timeTolerance = time.Second
+
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	if d < 0 {
+		d = -d
+	}
+	return d < timeTolerance
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// NewShortURL returns a valid ShortURL object with the ID {id}, which is not saved yet
+func NewShortURL(id string, expiration *time.Time) *short.ShortURL {
+	return &short.ShortURL{
+		ID:             short.ShortID(id),
+		FullURL:        "https://example.com/" + id,
+		CreationDate:   time.Now(),
+		ExpirationDate: expiration,
+		Secret:         "secret-" + id,
+	}
+}
+
+func mustSave(t *testing.T, database db.PersistentDatabase, u *short.ShortURL) {
+	t.Helper()
+	if err := database.SaveShortenedURL(u); err != nil {
+		t.Fatalf("saving %s: %v", u.ID, err)
+	}
+}
+
+func assertShortURL(t *testing.T, expected, actual *short.ShortURL) {
+	t.Helper()
+	if actual == nil {
+		t.Fatalf("expected short url %s, got nil", expected.ID)
+	}
+	if actual.ID != expected.ID {
+		t.Errorf("ID: expected %q, got %q", expected.ID, actual.ID)
+	}
+	if actual.FullURL != expected.FullURL {
+		t.Errorf("FullURL: expected %q, got %q", expected.FullURL, actual.FullURL)
+	}
+	if actual.Secret != expected.Secret {
+		t.Errorf("Secret: expected %q, got %q", expected.Secret, actual.Secret)
+	}
+	if actual.FallbackURL != expected.FallbackURL {
+		t.Errorf("FallbackURL: expected %q, got %q", expected.FallbackURL, actual.FallbackURL)
+	}
+	if (actual.ActivationDate == nil) != (expected.ActivationDate == nil) {
+		t.Errorf("ActivationDate: expected %v, got %v", expected.ActivationDate, actual.ActivationDate)
+	} else if actual.ActivationDate != nil && !sameTime(*actual.ActivationDate, *expected.ActivationDate) {
+		t.Errorf("ActivationDate: expected %v, got %v", *expected.ActivationDate, *actual.ActivationDate)
+	}
+	if actual.RedirectStatus != expected.RedirectStatus {
+		t.Errorf("RedirectStatus: expected %d, got %d", expected.RedirectStatus, actual.RedirectStatus)
+	}
+	if actual.ForwardQuery != expected.ForwardQuery || actual.ForwardPath != expected.ForwardPath {
+		t.Errorf("Forward: expected query=%v path=%v, got query=%v path=%v",
+			expected.ForwardQuery, expected.ForwardPath, actual.ForwardQuery, actual.ForwardPath)
+	}
+	if len(actual.Destinations) != len(expected.Destinations) {
+		t.Errorf("Destinations: expected %d, got %d", len(expected.Destinations), len(actual.Destinations))
+	} else {
+		for i, d := range expected.Destinations {
+			if *actual.Destinations[i] != *d {
+				t.Errorf("Destination #%d: expected %+v, got %+v", i, *d, *actual.Destinations[i])
+			}
+		}
+	}
+	if len(actual.Targets) != len(expected.Targets) {
+		t.Errorf("Targets: expected %d, got %d", len(expected.Targets), len(actual.Targets))
+	} else {
+		for i, r := range expected.Targets {
+			if *actual.Targets[i] != *r {
+				t.Errorf("Target #%d: expected %+v, got %+v", i, *r, *actual.Targets[i])
+			}
+		}
+	}
+	if actual.MaxClicks != expected.MaxClicks {
+		t.Errorf("MaxClicks: expected %d, got %d", expected.MaxClicks, actual.MaxClicks)
+	}
+	if actual.PasswordHash != expected.PasswordHash {
+		t.Errorf("PasswordHash: expected %q, got %q", expected.PasswordHash, actual.PasswordHash)
+	}
+	if actual.Owner != expected.Owner {
+		t.Errorf("Owner: expected %q, got %q", expected.Owner, actual.Owner)
+	}
+	if !sameTime(actual.CreationDate, expected.CreationDate) {
+		t.Errorf("CreationDate: expected %v, got %v", expected.CreationDate, actual.CreationDate)
+	}
+	if (actual.ExpirationDate == nil) != (expected.ExpirationDate == nil) {
+		t.Errorf("ExpirationDate: expected %v, got %v", expected.ExpirationDate, actual.ExpirationDate)
+	} else if actual.ExpirationDate != nil && !sameTime(*actual.ExpirationDate, *expected.ExpirationDate) {
+		t.Errorf("ExpirationDate: expected %v, got %v", *expected.ExpirationDate, *actual.ExpirationDate)
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                                   S H O R T   U R L S
+ * ==================================================================================================
+ */
+
+func testSaveFind(t *testing.T, database db.PersistentDatabase) {
+	permanent := NewShortURL("permanent", nil)
+	temporary := NewShortURL("temporary", timePtr(time.Now().Add(time.Hour)))
+	mustSave(t, database, permanent)
+	mustSave(t, database, temporary)
+
+	for _, expected := range []*short.ShortURL{permanent, temporary} {
+		actual, err := database.FindShortenedURL(&expected.ID)
+		if err != nil {
+			t.Fatalf("finding %s: %v", expected.ID, err)
+		}
+		assertShortURL(t, expected, actual)
+	}
+}
+
+func testSaveBulk(t *testing.T, database db.PersistentDatabase) {
+	if _, ok := database.(db.BulkPersistentDatabase); !ok {
+		t.Errorf("%T does not save short urls in bulk", database)
+	}
+	existing := NewShortURL("bulk-existing", nil)
+	mustSave(t, database, existing)
+
+	replaced := *existing
+	replaced.FullURL = "https://example.com/replaced"
+	urls := []*short.ShortURL{
+		NewShortURL("bulk-permanent", nil),
+		NewShortURL("bulk-temporary", timePtr(time.Now().Add(time.Hour))),
+		&replaced,
+	}
+	if err := db.SaveShortenedURLs(database, urls); err != nil {
+		t.Fatalf("saving in bulk: %v", err)
+	}
+	for _, expected := range urls {
+		actual, err := database.FindShortenedURL(&expected.ID)
+		if err != nil {
+			t.Fatalf("finding %s: %v", expected.ID, err)
+		}
+		assertShortURL(t, expected, actual)
+	}
+
+	// the expiration is tracked like with single saves
+	expired, err := database.FindExpiredURLs()
+	if err != nil {
+		t.Fatalf("finding expired: %v", err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("expected no expired urls, got %v", expired)
+	}
+
+	// nothing to save
+	if err := db.SaveShortenedURLs(database, nil); err != nil {
+		t.Errorf("saving no urls: %v", err)
+	}
+}
+
+func testSaveOverwrites(t *testing.T, database db.PersistentDatabase) {
+	u := NewShortURL("overwrite", timePtr(time.Now().Add(time.Hour)))
+	mustSave(t, database, u)
+
+	updated := NewShortURL("overwrite", nil)
+	updated.FullURL = "https://example.com/updated"
+	mustSave(t, database, updated)
+
+	actual, err := database.FindShortenedURL(&updated.ID)
+	if err != nil {
+		t.Fatalf("finding %s: %v", updated.ID, err)
+	}
+	assertShortURL(t, updated, actual)
+}
+
+func testUpdate(t *testing.T, database db.PersistentDatabase) {
+	// already expired, so it would be found by FindExpiredURLs
+	u := NewShortURL("update", timePtr(time.Now().Add(-time.Hour)))
+	mustSave(t, database, u)
+	// load it into the cache
+	if _, err := database.FindShortenedURL(&u.ID); err != nil {
+		t.Fatalf("finding %s: %v", u.ID, err)
+	}
+
+	// made permanent with a new url, password and activation date
+	updated := *u
+	updated.FullURL = "https://example.com/updated"
+	updated.ExpirationDate = nil
+	updated.PasswordHash = "$2a$10$hash"
+	updated.ActivationDate = timePtr(time.Now().Add(time.Hour))
+	updated.FallbackURL = "https://example.com/soon"
+	updated.RedirectStatus = 308
+	updated.ForwardQuery = true
+	updated.ForwardPath = true
+	updated.Destinations = []*short.Destination{
+		{URL: "https://example.com/a", Weight: 70},
+		{URL: "https://example.com/b", Weight: 30},
+	}
+	updated.Targets = []*short.TargetRule{
+		{OS: "iOS", URL: "https://apps.apple.com/app/example"},
+		{Device: short.DeviceMobile, Language: "de", URL: "https://example.com/de/mobile"},
+	}
+	if err := database.UpdateShortenedURL(&updated); err != nil {
+		t.Fatalf("updating %s: %v", u.ID, err)
+	}
+	actual, err := database.FindShortenedURL(&u.ID)
+	if err != nil {
+		t.Fatalf("finding %s: %v", u.ID, err)
+	}
+	assertShortURL(t, &updated, actual)
+
+	// the expiration was removed
+	var expired []*short.ShortURL
+	if expired, err = database.FindExpiredURLs(); err != nil {
+		t.Fatalf("finding expired urls: %v", err)
+	}
+	for _, e := range expired {
+		if e.ID == u.ID {
+			t.Errorf("expected updated url %s to be permanent", u.ID)
+		}
+	}
+}
+
+func testFindMissing(t *testing.T, database db.PersistentDatabase) {
+	id := short.ShortID("missing")
+	u, err := database.FindShortenedURL(&id)
+	if u != nil {
+		t.Errorf("expected nil for missing short url, got %v", u)
+	}
+	if err == nil {
+		t.Error("expected an error for missing short url")
+	}
+}
+
+func testDelete(t *testing.T, database db.PersistentDatabase) {
+	u := NewShortURL("delete", nil)
+	mustSave(t, database, u)
+
+	if err := database.DeleteShortenedURL(&u.ID); err != nil {
+		t.Fatalf("deleting %s: %v", u.ID, err)
+	}
+	if found, err := database.FindShortenedURL(&u.ID); found != nil || err == nil {
+		t.Errorf("expected deleted short url to be gone, got %v (err: %v)", found, err)
+	}
+
+	// deleting a missing short url is not an error
+	id := short.ShortID("never-existed")
+	if err := database.DeleteShortenedURL(&id); err != nil {
+		t.Errorf("deleting missing short url: %v", err)
+	}
+}
+
+func testAvailability(t *testing.T, database db.PersistentDatabase) {
+	u := NewShortURL("available", nil)
+	if !database.ShortURLAvailable(&u.ID) {
+		t.Error("expected unused id to be available")
+	}
+	mustSave(t, database, u)
+	if database.ShortURLAvailable(&u.ID) {
+		t.Error("expected saved id to be unavailable")
+	}
+	if err := database.DeleteShortenedURL(&u.ID); err != nil {
+		t.Fatalf("deleting %s: %v", u.ID, err)
+	}
+	if !database.ShortURLAvailable(&u.ID) {
+		t.Error("expected deleted id to be available")
+	}
+
+	expired := NewShortURL("available-expired", timePtr(time.Now().Add(-time.Hour)))
+	mustSave(t, database, expired)
+	if !database.ShortURLAvailable(&expired.ID) {
+		t.Error("expected expired id to be available")
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                                    E X P I R A T I O N
+ * ==================================================================================================
+ */
+
+func testDedup(t *testing.T, database db.PersistentDatabase) {
+	plain := NewShortURL("dedup-plain", nil)
+	plain.FullURL = "https://Example.com:443?b=2&a=1"
+	protected := NewShortURL("dedup-protected", nil)
+	protected.FullURL = plain.FullURL
+	protected.PasswordHash = "$2a$10$hash"
+	mustSave(t, database, plain)
+	mustSave(t, database, protected)
+
+	if protected.DedupHash() != "" {
+		t.Fatalf("expected protected urls not to be deduplicable")
+	}
+	expiration := time.Now().Add(time.Hour)
+	temporary := NewShortURL("dedup-temporary", &expiration)
+	temporary.FullURL = "https://example.com/temporary"
+	mustSave(t, database, temporary)
+	if temporary.DedupHash() != "" {
+		t.Fatalf("expected temporary urls not to be deduplicable")
+	}
+	// the url is not indexed under the hash it would have without expiration
+	permanent := *temporary
+	permanent.ExpirationDate = nil
+	if actual, err := database.FindDuplicate(permanent.DedupHash()); err == nil || actual != nil {
+		t.Errorf("expected no duplicate for a temporary url, got %v", actual)
+	}
+	// the long url is normalized
+	same := NewShortURL("dedup-same", nil)
+	same.FullURL = "https://example.com/?a=1&b=2"
+	if same.DedupHash() != plain.DedupHash() {
+		t.Fatalf("expected %q and %q to have the same hash", plain.FullURL, same.FullURL)
+	}
+	actual, err := database.FindDuplicate(plain.DedupHash())
+	if err != nil {
+		t.Fatalf("finding duplicate: %v", err)
+	}
+	assertShortURL(t, plain, actual)
+
+	// bulk saves are indexed, too
+	bulk := NewShortURL("dedup-bulk", nil)
+	bulk.FullURL = "https://example.com/bulk"
+	if err := db.SaveShortenedURLs(database, []*short.ShortURL{bulk}); err != nil {
+		t.Fatalf("saving in bulk: %v", err)
+	}
+	if actual, err = database.FindDuplicate(bulk.DedupHash()); err != nil {
+		t.Fatalf("finding duplicate: %v", err)
+	}
+	assertShortURL(t, bulk, actual)
+
+	// changed urls are not found with the old hash
+	oldHash := bulk.DedupHash()
+	changed := *bulk
+	changed.FullURL = "https://example.com/changed"
+	if err := database.UpdateShortenedURL(&changed); err != nil {
+		t.Fatalf("updating %s: %v", changed.ID, err)
+	}
+	if actual, err = database.FindDuplicate(oldHash); err == nil || actual != nil {
+		t.Errorf("expected no duplicate for the old url, got %v", actual)
+	}
+	if actual, err = database.FindDuplicate(changed.DedupHash()); err != nil {
+		t.Fatalf("finding duplicate: %v", err)
+	}
+	assertShortURL(t, &changed, actual)
+
+	// deleted urls are removed from the index
+	if err := database.DeleteShortenedURL(&plain.ID); err != nil {
+		t.Fatalf("deleting %s: %v", plain.ID, err)
+	}
+	if actual, err = database.FindDuplicate(plain.DedupHash()); err == nil || actual != nil {
+		t.Errorf("expected no duplicate after deletion, got %v", actual)
+	}
+	if actual, err = database.FindDuplicate("missing"); err == nil || actual != nil {
+		t.Errorf("expected no duplicate for a missing hash, got %v", actual)
+	}
+}
+
+func testFindExpired(t *testing.T, database db.PersistentDatabase) {
+	expired := NewShortURL("expired", timePtr(time.Now().Add(-time.Hour)))
+	mustSave(t, database, expired)
+	mustSave(t, database, NewShortURL("not-expired", timePtr(time.Now().Add(time.Hour))))
+	mustSave(t, database, NewShortURL("no-expiration", nil))
+
+	// urls which are not active yet are not expired
+	pending := NewShortURL("pending", timePtr(time.Now().Add(2*time.Hour)))
+	pending.ActivationDate = timePtr(time.Now().Add(time.Hour))
+	mustSave(t, database, pending)
+	active := NewShortURL("active", timePtr(time.Now().Add(time.Hour)))
+	active.ActivationDate = timePtr(time.Now().Add(-time.Hour))
+	mustSave(t, database, active)
+
+	// the window of an activation link ends with its expiration date
+	elapsed := NewShortURL("elapsed", timePtr(time.Now().Add(-time.Hour)))
+	elapsed.ActivationDate = timePtr(time.Now().Add(-2 * time.Hour))
+	mustSave(t, database, elapsed)
+
+	// a temporary url which was made permanent must not be found
+	madePermanent := NewShortURL("made-permanent", timePtr(time.Now().Add(-time.Hour)))
+	mustSave(t, database, madePermanent)
+	madePermanent.ExpirationDate = nil
+	mustSave(t, database, madePermanent)
+
+	res, err := database.FindExpiredURLs()
+	if err != nil {
+		t.Fatalf("finding expired: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("expected exactly 2 expired urls, got %d: %v", len(res), res)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	assertShortURL(t, elapsed, res[0])
+	assertShortURL(t, expired, res[1])
+
+	// deleted urls are not expired anymore
+	for _, u := range []*short.ShortURL{expired, elapsed} {
+		if err := database.DeleteShortenedURL(&u.ID); err != nil {
+			t.Fatalf("deleting %s: %v", u.ID, err)
+		}
+	}
+	if res, err = database.FindExpiredURLs(); err != nil {
+		t.Fatalf("finding expired: %v", err)
+	}
+	if len(res) != 0 {
+		t.Errorf("expected no expired urls after deletion, got %v", res)
+	}
+}
+
+func testClickLimit(t *testing.T, database db.PersistentDatabase) {
+	limited := NewShortURL("limited", nil)
+	limited.MaxClicks = 5
+	mustSave(t, database, limited)
+	other := NewShortURL("limited-other", nil)
+	other.MaxClicks = 5
+	mustSave(t, database, other)
+
+	// concurrent clicks must not exceed the limit
+	var wg sync.WaitGroup
+	var allowed int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := database.ConsumeClick(&limited.ID, limited.MaxClicks)
+			if err != nil {
+				t.Errorf("consuming click: %v", err)
+			}
+			if ok {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 5 {
+		t.Errorf("expected 5 allowed clicks, got %d", allowed)
+	}
+	if ok, err := database.ConsumeClick(&other.ID, other.MaxClicks); err != nil || !ok {
+		t.Errorf("expected counters to be independent, got %v, %v", ok, err)
+	}
+
+	// exhausted urls are found by the ExpirationCheck, even after they were updated
+	if err := database.UpdateShortenedURL(limited); err != nil {
+		t.Fatalf("updating %s: %v", limited.ID, err)
+	}
+	res, err := database.FindExpiredURLs()
+	if err != nil {
+		t.Fatalf("finding expired: %v", err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("expected exactly 1 exhausted url, got %d: %v", len(res), res)
+	}
+	assertShortURL(t, limited, res[0])
+
+	// the counter is reset with the url
+	if err = database.DeleteShortenedURL(&limited.ID); err != nil {
+		t.Fatalf("deleting %s: %v", limited.ID, err)
+	}
+	mustSave(t, database, limited)
+	if ok, err := database.ConsumeClick(&limited.ID, limited.MaxClicks); err != nil || !ok {
+		t.Errorf("expected click to be allowed after re-creation, got %v, %v", ok, err)
+	}
+}
+
+// testClickLimitReuse -> a new url saved with the id of an exhausted url doesn't inherit its clicks
+func testClickLimitReuse(t *testing.T, database db.PersistentDatabase) {
+	old := NewShortURL("reused", nil)
+	old.MaxClicks = 1
+	mustSave(t, database, old)
+	if ok, err := database.ConsumeClick(&old.ID, old.MaxClicks); err != nil || !ok {
+		t.Fatalf("expected first click to be allowed, got %v, %v", ok, err)
+	}
+	if ok, err := database.ConsumeClick(&old.ID, old.MaxClicks); err != nil || ok {
+		t.Fatalf("expected url to be exhausted, got %v, %v", ok, err)
+	}
+
+	// the exhausted url was not removed by the ExpirationCheck yet
+	reused := NewShortURL("reused", nil)
+	reused.FullURL = "https://example.com/reused"
+	reused.MaxClicks = 1
+	mustSave(t, database, reused)
+	res, err := database.FindExpiredURLs()
+	if err != nil {
+		t.Fatalf("finding expired: %v", err)
+	}
+	if len(res) != 0 {
+		t.Errorf("expected the new url not to be exhausted, got %v", res)
+	}
+	if ok, err := database.ConsumeClick(&reused.ID, reused.MaxClicks); err != nil || !ok {
+		t.Errorf("expected click on the new url to be allowed, got %v, %v", ok, err)
+	}
+}
+
+func testCounter(t *testing.T, database db.PersistentDatabase) {
+	// concurrent increments must return distinct values 1..20
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[uint64]bool)
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := database.IncrementCounter("ids")
+			if err != nil {
+				t.Errorf("incrementing counter: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[n] {
+				t.Errorf("counter value %d returned twice", n)
+			}
+			seen[n] = true
+		}()
+	}
+	wg.Wait()
+	for n := uint64(1); n <= 20; n++ {
+		if !seen[n] {
+			t.Errorf("expected counter value %d, got %v", n, seen)
+		}
+	}
+
+	// counters are independent
+	if n, err := database.IncrementCounter("other"); err != nil || n != 1 {
+		t.Errorf("expected 1 for a new counter, got %d, %v", n, err)
+	}
+	if n, err := database.IncrementCounter("ids"); err != nil || n != 21 {
+		t.Errorf("expected 21, got %d, %v", n, err)
+	}
+
+	// decremented values are returned again, but the counter doesn't go below 0
+	if err := database.DecrementCounter("ids", 2); err != nil {
+		t.Fatalf("decrementing counter: %v", err)
+	}
+	if n, err := database.IncrementCounter("ids"); err != nil || n != 20 {
+		t.Errorf("expected 20 after decrementing, got %d, %v", n, err)
+	}
+	if err := database.DecrementCounter("other", 5); err != nil {
+		t.Fatalf("decrementing counter: %v", err)
+	}
+	if n, err := database.IncrementCounter("other"); err != nil || n != 1 {
+		t.Errorf("expected 1 after decrementing below 0, got %d, %v", n, err)
+	}
+	if err := database.DecrementCounter("missing", 1); err != nil {
+		t.Errorf("decrementing a missing counter: %v", err)
+	}
+
+	// counters are kept until they expire
+	if _, err := database.IncrementCounter("expiring"); err != nil {
+		t.Fatalf("incrementing counter: %v", err)
+	}
+	if err := database.ExpireCounter("expiring", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("expiring counter: %v", err)
+	}
+	if n, err := database.IncrementCounter("expiring"); err != nil || n != 2 {
+		t.Errorf("expected 2 before the expiration, got %d, %v", n, err)
+	}
+	if err := database.ExpireCounter("never-incremented", time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("expiring a missing counter: %v", err)
+	}
+}
+
+func testLastExpirationCheck(t *testing.T, database db.PersistentDatabase) {
+	if m := database.GetLastExpirationCheck(); m == nil || !m.LastCheck.Before(time.Now().Add(-time.Hour)) {
+		t.Errorf("expected initial last expiration check to be in the past, got %v", m)
+	}
+
+	now := time.Now()
+	database.UpdateLastExpirationCheck(now)
+	if m := database.GetLastExpirationCheck(); m == nil || !sameTime(m.LastCheck, now) {
+		t.Errorf("expected last expiration check %v, got %v", now, m)
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                                     T E M P L A T E S
+ * ==================================================================================================
+ */
+
+func testTemplates(t *testing.T, database db.PersistentDatabase) {
+	templates, err := database.FindTemplates()
+	if err != nil {
+		t.Fatalf("finding templates: %v", err)
+	}
+	if len(templates) != 0 {
+		t.Fatalf("expected no templates, got %v", templates)
+	}
+
+	for _, tp := range []*tpl.Template{
+		{TemplateURL: "/gh/:user", FullURL: "https://github.com/:user"},
+		{TemplateURL: "/tw/:user", FullURL: "https://twitter.com/:user", RedirectStatus: 301},
+		// overwrites the first one
+		{TemplateURL: "/gh/:user", FullURL: "https://gitlab.com/:user"},
+	} {
+		if err := database.SaveTemplate(tp); err != nil {
+			t.Fatalf("saving template %s: %v", tp.TemplateURL, err)
+		}
+	}
+
+	if templates, err = database.FindTemplates(); err != nil {
+		t.Fatalf("finding templates: %v", err)
+	}
+	found := make(map[string]string)
+	for _, tp := range templates {
+		found[tp.TemplateURL] = tp.FullURL
+		if tp.TemplateURL == "/tw/:user" && tp.RedirectStatus != 301 {
+			t.Errorf("RedirectStatus of %s: expected 301, got %d", tp.TemplateURL, tp.RedirectStatus)
+		}
+	}
+	if len(templates) != 2 || found["/gh/:user"] != "https://gitlab.com/:user" ||
+		found["/tw/:user"] != "https://twitter.com/:user" {
+		t.Errorf("unexpected templates: %v", found)
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                                         P O O L S
+ * ==================================================================================================
+ */
+
+func testPools(t *testing.T, database db.PersistentDatabase) {
+	id := short.PoolID("pool")
+	if pool, err := database.FindPool(&id); pool != nil || err == nil {
+		t.Fatalf("expected missing pool to return nil and an error, got %v (err: %v)", pool, err)
+	}
+
+	pool := &short.Pool{
+		ID:      id,
+		Created: time.Now(),
+		Secret:  "pool-secret",
+		Entries: map[string][]*short.PoolEntry{
+			"mbp": {{URL: "https://github.com", Time: time.Now()}},
+		},
+	}
+	if err := database.SavePool(pool); err != nil {
+		t.Fatalf("saving pool: %v", err)
+	}
+
+	pool.Entries["mbp"] = append(pool.Entries["mbp"], &short.PoolEntry{URL: "https://gme.sh", Time: time.Now()})
+	if err := database.SavePool(pool); err != nil {
+		t.Fatalf("updating pool: %v", err)
+	}
+
+	actual, err := database.FindPool(&id)
+	if err != nil {
+		t.Fatalf("finding pool: %v", err)
+	}
+	if actual.ID != pool.ID || actual.Secret != pool.Secret || !sameTime(actual.Created, pool.Created) {
+		t.Errorf("expected pool %v, got %v", pool, actual)
+	}
+	if entries := actual.Entries["mbp"]; len(entries) != 2 || entries[1].URL != "https://gme.sh" {
+		t.Errorf("unexpected pool entries: %v", actual.Entries)
+	}
+}
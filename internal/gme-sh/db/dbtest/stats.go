@@ -0,0 +1,317 @@
+package dbtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// StatsFactory returns a new, empty StatsDatabase.
+// It is called once per test, so tests don't influence each other.
+type StatsFactory func(t *testing.T) db.StatsDatabase
+
+// RunStatsDatabaseSuite runs all conformance tests for a StatsDatabase implementation
+func RunStatsDatabaseSuite(t *testing.T, factory StatsFactory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, db.StatsDatabase)
+	}{
+		{"FindMissing", testStatsFindMissing},
+		{"AddFind", testStatsAddFind},
+		{"AddConcurrent", testStatsAddConcurrent},
+		{"Delete", testStatsDelete},
+		{"TimeSeries", testStatsTimeSeries},
+		{"Breakdowns", testStatsBreakdowns},
+		{"BreakdownLimit", testStatsBreakdownLimit},
+		{"Visitors", testStatsVisitors},
+		{"RateLimit", testStatsRateLimit},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, factory(t))
+		})
+	}
+}
+
+func mustFindStats(t *testing.T, database db.StatsDatabase, id short.ShortID) *short.Stats {
+	t.Helper()
+	stats, err := database.FindStats(&id)
+	if err != nil {
+		t.Fatalf("finding stats for %s: %v", id, err)
+	}
+	if stats == nil {
+		t.Fatalf("expected stats for %s, got nil", id)
+	}
+	return stats
+}
+
+func mustAddStats(t *testing.T, database db.StatsDatabase, id short.ShortID, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := database.AddStats(&id, nil); err != nil {
+			t.Fatalf("adding stats for %s: %v", id, err)
+		}
+	}
+}
+
+func testStatsFindMissing(t *testing.T, database db.StatsDatabase) {
+	// links which were never called have zero stats, that's not an error
+	if stats := mustFindStats(t, database, "never-called"); stats.Calls != 0 || stats.Calls60 != 0 {
+		t.Errorf("expected zero stats, got %+v", stats)
+	}
+}
+
+func testStatsAddFind(t *testing.T, database db.StatsDatabase) {
+	mustAddStats(t, database, "a", 3)
+	mustAddStats(t, database, "b", 1)
+
+	if stats := mustFindStats(t, database, "a"); stats.Calls != 3 || stats.Calls60 != 3 {
+		t.Errorf("expected 3 calls for a, got %+v", stats)
+	}
+	if stats := mustFindStats(t, database, "b"); stats.Calls != 1 || stats.Calls60 != 1 {
+		t.Errorf("expected 1 call for b, got %+v", stats)
+	}
+}
+
+// testStatsAddConcurrent -> the first clicks of multiple nodes must not split the stats
+func testStatsAddConcurrent(t *testing.T, database db.StatsDatabase) {
+	id := short.ShortID("concurrent")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := database.AddStats(&id, nil); err != nil {
+				t.Errorf("adding stats for %s: %v", id, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if stats := mustFindStats(t, database, id); stats.Calls != 20 {
+		t.Errorf("expected 20 calls, got %+v", stats)
+	}
+}
+
+func testStatsDelete(t *testing.T, database db.StatsDatabase) {
+	mustAddStats(t, database, "delete", 2)
+	mustAddStats(t, database, "keep", 1)
+
+	id := short.ShortID("delete")
+	if err := database.DeleteStats(&id); err != nil {
+		t.Fatalf("deleting stats: %v", err)
+	}
+	if stats := mustFindStats(t, database, "delete"); stats.Calls != 0 || stats.Calls60 != 0 {
+		t.Errorf("expected zero stats after deletion, got %+v", stats)
+	}
+	if stats := mustFindStats(t, database, "keep"); stats.Calls != 1 {
+		t.Errorf("expected other stats to be untouched, got %+v", stats)
+	}
+}
+
+func testStatsTimeSeries(t *testing.T, database db.StatsDatabase) {
+	id := short.ShortID("timeseries")
+	now := time.Now()
+
+	// no calls -> every bucket is zero
+	ts, err := database.FindTimeSeries(&id, short.GranularityHour, now.Add(-2*time.Hour), now)
+	if err != nil {
+		t.Fatalf("finding time series: %v", err)
+	}
+	if len(ts.Points) != 3 {
+		t.Fatalf("expected 3 hourly points, got %d", len(ts.Points))
+	}
+	for _, p := range ts.Points {
+		if p.Calls != 0 {
+			t.Errorf("expected zero calls at %v, got %d", p.Time, p.Calls)
+		}
+	}
+
+	mustAddStats(t, database, id, 4)
+
+	for _, g := range []short.Granularity{short.GranularityHour, short.GranularityDay} {
+		if ts, err = database.FindTimeSeries(&id, g, now.Add(-2*g.Duration()), now); err != nil {
+			t.Fatalf("finding %s time series: %v", g, err)
+		}
+		if ts.Granularity != g || len(ts.Points) != 3 {
+			t.Fatalf("expected 3 %s points, got %+v", g, ts)
+		}
+		last := ts.Points[len(ts.Points)-1]
+		if last.Calls != 4 || !last.Time.Equal(g.Time(g.Bucket(now))) {
+			t.Errorf("expected 4 calls in the current %s, got %+v", g, last)
+		}
+		if first := ts.Points[0]; first.Calls != 0 || !first.Time.Before(last.Time) {
+			t.Errorf("expected zero calls in the first %s bucket, got %+v", g, first)
+		}
+	}
+
+	// buckets are removed with the other stats
+	if err = database.DeleteStats(&id); err != nil {
+		t.Fatalf("deleting stats: %v", err)
+	}
+	if ts, err = database.FindTimeSeries(&id, short.GranularityDay, now, now); err != nil {
+		t.Fatalf("finding time series: %v", err)
+	}
+	if len(ts.Points) != 1 || ts.Points[0].Calls != 0 {
+		t.Errorf("expected zero calls after deletion, got %+v", ts.Points)
+	}
+}
+
+func testStatsBreakdowns(t *testing.T, database db.StatsDatabase) {
+	id := short.ShortID("breakdowns")
+	clicks := []*short.Click{
+		{Referrer: "github.com", Browser: "Firefox", OS: "Linux", Device: short.DeviceDesktop, Language: "de",
+			Variant: "0"},
+		{Referrer: "github.com", Browser: "Safari", OS: "iOS", Device: short.DeviceMobile, Language: "en",
+			Variant: "1"},
+		{Referrer: "news.ycombinator.com", Browser: "Firefox", OS: "Windows", Device: short.DeviceDesktop, Language: "en",
+			Variant: "0"},
+		{Referrer: short.ReferrerDirect, Browser: "Chrome", OS: "Android", Device: short.DeviceMobile, Language: "en"},
+		{Referrer: "github.com", Browser: "Firefox", OS: "Linux", Device: short.DeviceDesktop, Language: "de",
+			Variant: "0"},
+	}
+	for _, c := range clicks {
+		if err := database.AddStats(&id, c); err != nil {
+			t.Fatalf("adding stats: %v", err)
+		}
+	}
+
+	stats := mustFindStats(t, database, id)
+	if stats.Calls != uint64(len(clicks)) {
+		t.Errorf("expected %d calls, got %d", len(clicks), stats.Calls)
+	}
+	expected := map[string][]short.StatsEntry{
+		short.BreakdownReferrer: {{Value: "github.com", Calls: 3}, {Value: short.ReferrerDirect, Calls: 1},
+			{Value: "news.ycombinator.com", Calls: 1}},
+		short.BreakdownBrowser: {{Value: "Firefox", Calls: 3}, {Value: "Chrome", Calls: 1}, {Value: "Safari", Calls: 1}},
+		short.BreakdownOS: {{Value: "Linux", Calls: 2}, {Value: "Android", Calls: 1}, {Value: "Windows", Calls: 1},
+			{Value: "iOS", Calls: 1}},
+		short.BreakdownDevice:   {{Value: short.DeviceDesktop, Calls: 3}, {Value: short.DeviceMobile, Calls: 2}},
+		short.BreakdownLanguage: {{Value: "en", Calls: 3}, {Value: "de", Calls: 2}},
+		short.BreakdownVariant:  {{Value: "0", Calls: 3}, {Value: "1", Calls: 1}},
+	}
+	for dim, entries := range expected {
+		actual := stats.Breakdowns[dim]
+		if len(actual) != len(entries) {
+			t.Errorf("%s: expected %v, got %d entries", dim, entries, len(actual))
+			continue
+		}
+		for i, e := range entries {
+			if *actual[i] != e {
+				t.Errorf("%s #%d: expected %+v, got %+v", dim, i, e, *actual[i])
+			}
+		}
+	}
+
+	// breakdowns are removed with the other stats
+	if err := database.DeleteStats(&id); err != nil {
+		t.Fatalf("deleting stats: %v", err)
+	}
+	if stats = mustFindStats(t, database, id); len(stats.Breakdowns[short.BreakdownReferrer]) != 0 {
+		t.Errorf("expected no breakdowns after deletion, got %v", stats.Breakdowns)
+	}
+}
+
+func testStatsBreakdownLimit(t *testing.T, database db.StatsDatabase) {
+	id := short.ShortID("breakdown-limit")
+	click := func(referrer string) {
+		if err := database.AddStats(&id, &short.Click{Referrer: referrer}); err != nil {
+			t.Fatalf("adding stats: %v", err)
+		}
+	}
+	// a dimension holds up to 1000 distinct values
+	for i := 0; i < 1000; i++ {
+		click(fmt.Sprintf("ref-%d.example", i))
+	}
+	// new values are counted as "other", known values are still counted
+	click("new-1.example")
+	click("new-2.example")
+	click("ref-0.example")
+	click("ref-0.example")
+
+	stats := mustFindStats(t, database, id)
+	actual := stats.Breakdowns[short.BreakdownReferrer]
+	expected := []short.StatsEntry{{Value: "ref-0.example", Calls: 3}, {Value: "other", Calls: 2}}
+	if len(actual) < len(expected) {
+		t.Fatalf("expected at least %d referrers, got %d", len(expected), len(actual))
+	}
+	for i, e := range expected {
+		if *actual[i] != e {
+			t.Errorf("referrer #%d: expected %+v, got %+v", i, e, *actual[i])
+		}
+	}
+}
+
+func testStatsVisitors(t *testing.T, database db.StatsDatabase) {
+	id := short.ShortID("visitors")
+	// 20 visitors of yesterday, they only count for all time
+	for i := 0; i < 20; i++ {
+		click := &short.Click{Time: time.Now().AddDate(0, 0, -1), Visitor: fmt.Sprintf("yesterday-%d", i)}
+		if err := database.AddStats(&id, click); err != nil {
+			t.Fatalf("adding stats: %v", err)
+		}
+	}
+	// 100 visitors calling the link twice, and some clicks of unknown visitors
+	for i := 0; i < 200; i++ {
+		click := &short.Click{Time: time.Now(), Visitor: fmt.Sprintf("visitor-%d", i%100)}
+		if err := database.AddStats(&id, click); err != nil {
+			t.Fatalf("adding stats: %v", err)
+		}
+	}
+	mustAddStats(t, database, id, 10)
+
+	// the count is an approximation
+	stats := mustFindStats(t, database, id)
+	if stats.Calls != 230 {
+		t.Errorf("expected 230 calls, got %d", stats.Calls)
+	}
+	if stats.Visitors < 114 || stats.Visitors > 126 {
+		t.Errorf("expected about 120 visitors, got %d", stats.Visitors)
+	}
+	if stats.VisitorsToday < 95 || stats.VisitorsToday > 105 {
+		t.Errorf("expected about 100 visitors today, got %d", stats.VisitorsToday)
+	}
+	if stats = mustFindStats(t, database, "no-visitors"); stats.Visitors != 0 || stats.VisitorsToday != 0 {
+		t.Errorf("expected no visitors, got %+v", stats)
+	}
+
+	if err := database.DeleteStats(&id); err != nil {
+		t.Fatalf("deleting stats: %v", err)
+	}
+	if stats = mustFindStats(t, database, id); stats.Visitors != 0 || stats.VisitorsToday != 0 {
+		t.Errorf("expected no visitors after deletion, got %+v", stats)
+	}
+}
+
+func testStatsRateLimit(t *testing.T, database db.StatsDatabase) {
+	window := time.Minute
+	start := time.Now().Truncate(window)
+	hit := func(key string, start time.Time) uint64 {
+		t.Helper()
+		hits, err := database.IncrementRateLimit(key, start, window)
+		if err != nil {
+			t.Fatalf("incrementing rate limit %s: %v", key, err)
+		}
+		return hits
+	}
+	for i := uint64(1); i <= 3; i++ {
+		if hits := hit("create::1.2.3.4", start); hits != i {
+			t.Errorf("expected %d hits, got %d", i, hits)
+		}
+	}
+	// keys are counted separately
+	if hits := hit("create::5.6.7.8", start); hits != 1 {
+		t.Errorf("expected 1 hit for another key, got %d", hits)
+	}
+	// the next window starts again
+	if hits := hit("create::1.2.3.4", start.Add(window)); hits != 1 {
+		t.Errorf("expected 1 hit in the next window, got %d", hits)
+	}
+	if hits := hit("create::1.2.3.4", start.Add(window)); hits != 2 {
+		t.Errorf("expected 2 hits in the next window, got %d", hits)
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// The reverse index (ShortURL.DedupHash -> ShortID) is only updated when urls are saved or deleted,
+// so an entry may belong to an url which was changed since. The entries are checked by findDuplicate.
+
+// findDuplicate returns the ShortURL {id} of the reverse index if it still has the {hash}
+func findDuplicate(database PersistentDatabase, id short.ShortID, hash string) (*short.ShortURL, error) {
+	u, err := database.FindShortenedURL(&id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DedupHash() != hash {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// dedupHashOf returns the DedupHash of a JSON encoded ShortURL
+func dedupHashOf(data []byte) string {
+	var u short.ShortURL
+	if len(data) == 0 || json.Unmarshal(data, &u) != nil {
+		return ""
+	}
+	return u.DedupHash()
+}
@@ -28,7 +28,7 @@ func (e *ExpirationCheck) Check() {
 		return
 	}
 	for _, ex := range expired {
-		log.Println("💔 Would delete expired url ::", *ex)
+		log.Println("💔 Would delete expired url ::", ex)
 		if !e.DryRun {
 			if err := e.DB.DeleteShortenedURL(&ex.ID); err != nil {
 				log.Println("⚠️ Error deleting expired url #", ex.ID, ":", err)
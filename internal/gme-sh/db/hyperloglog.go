@@ -0,0 +1,63 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+const (
+	// hllPrecision -> 2^12 registers, the standard error is about 1.6%
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog approximates the number of distinct values added to it.
+// It is used for unique visitors by the stats backends without native support (memory, bbolt, mongo).
+// Every register holds the maximum rank of the values hashed to it, so two sketches can be merged
+// (or updated atomically by a database) by taking the maximum of each register.
+type hyperLogLog []uint8
+
+func newHyperLogLog() hyperLogLog {
+	return make(hyperLogLog, hllRegisters)
+}
+
+// hllRegister returns the register of {value} and its rank
+// (position of the first 1 bit of the remaining hash)
+func hllRegister(value string) (register int, rank uint8) {
+	sum := sha256.Sum256([]byte(value))
+	hash := binary.BigEndian.Uint64(sum[:8])
+	register = int(hash >> (64 - hllPrecision))
+	rank = uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	return
+}
+
+func (h hyperLogLog) add(value string) {
+	register, rank := hllRegister(value)
+	if rank > h[register] {
+		h[register] = rank
+	}
+}
+
+// count returns the estimated number of distinct values
+func (h hyperLogLog) count() uint64 {
+	if len(h) != hllRegisters {
+		return 0
+	}
+	m := float64(hllRegisters)
+	var sum float64
+	var zeros int
+	for _, r := range h {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// small cardinalities: linear counting is more accurate
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval -> interval in which the memory and bbolt backends remove the counters of ended windows
+// (and the memory, bbolt and sql backends the expired counters, see PersistentDatabase.ExpireCounter)
+const rateLimitSweepInterval = time.Minute
+
+// rateLimitWindow -> requests of a rate limit key in the window [start, end) (unix nanoseconds).
+// Used by the backends which keep one counter per key instead of expiring keys.
+type rateLimitWindow struct {
+	start int64
+	end   int64
+	hits  uint64
+}
+
+// hit counts a request in the window [start, start + window) and returns the requests in the window.
+// The counter starts again if the request belongs to another window.
+func (w *rateLimitWindow) hit(start time.Time, window time.Duration) uint64 {
+	if w.start != start.UnixNano() {
+		w.start = start.UnixNano()
+		w.end = start.Add(window).UnixNano()
+		w.hits = 0
+	}
+	w.hits++
+	return w.hits
+}
+
+// ended returns true if the window ended before {now}
+func (w *rateLimitWindow) ended(now time.Time) bool {
+	return w.end <= now.UnixNano()
+}
+
+func (w *rateLimitWindow) bytes() []byte {
+	buf := make([]byte, 24)
+	binary.BigEndian.PutUint64(buf[0:8], uint64(w.start))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(w.end))
+	binary.BigEndian.PutUint64(buf[16:24], w.hits)
+	return buf
+}
+
+// parseRateLimitWindow decodes a window encoded by bytes, invalid data is an empty window
+func parseRateLimitWindow(data []byte) (w rateLimitWindow) {
+	if len(data) != 24 {
+		return
+	}
+	w.start = int64(binary.BigEndian.Uint64(data[0:8]))
+	w.end = int64(binary.BigEndian.Uint64(data[8:16]))
+	w.hits = binary.BigEndian.Uint64(data[16:24])
+	return
+}
+
+// rateLimitSweeper -> decides when the counters of ended windows or expired counters are removed
+type rateLimitSweeper struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// due returns true at most once per rateLimitSweepInterval
+func (s *rateLimitSweeper) due(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Before(s.next) {
+		return false
+	}
+	s.next = now.Add(rateLimitSweepInterval)
+	return true
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+const (
+	// StatsBreakdownTop -> number of values returned per breakdown dimension
+	StatsBreakdownTop = 10
+
+	// maxBreakdownValues -> number of distinct values stored per breakdown dimension.
+	// Further values are counted as breakdownOther, so the stats can't grow without limit.
+	maxBreakdownValues = 1000
+
+	breakdownOther = "other"
+)
+
+// breakdownValue returns the value which is counted for {value}
+// if the dimension already holds {distinct} values and {value} is one of them ({exists})
+func breakdownValue(value string, exists bool, distinct int) string {
+	if !exists && distinct >= maxBreakdownValues {
+		return breakdownOther
+	}
+	return value
+}
+
+// clickTime returns the time of the click or the current time if the click has no time
+func clickTime(click *short.Click) time.Time {
+	if click == nil || click.Time.IsZero() {
+		return time.Now()
+	}
+	return click.Time
+}
+
+// clickVisitor returns the anonymous visitor id of the click or nothing if the visitor is unknown
+func clickVisitor(click *short.Click) string {
+	if click == nil {
+		return ""
+	}
+	return click.Visitor
+}
+
+// clickDimensions returns the dimensions of the click or nothing if there is no click
+func clickDimensions(click *short.Click) map[string]string {
+	if click == nil {
+		return nil
+	}
+	return click.Dimensions()
+}
+
+// topBreakdowns returns the top values of every dimension
+func topBreakdowns(breakdowns map[string]map[string]uint64) map[string][]*short.StatsEntry {
+	res := make(map[string][]*short.StatsEntry)
+	for _, dim := range short.Breakdowns {
+		res[dim] = short.TopStatsEntries(breakdowns[dim], StatsBreakdownTop)
+	}
+	return res
+}
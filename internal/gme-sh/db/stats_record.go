@@ -0,0 +1,121 @@
+package db
+
+import (
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// statsRecord holds the stats of a single ShortURL for backends without native counters (memory, bbolt).
+// Calls within the last 60 minutes are counted in minute buckets, so Calls60 is a real rolling window.
+// Hours and Days hold the time series buckets (see short.Granularity.Bucket).
+// Breakdowns holds the calls per dimension and value.
+// Visitors and DailyVisitors hold the unique visitors of all time and of the current day.
+type statsRecord struct {
+	Calls         uint64                       `json:"calls"`
+	Minutes       map[int64]uint64             `json:"minutes"`
+	Hours         map[int64]uint64             `json:"hours"`
+	Days          map[int64]uint64             `json:"days"`
+	Breakdowns    map[string]map[string]uint64 `json:"breakdowns"`
+	Visitors      hyperLogLog                  `json:"visitors"`
+	DailyVisitors map[int64]hyperLogLog        `json:"daily_visitors"`
+}
+
+// add counts the {click}
+func (r *statsRecord) add(click *short.Click, retention *StatsRetention) {
+	now := clickTime(click)
+	r.prune(now, retention)
+	if r.Minutes == nil {
+		r.Minutes = make(map[int64]uint64)
+	}
+	if r.Hours == nil {
+		r.Hours = make(map[int64]uint64)
+	}
+	if r.Days == nil {
+		r.Days = make(map[int64]uint64)
+	}
+	r.Calls++
+	r.Minutes[now.Unix()/60]++
+	r.Hours[short.GranularityHour.Bucket(now)]++
+	r.Days[short.GranularityDay.Bucket(now)]++
+
+	if r.Breakdowns == nil {
+		r.Breakdowns = make(map[string]map[string]uint64)
+	}
+	for dim, value := range clickDimensions(click) {
+		values, ok := r.Breakdowns[dim]
+		if !ok {
+			values = make(map[string]uint64)
+			r.Breakdowns[dim] = values
+		}
+		_, exists := values[value]
+		values[breakdownValue(value, exists, len(values))]++
+	}
+
+	if visitor := clickVisitor(click); visitor != "" {
+		if r.Visitors == nil {
+			r.Visitors = newHyperLogLog()
+		}
+		if r.DailyVisitors == nil {
+			r.DailyVisitors = make(map[int64]hyperLogLog)
+		}
+		day := short.GranularityDay.Bucket(now)
+		if r.DailyVisitors[day] == nil {
+			r.DailyVisitors[day] = newHyperLogLog()
+		}
+		r.Visitors.add(visitor)
+		r.DailyVisitors[day].add(visitor)
+	}
+}
+
+// prune removes all minute buckets older than 60 minutes
+// and all time series buckets older than the retention.
+// Only the visitors of the current day are kept.
+func (r *statsRecord) prune(now time.Time, retention *StatsRetention) {
+	pruneBuckets(r.Minutes, now.Add(-time.Hour).Unix()/60+1)
+	today := short.GranularityDay.Bucket(now)
+	for day := range r.DailyVisitors {
+		if day < today {
+			delete(r.DailyVisitors, day)
+		}
+	}
+	if retention != nil {
+		pruneBuckets(r.Hours, retention.oldestBucket(short.GranularityHour, now))
+		pruneBuckets(r.Days, retention.oldestBucket(short.GranularityDay, now))
+	}
+}
+
+func pruneBuckets(buckets map[int64]uint64, oldest int64) {
+	for b := range buckets {
+		if b < oldest {
+			delete(buckets, b)
+		}
+	}
+}
+
+// stats converts the record to a short.Stats object
+func (r *statsRecord) stats(now time.Time) *short.Stats {
+	r.prune(now, nil)
+	var calls60 uint64
+	for _, c := range r.Minutes {
+		calls60 += c
+	}
+	return &short.Stats{
+		Calls:         r.Calls,
+		Calls60:       calls60,
+		Visitors:      r.Visitors.count(),
+		VisitorsToday: r.DailyVisitors[short.GranularityDay.Bucket(now)].count(),
+		Breakdowns:    topBreakdowns(r.Breakdowns),
+	}
+}
+
+// timeSeries returns the calls between {from} and {to}
+func (r *statsRecord) timeSeries(g short.Granularity, from, to time.Time, retention *StatsRetention) *short.TimeSeries {
+	buckets := r.Hours
+	if g == short.GranularityDay {
+		buckets = r.Days
+	}
+	return retention.timeSeries(g, from, to, func(bucket int64) uint64 {
+		return buckets[bucket]
+	})
+}
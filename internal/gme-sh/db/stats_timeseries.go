@@ -0,0 +1,85 @@
+package db
+
+import (
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+const (
+	// DefaultHourlyRetentionDays -> hourly buckets are kept for 7 days by default
+	DefaultHourlyRetentionDays = 7
+
+	// DefaultDailyRetentionDays -> daily buckets are kept for a year by default
+	DefaultDailyRetentionDays = 365
+)
+
+// StatsRetention defines how long the time series buckets are kept by a StatsDatabase.
+// Every call is counted in an hourly and a daily bucket,
+// so after the hourly buckets are removed, the calls are still available per day.
+type StatsRetention struct {
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// NewStatsRetention creates a StatsRetention from the config and uses the defaults for missing values
+func NewStatsRetention(cfg *config.StatsConfig) *StatsRetention {
+	hourly, daily := DefaultHourlyRetentionDays, DefaultDailyRetentionDays
+	if cfg != nil && cfg.HourlyRetentionDays > 0 {
+		hourly = cfg.HourlyRetentionDays
+	}
+	if cfg != nil && cfg.DailyRetentionDays > 0 {
+		daily = cfg.DailyRetentionDays
+	}
+	return &StatsRetention{
+		Hourly: time.Duration(hourly) * 24 * time.Hour,
+		Daily:  time.Duration(daily) * 24 * time.Hour,
+	}
+}
+
+// Of returns how long buckets of the granularity {g} are kept
+func (r *StatsRetention) Of(g short.Granularity) time.Duration {
+	if g == short.GranularityDay {
+		return r.Daily
+	}
+	return r.Hourly
+}
+
+// oldestBucket returns the oldest bucket of granularity {g} which is still kept at {now}
+func (r *StatsRetention) oldestBucket(g short.Granularity, now time.Time) int64 {
+	return g.Bucket(now.Add(-r.Of(g)))
+}
+
+// buckets returns all buckets between {from} and {to} (inclusive) which are still kept at {now}
+func (r *StatsRetention) buckets(g short.Granularity, from, to, now time.Time) (res []int64) {
+	first, last := g.Bucket(from), g.Bucket(to)
+	if oldest := r.oldestBucket(g, now); first < oldest {
+		first = oldest
+	}
+	if newest := g.Bucket(now); last > newest {
+		last = newest
+	}
+	for b := first; b <= last; b++ {
+		res = append(res, b)
+	}
+	return
+}
+
+// timeSeries builds a TimeSeries for every bucket between {from} and {to} with the calls returned by {calls}
+func (r *StatsRetention) timeSeries(g short.Granularity, from, to time.Time,
+	calls func(bucket int64) uint64) *short.TimeSeries {
+	ts := &short.TimeSeries{
+		Granularity: g,
+		From:        from,
+		To:          to,
+		Points:      []*short.TimeSeriesPoint{},
+	}
+	for _, b := range r.buckets(g, from, to, time.Now()) {
+		ts.Points = append(ts.Points, &short.TimeSeriesPoint{
+			Time:  g.Time(b),
+			Calls: calls(b),
+		})
+	}
+	return ts
+}
@@ -0,0 +1,212 @@
+package geoip
+
+import (
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ReloadCheckInterval -> how often the database files are checked for changes
+var ReloadCheckInterval = time.Minute
+
+// Location -> the location of an IP address.
+// Fields which were not looked up are empty, fields which were not found short.Unknown.
+type Location struct {
+	Country string
+	City    string
+	ASN     string
+}
+
+// Database looks up IP addresses in local MaxMind databases (.mmdb), without any network access.
+// The files are reloaded when they change. A nil *Database is disabled and finds nothing.
+type Database struct {
+	cfg       *config.GeoIPConfig
+	mu        sync.RWMutex
+	country   *mmdbFile
+	asn       *mmdbFile
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type mmdbFile struct {
+	path    string
+	modTime time.Time
+	reader  *maxminddb.Reader
+}
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// New -> opens the databases of the {cfg}.
+// Returns nil (disabled) without an error if no database is configured.
+func New(cfg *config.GeoIPConfig) (db *Database, err error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, nil
+	}
+	db = &Database{
+		cfg:    cfg,
+		closed: make(chan struct{}),
+	}
+	if db.country, err = openMMDB(cfg.Path); err != nil {
+		return nil, err
+	}
+	if cfg.ASNPath != "" {
+		if db.asn, err = openMMDB(cfg.ASNPath); err != nil {
+			_ = db.country.reader.Close()
+			return nil, err
+		}
+	}
+	go db.watch()
+	return
+}
+
+func openMMDB(path string) (f *mmdbFile, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return
+	}
+	var reader *maxminddb.Reader
+	if reader, err = maxminddb.Open(path); err != nil {
+		return
+	}
+	f = &mmdbFile{
+		path:    path,
+		modTime: info.ModTime(),
+		reader:  reader,
+	}
+	return
+}
+
+// watch reloads the databases when the files change until the Database is closed
+func (db *Database) watch() {
+	ticker := time.NewTicker(ReloadCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-db.closed:
+			return
+		case <-ticker.C:
+			db.reload()
+		}
+	}
+}
+
+// reload replaces the databases whose files changed.
+// If a file can't be opened (e. g. while it is being replaced), the old database is kept.
+func (db *Database) reload() {
+	for _, f := range []**mmdbFile{&db.country, &db.asn} {
+		db.mu.RLock()
+		old := *f
+		db.mu.RUnlock()
+		if old == nil {
+			continue
+		}
+		if info, err := os.Stat(old.path); err != nil || info.ModTime().Equal(old.modTime) {
+			continue
+		}
+		updated, err := openMMDB(old.path)
+		if err != nil {
+			log.Println("GEOIP :: Could not reload", old.path, ":", err)
+			continue
+		}
+		db.mu.Lock()
+		// Close only closes the readers which are set, so a reader must not be swapped in afterwards
+		if db.isClosed() {
+			db.mu.Unlock()
+			_ = updated.reader.Close()
+			return
+		}
+		*f = updated
+		db.mu.Unlock()
+		_ = old.reader.Close()
+		log.Println("GEOIP :: Reloaded", old.path)
+	}
+}
+
+// isClosed returns true if Close was called
+func (db *Database) isClosed() bool {
+	select {
+	case <-db.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Lookup returns the location of the {ip} or nil if the Database is disabled
+func (db *Database) Lookup(ip string) *Location {
+	if db == nil {
+		return nil
+	}
+	loc := &Location{Country: short.Unknown}
+	if db.cfg.City {
+		loc.City = short.Unknown
+	}
+	if db.cfg.ASNPath != "" {
+		loc.ASN = short.Unknown
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return loc
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var rec countryRecord
+	if err := db.country.reader.Lookup(addr, &rec); err == nil {
+		if rec.Country.ISOCode != "" {
+			loc.Country = rec.Country.ISOCode
+		}
+		if name := rec.City.Names["en"]; db.cfg.City && name != "" {
+			loc.City = name + ", " + loc.Country
+		}
+	}
+	if db.asn != nil {
+		var asn asnRecord
+		if err := db.asn.reader.Lookup(addr, &asn); err == nil && asn.Number != 0 {
+			loc.ASN = "AS" + strconv.FormatUint(uint64(asn.Number), 10)
+			if asn.Organization != "" {
+				loc.ASN += " " + asn.Organization
+			}
+		}
+	}
+	return loc
+}
+
+// Close stops reloading and closes the databases
+func (db *Database) Close() (err error) {
+	if db == nil {
+		return
+	}
+	db.closeOnce.Do(func() {
+		close(db.closed)
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		err = db.country.reader.Close()
+		if db.asn != nil {
+			if asnErr := db.asn.reader.Close(); err == nil {
+				err = asnErr
+			}
+		}
+	})
+	return
+}
@@ -0,0 +1,237 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+/*
+ * ==================================================================================================
+ *                                 M M D B   F I X T U R E S
+ * ==================================================================================================
+ */
+
+// mmdbValue encodes {v} in the data section format of the MaxMind DB spec
+// (only the types the fixtures need, with sizes < 29)
+func mmdbValue(v interface{}) []byte {
+	control := func(typ, size int) []byte {
+		if typ <= 7 {
+			return []byte{byte(typ<<5 | size)}
+		}
+		// extended type
+		return []byte{byte(size), byte(typ - 7)}
+	}
+	number := func(typ int, n uint64) []byte {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], n)
+		b := buf[:]
+		for len(b) > 0 && b[0] == 0 {
+			b = b[1:]
+		}
+		return append(control(typ, len(b)), b...)
+	}
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case uint16:
+		return number(5, uint64(v))
+	case uint32:
+		return number(6, uint64(v))
+	case uint64:
+		return number(9, v)
+	case []string:
+		b := control(11, len(v))
+		for _, s := range v {
+			b = append(b, mmdbValue(s)...)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b := control(7, len(v))
+		for _, k := range keys {
+			b = append(b, mmdbValue(k)...)
+			b = append(b, mmdbValue(v[k])...)
+		}
+		return b
+	}
+	panic("unsupported mmdb value")
+}
+
+// writeMMDB writes an IPv4 database to {path} which only contains the {record} for the /24 {network}
+func writeMMDB(t *testing.T, path, network string, record map[string]interface{}) {
+	t.Helper()
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := ipNet.IP.To4()
+	const nodeCount = 24
+
+	// one node per bit of the prefix, the other branch is empty (= nodeCount)
+	tree := make([]byte, 0, nodeCount*6)
+	for i := 0; i < nodeCount; i++ {
+		next := uint32(i + 1)
+		if i == nodeCount-1 {
+			// pointer to the record at the start of the data section
+			next = nodeCount + 16
+		}
+		records := [2]uint32{nodeCount, nodeCount}
+		records[ip[i/8]>>(7-uint(i%8))&1] = next
+		for _, r := range records {
+			tree = append(tree, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+
+	data := append(tree, make([]byte, 16)...)
+	data = append(data, mmdbValue(record)...)
+	data = append(data, "\xAB\xCD\xEFMaxMind.com"...)
+	data = append(data, mmdbValue(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               "GeoIP2-City",
+		"description":                 map[string]interface{}{"en": "gme.sh test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []string{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})...)
+	replaceFile(t, path, data)
+}
+
+// replaceFile replaces {path} atomically like geoipupdate does,
+// the open databases are memory mapped, so the file must not be changed in place
+func replaceFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cityRecord returns a record of a GeoIP2 City database
+func cityRecord(country, city string) map[string]interface{} {
+	return map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": country},
+		"city":    map[string]interface{}{"names": map[string]interface{}{"en": city}},
+	}
+}
+
+/*
+ * ==================================================================================================
+ *                                        T E S T S
+ * ==================================================================================================
+ */
+
+func TestDisabled(t *testing.T) {
+	for _, cfg := range []*config.GeoIPConfig{nil, {}} {
+		db, err := New(cfg)
+		if db != nil || err != nil {
+			t.Fatalf("expected disabled database, got %v, %v", db, err)
+		}
+	}
+	var db *Database
+	if loc := db.Lookup("1.2.3.4"); loc != nil {
+		t.Errorf("expected no location, got %+v", loc)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("closing disabled database: %v", err)
+	}
+}
+
+func TestMissingFile(t *testing.T) {
+	db, err := New(&config.GeoIPConfig{Path: filepath.Join(t.TempDir(), "missing.mmdb")})
+	if err == nil {
+		_ = db.Close()
+		t.Fatal("expected an error for a missing database file")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeMMDB(t, path, "1.2.3.0/24", cityRecord("DE", "Berlin"))
+	db, err := New(&config.GeoIPConfig{Path: path, City: true})
+	if err != nil {
+		t.Fatal("opening database:", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		ip       string
+		expected Location
+	}{
+		{"1.2.3.4", Location{Country: "DE", City: "Berlin, DE"}},
+		{"1.2.4.4", Location{Country: short.Unknown, City: short.Unknown}},
+		{"not an ip", Location{Country: short.Unknown, City: short.Unknown}},
+	}
+	for _, tc := range tests {
+		if loc := db.Lookup(tc.ip); *loc != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.ip, tc.expected, *loc)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeMMDB(t, path, "1.2.3.0/24", cityRecord("DE", "Berlin"))
+	db, err := New(&config.GeoIPConfig{Path: path})
+	if err != nil {
+		t.Fatal("opening database:", err)
+	}
+	defer db.Close()
+
+	// unchanged files are kept
+	old := db.country
+	db.reload()
+	if db.country != old {
+		t.Error("expected the unchanged database to be kept")
+	}
+
+	// a file which can't be opened is ignored, the old database is kept
+	replaceFile(t, path, []byte("broken"))
+	if err = os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	db.reload()
+	if loc := db.Lookup("1.2.3.4"); loc.Country != "DE" {
+		t.Errorf("expected the old database to be kept, got %+v", *loc)
+	}
+
+	// changed files are reloaded
+	writeMMDB(t, path, "1.2.3.0/24", cityRecord("FR", "Paris"))
+	if err = os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	db.reload()
+	if loc := db.Lookup("1.2.3.4"); loc.Country != "FR" {
+		t.Errorf("expected the reloaded database, got %+v", *loc)
+	}
+
+	// no database is swapped in after the Database was closed
+	if err = db.Close(); err != nil {
+		t.Fatal("closing database:", err)
+	}
+	closed := db.country
+	writeMMDB(t, path, "1.2.3.0/24", cityRecord("IT", "Rome"))
+	if err = os.Chtimes(path, time.Now(), time.Now().Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	db.reload()
+	if db.country != closed {
+		t.Error("expected no reload after Close")
+	}
+}
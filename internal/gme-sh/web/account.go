@@ -0,0 +1,79 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strings"
+	"time"
+)
+
+// localsAccount -> key of the account of the request in the fiber locals
+const localsAccount = "account"
+
+// account returns the account of the API key (Authorization: Bearer {key}).
+// Requests without API key are anonymous (nil, nil), an unknown key returns the error response.
+// The account is kept for the request, so the key is only looked up once (e. g. by the rate limit and the route).
+func (ws *WebServer) account(ctx *fiber.Ctx) (*short.Account, *shortreq.Response) {
+	if account, ok := ctx.Locals(localsAccount).(*short.Account); ok {
+		return account, nil
+	}
+	auth := ctx.Get(fiber.HeaderAuthorization)
+	if auth == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, shortreq.ResponseErrInvalidAPIKey
+	}
+	account, err := ws.accountDB.FindAccountByKey(short.HashAPIKey(strings.TrimPrefix(auth, "Bearer ")))
+	if err != nil || account == nil {
+		return nil, shortreq.ResponseErrInvalidAPIKey
+	}
+	ctx.Locals(localsAccount, account)
+	return account, nil
+}
+
+// consumeQuota counts a new short url of the {account} on the day of {now} and returns the error response
+// if the daily quota is exceeded. Anonymous requests and accounts without quota are not counted.
+// The counter is stored in the persistent backend, so the quota holds across all instances.
+// It is removed by the backend after the day.
+func (ws *WebServer) consumeQuota(account *short.Account, now time.Time) *shortreq.Response {
+	if account == nil || !account.HasQuota() {
+		return nil
+	}
+	n, err := ws.persistentDB.IncrementCounter(account.QuotaCounter(now))
+	if err != nil {
+		log.Println("⚠️ Error counting quota of", account.ID, ":", err)
+		return shortreq.ResponseErrAccountDatabase.WithMessage(err.Error())
+	}
+	// the counter was just created
+	if n == 1 {
+		if err = ws.persistentDB.ExpireCounter(account.QuotaCounter(now), account.QuotaReset(now)); err != nil {
+			log.Println("⚠️ Error expiring quota counter of", account.ID, ":", err)
+		}
+	}
+	if n > account.DailyQuota {
+		return shortreq.ResponseErrQuotaExceeded
+	}
+	return nil
+}
+
+// refundQuota gives back {n} short urls of the {account} which were counted by consumeQuota
+// on the day of {now}, but could not be saved
+func (ws *WebServer) refundQuota(account *short.Account, now time.Time, n int) {
+	if account == nil || !account.HasQuota() || n <= 0 {
+		return
+	}
+	if err := ws.persistentDB.DecrementCounter(account.QuotaCounter(now), uint64(n)); err != nil {
+		log.Println("⚠️ Error refunding quota of", account.ID, ":", err)
+	}
+}
+
+// ownerOf returns the id of the {account} or an empty id for anonymous requests
+func ownerOf(account *short.Account) short.AccountID {
+	if account == nil {
+		return ""
+	}
+	return account.ID
+}
@@ -0,0 +1,35 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gofiber/fiber/v2"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// DestinationCookieMaxAge -> how long a visitor is sent to the same destination of an A/B split
+const DestinationCookieMaxAge = 30 * 24 * time.Hour
+
+// pickDestination returns the destination of the visitor if the traffic of {sh} is split.
+// The destination is kept in a cookie, so the visitor is always sent to the same destination.
+func (ws *WebServer) pickDestination(ctx *fiber.Ctx, sh *short.ShortURL) int {
+	if !sh.IsSplit() {
+		return -1
+	}
+	name := "gme_dest_" + sh.ID.String()
+	if i, err := strconv.Atoi(ctx.Cookies(name)); err == nil &&
+		i >= 0 && i < len(sh.Destinations) && sh.Destinations[i].Weight > 0 {
+		return i
+	}
+	destination := sh.PickDestination(rand.Float64())
+	ctx.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    strconv.Itoa(destination),
+		Path:     "/" + sh.ID.String(),
+		MaxAge:   int(DestinationCookieMaxAge.Seconds()),
+		HTTPOnly: true,
+		SameSite: "Lax",
+	})
+	return destination
+}
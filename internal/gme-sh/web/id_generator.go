@@ -0,0 +1,64 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"log"
+)
+
+// idCounterName -> name of the persistent counter of the sequential and hashids id generators
+const idCounterName = "short_ids"
+
+// idGenerators -> all id generator strategies and the configured default
+type idGenerators struct {
+	strategies map[string]short.IDGenerator
+	fallback   short.IDGenerator
+}
+
+// newIDGenerators creates all id generator strategies.
+// The sequential and hashids generators share a counter in the {persistentDB}.
+func newIDGenerators(persistentDB db.PersistentDatabase, cfg *config.WebServerConfig) *idGenerators {
+	if cfg == nil {
+		cfg = &config.WebServerConfig{}
+	}
+	next := func() (uint64, error) {
+		return persistentDB.IncrementCounter(idCounterName)
+	}
+	res := &idGenerators{strategies: make(map[string]short.IDGenerator)}
+	for _, name := range []string{
+		short.IDGeneratorRandom,
+		short.IDGeneratorSequential,
+		short.IDGeneratorHashids,
+		short.IDGeneratorWords,
+	} {
+		gen, err := short.NewIDGenerator(name, cfg.IDLength, cfg.IDSalt, next)
+		if err != nil {
+			log.Fatalln("🚨 Error creating id generator:", err)
+		}
+		res.strategies[name] = gen
+	}
+
+	name := cfg.IDGenerator
+	if name == "" {
+		name = short.IDGeneratorRandom
+	} else if !short.IsValidIDGenerator(name) {
+		log.Println("⚠️ Invalid id generator", name, "configured, using", short.IDGeneratorRandom)
+		name = short.IDGeneratorRandom
+	}
+	if name == short.IDGeneratorHashids && cfg.IDSalt == "" {
+		log.Println("⚠️ No id salt configured, hashids ids can be decoded")
+	}
+	res.fallback = res.strategies[name]
+	return res
+}
+
+// find returns the id generator strategy {name} or the configured default if {name} is empty.
+// ok is false for unknown strategies.
+func (g *idGenerators) find(name string) (gen short.IDGenerator, ok bool) {
+	if name == "" {
+		return g.fallback, true
+	}
+	gen, ok = g.strategies[name]
+	return
+}
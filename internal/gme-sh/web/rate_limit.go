@@ -0,0 +1,110 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Route groups of the rate limit (see config.RateLimitConfig)
+const (
+	rateLimitCreate   = "create"
+	rateLimitDelete   = "delete"
+	rateLimitStats    = "stats"
+	rateLimitRedirect = "redirect"
+	rateLimitPool     = "pool"
+	rateLimitAdmin    = "admin"
+)
+
+// defaultRateLimitWindow -> window of policies without window
+const defaultRateLimitWindow = time.Minute
+
+// rateLimit returns the middleware which limits the requests of the route {group}.
+// Requests are counted per client ip or, with a valid API key, per account in fixed windows.
+// Admin requests are always counted per ip, the admin token is not an API key.
+// The counters are stored in the StatsDatabase, so the limit holds across all instances.
+// Every limited response has the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset (seconds) headers.
+func (ws *WebServer) rateLimit(group string) fiber.Handler {
+	cfg := ws.config.RateLimit
+	policy := rateLimitPolicy(cfg, group)
+	if policy == nil {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+	return func(ctx *fiber.Ctx) error {
+		key, max, window := "ip::"+ctx.IP(), policy.Max, policy.Window.Duration
+		// invalid keys are counted per ip, the route sends the error
+		if group != rateLimitAdmin {
+			if account, _ := ws.account(ctx); account != nil {
+				key, max = "key::"+account.ID.String(), keyMax(policy)
+				if p := cfg.Keys[account.ID.String()]; p != nil {
+					max, window = keyMax(p), p.Window.Duration
+				}
+			}
+		}
+		if max == 0 {
+			return ctx.Next()
+		}
+		if window <= 0 {
+			window = defaultRateLimitWindow
+		}
+
+		now := time.Now()
+		start := now.Truncate(window)
+		hits, err := ws.statsDB.IncrementRateLimit(group+"::"+key, start, window)
+		if err != nil {
+			// don't reject all requests if the stats backend is not available
+			log.Println("⚠️ Error counting rate limit", group, ":", err)
+			return ctx.Next()
+		}
+
+		var remaining uint64
+		if hits < max {
+			remaining = max - hits
+		}
+		// seconds until the window ends, rounded up
+		reset := strconv.FormatInt(int64((start.Add(window).Sub(now)+time.Second-1)/time.Second), 10)
+		ctx.Set("RateLimit-Limit", strconv.FormatUint(max, 10))
+		ctx.Set("RateLimit-Remaining", strconv.FormatUint(remaining, 10))
+		ctx.Set("RateLimit-Reset", reset)
+		if hits > max {
+			ctx.Set(fiber.HeaderRetryAfter, reset)
+			return shortreq.ResponseErrRateLimited.Send(ctx)
+		}
+		return ctx.Next()
+	}
+}
+
+// rateLimitPolicy returns the policy of the route {group} or nil if the group is not limited
+func rateLimitPolicy(cfg *config.RateLimitConfig, group string) *config.RateLimitPolicy {
+	if cfg == nil || cfg.Disabled {
+		return nil
+	}
+	switch group {
+	case rateLimitCreate:
+		return cfg.Create
+	case rateLimitDelete:
+		return cfg.Delete
+	case rateLimitStats:
+		return cfg.Stats
+	case rateLimitRedirect:
+		return cfg.Redirect
+	case rateLimitPool:
+		return cfg.Pool
+	case rateLimitAdmin:
+		return cfg.Admin
+	}
+	return nil
+}
+
+// keyMax returns the requests per window and API key of the {policy}
+func keyMax(policy *config.RateLimitPolicy) uint64 {
+	if policy.KeyMax > 0 {
+		return policy.KeyMax
+	}
+	return policy.Max
+}
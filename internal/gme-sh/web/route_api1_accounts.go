@@ -0,0 +1,123 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strings"
+	"time"
+)
+
+// accountIDLength -> length of generated account ids
+const accountIDLength = 10
+
+// accountWithKey -> data of a new account or a rotated key, the key is only returned once
+type accountWithKey struct {
+	*short.Account
+	Key string `json:"key"`
+}
+
+// POST /admin/accounts
+// Creates an account and returns its API key (requires the admin token)
+func (ws *WebServer) fiberRouteAdminAccountCreate(ctx *fiber.Ctx) (err error) {
+	if res := ws.checkAdmin(ctx); res != nil {
+		return res.Send(ctx)
+	}
+	req := new(shortreq.CreateAccountPayload)
+	if err = ctx.BodyParser(req); err != nil {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return shortreq.ResponseErrInvalidAccount.Send(ctx)
+	}
+
+	// generate an unused id
+	id, err := short.NewRandomIDGenerator(accountIDLength).Generate(func(id *short.ShortID) bool {
+		accountID := short.AccountID(*id)
+		a, err := ws.accountDB.FindAccount(&accountID)
+		return err != nil || a == nil
+	})
+	if err != nil {
+		return shortreq.ResponseErrAccountDatabase.SendWithMessage(ctx, err.Error())
+	}
+	account := &short.Account{
+		ID:         short.AccountID(id),
+		Name:       req.Name,
+		Created:    time.Now(),
+		DailyQuota: req.DailyQuota,
+	}
+	log.Println("🔑 Admin created account", account.ID, "("+account.Name+")")
+	return ws.sendNewAPIKey(ctx, account, shortreq.ResponseOkAccountCreated)
+}
+
+// GET /admin/accounts
+// Lists all accounts without their keys (requires the admin token)
+func (ws *WebServer) fiberRouteAdminAccounts(ctx *fiber.Ctx) (err error) {
+	if res := ws.checkAdmin(ctx); res != nil {
+		return res.Send(ctx)
+	}
+	accounts, err := ws.accountDB.FindAccounts()
+	if err != nil {
+		return shortreq.ResponseErrAccountDatabase.SendWithMessage(ctx, err.Error())
+	}
+	res := make([]*short.Account, len(accounts))
+	for i, a := range accounts {
+		res[i] = a.Redacted()
+	}
+	return shortreq.ResponseOkAccounts.SendWithData(ctx, res)
+}
+
+// DELETE /admin/accounts/:id
+// Deletes an account, its key is invalid afterwards. The short urls of the account are kept.
+func (ws *WebServer) fiberRouteAdminAccountDelete(ctx *fiber.Ctx) (err error) {
+	if res := ws.checkAdmin(ctx); res != nil {
+		return res.Send(ctx)
+	}
+	var account *short.Account
+	if account, err = ws.findAccountOrDie(ctx); account == nil {
+		return
+	}
+	if err = ws.accountDB.DeleteAccount(&account.ID); err != nil {
+		return shortreq.ResponseErrAccountDatabase.SendWithMessage(ctx, err.Error())
+	}
+	log.Println("🔑 Admin deleted account", account.ID)
+	return shortreq.ResponseOkAccountDeleted.Send(ctx)
+}
+
+// POST /admin/accounts/:id/rotate
+// Issues a new API key for an account, the old key is invalid afterwards (requires the admin token)
+func (ws *WebServer) fiberRouteAdminAccountRotate(ctx *fiber.Ctx) (err error) {
+	if res := ws.checkAdmin(ctx); res != nil {
+		return res.Send(ctx)
+	}
+	var account *short.Account
+	if account, err = ws.findAccountOrDie(ctx); account == nil {
+		return
+	}
+	log.Println("🔑 Admin rotated the api key of account", account.ID)
+	return ws.sendNewAPIKey(ctx, account, shortreq.ResponseOkAPIKeyRotated)
+}
+
+func (ws *WebServer) findAccountOrDie(ctx *fiber.Ctx) (account *short.Account, err error) {
+	id := short.AccountID(ctx.Params("id"))
+	if account, err = ws.accountDB.FindAccount(&id); err != nil || account == nil {
+		account = nil
+		err = shortreq.ResponseErrAccountNotFound.Send(ctx)
+	}
+	return
+}
+
+// sendNewAPIKey saves the {account} with a new API key and sends the key once with the {ok} response
+func (ws *WebServer) sendNewAPIKey(ctx *fiber.Ctx, account *short.Account, ok *shortreq.Response) error {
+	key, err := short.GenerateAPIKey()
+	if err != nil {
+		return shortreq.ResponseErrAccountDatabase.SendWithMessage(ctx, err.Error())
+	}
+	account.KeyHash = short.HashAPIKey(key)
+	if err = ws.accountDB.SaveAccount(account); err != nil {
+		return shortreq.ResponseErrAccountDatabase.SendWithMessage(ctx, err.Error())
+	}
+	return ok.SendWithData(ctx, &accountWithKey{Account: account.Redacted(), Key: key})
+}
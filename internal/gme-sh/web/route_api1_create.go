@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,65 +17,285 @@ func (ws *WebServer) fiberRouteCreate(ctx *fiber.Ctx) (err error) {
 	if err != nil {
 		return
 	}
+	account, res := ws.account(ctx)
+	if res != nil {
+		return res.Send(ctx)
+	}
+	dedup := ws.deduplicate(req)
+	sh, res := ws.newShortURL(req, ownerOf(account))
+	if res != nil {
+		return res.Send(ctx)
+	}
+	// duplicates are found before an id is generated, so they don't use up ids
+	if dedup {
+		if existing := ws.findDuplicate(sh); existing != nil {
+			return shortreq.ResponseOkExisting.SendWithData(ctx, existing)
+		}
+	}
+	secret, res := ws.assignID(sh, req, ws.persistentDB.ShortURLAvailable)
+	if res != nil {
+		return res.Send(ctx)
+	}
+	// only new short urls count towards the quota
+	now := time.Now()
+	if res = ws.consumeQuota(account, now); res != nil {
+		return res.Send(ctx)
+	}
+
+	// save to database
+	if err := ws.persistentDB.SaveShortenedURL(sh); err != nil {
+		ws.refundQuota(account, now, 1)
+		return shortreq.ResponseErrDatabaseSave.SendWithMessage(ctx, err.Error())
+	}
+
+	log.Println("    └ 💚 Looks like it worked out")
+	return shortreq.ResponseOkCreate.SendWithData(ctx, withSecret(sh, secret))
+}
+
+// newShortURL validates the {req} and creates the short url, which is not saved yet.
+// The url has no id and no secret until assignID is called,
+// so requests which are answered with a duplicate don't generate them.
+// {owner} is the account which creates the url (empty for anonymous requests).
+// If the request is invalid, the error response is returned.
+func (ws *WebServer) newShortURL(req *shortreq.CreateShortURLPayload,
+	owner short.AccountID) (sh *short.ShortURL, res *shortreq.Response) {
+	var err error
+	// a/b split (checked first, the first destination is the default url)
+	if res = ws.checkDestinations(req.Destinations); res != nil {
+		return
+	}
+	if len(req.Destinations) > 0 && req.FullURL == "" {
+		req.FullURL = req.Destinations[0].URL
+	}
 	// check url
-	if !shortreq.UrlRegex.MatchString(req.FullURL) {
-		log.Println("    └ 🤬 But the URL didn't match the regex")
-		return shortreq.ResponseErrInvalidURL.Send(ctx)
+	if res = ws.checkFullURL(req.FullURL); res != nil {
+		return
 	}
-	// parse given url
-	u, err := url.Parse(req.FullURL)
-	if err != nil {
-		return shortreq.ResponseErrInvalidURL.Send(ctx)
+	// utm
+	if req.FullURL, err = ws.tagUTM(req.FullURL, req.UTM); err != nil {
+		return nil, shortreq.ResponseErrInvalidURL
 	}
-	// check if url is blacklisted
-	if i, b := ws.getBlockedHostLocation(u); b {
-		return shortreq.ResponseErrDomainBlocked.SendWithMessage(ctx,
-			"domain is blocked (i#"+strconv.Itoa(i)+")")
+	for _, d := range req.Destinations {
+		if d.URL, err = ws.tagUTM(d.URL, req.UTM); err != nil {
+			return nil, shortreq.ResponseErrInvalidURL
+		}
+	}
+	if res = ws.checkTargets(req.Targets); res != nil {
+		return
+	}
+	for _, r := range req.Targets {
+		if r.URL, err = ws.tagUTM(r.URL, req.UTM); err != nil {
+			return nil, shortreq.ResponseErrInvalidURL
+		}
+	}
+	if req.FallbackURL != "" {
+		if res = ws.checkFullURL(req.FallbackURL); res != nil {
+			return
+		}
+	}
+	if req.RedirectStatus != 0 && !short.IsValidRedirectStatus(req.RedirectStatus) {
+		return nil, shortreq.ResponseErrInvalidRedirectStatus
+	}
+	if _, ok := ws.idGenerators.find(req.IDGenerator); !ok {
+		return nil, shortreq.ResponseErrInvalidIDGenerator
+	}
+
+	// expiration
+	expiration := expireAfter(req.ExpireAfterSeconds)
+
+	// password
+	var passwordHash string
+	if req.Password != "" {
+		if passwordHash, err = short.HashPassword(req.Password); err != nil {
+			return nil, shortreq.ResponseErrInvalidPassword
+		}
+	}
+
+	// create short url object
+	sh = &short.ShortURL{
+		FullURL:        req.FullURL,
+		CreationDate:   time.Now(),
+		ExpirationDate: expiration,
+		PasswordHash:   passwordHash,
+		MaxClicks:      req.MaxClicks,
+		ActivationDate: req.ActivationDate,
+		FallbackURL:    req.FallbackURL,
+		RedirectStatus: req.RedirectStatus,
+		ForwardQuery:   req.ForwardQuery,
+		ForwardPath:    req.ForwardPath,
+		Destinations:   req.Destinations,
+		Targets:        req.Targets,
+		Owner:          owner,
+	}
+
+	// activation
+	if !validActivation(sh) {
+		return nil, shortreq.ResponseErrInvalidActivation
+	}
+	return sh, nil
+}
+
+// reservedIDs -> ids which can't be used, because GET /{id}, POST /{id} (password)
+// or their forwarded paths are shadowed by another route (fiber ignores the case).
+// GET /health is registered in main, before the routes of the WebServer.
+var reservedIDs = map[string]bool{
+	"admin":     true,
+	"create":    true,
+	"dashboard": true,
+	"health":    true,
+	"links":     true,
+	"pool":      true,
+	"stats":     true,
+}
+
+// isReservedID returns true if {id} is one of the reservedIDs
+func isReservedID(id *short.ShortID) bool {
+	return reservedIDs[strings.ToLower(id.String())]
+}
+
+// assignID sets the id of the new {sh} to the preferred alias of the {req} or generates one,
+// and sets the hash of a new secret, which is returned.
+// {available} checks if an alias can be used, reserved ids are never used. Otherwise the error response is returned.
+func (ws *WebServer) assignID(sh *short.ShortURL, req *shortreq.CreateShortURLPayload,
+	available func(*short.ShortID) bool) (secret string, res *shortreq.Response) {
+	var err error
+	generator, ok := ws.idGenerators.find(req.IDGenerator)
+	if !ok {
+		return "", shortreq.ResponseErrInvalidIDGenerator
+	}
+	accept := func(id *short.ShortID) bool {
+		return !isReservedID(id) && available(id)
 	}
 	// no custom alias set?
 	// -> generate alias
-	if req.PreferredAlias == "" {
-		if generated := short.GenerateShortID(ws.persistentDB.ShortURLAvailable); !generated.IsEmpty() {
-			req.PreferredAlias = generated
-		} else {
-			return shortreq.ResponseErrGeneratedAliasNotAvailable.Send(ctx)
+	id := req.PreferredAlias
+	if id == "" {
+		if id, err = generator.Generate(accept); err == short.ErrIDNotAvailable {
+			return "", shortreq.ResponseErrGeneratedAliasNotAvailable
+		} else if err != nil {
+			return "", shortreq.ResponseErrGeneratedAliasNotAvailable.WithMessage(err.Error())
 		}
 	} else {
-		if available := ws.persistentDB.ShortURLAvailable(&req.PreferredAlias); !available {
-			return shortreq.ResponseErrAliasOccupied.Send(ctx)
+		if !accept(&id) {
+			return "", shortreq.ResponseErrAliasOccupied
 		}
 	}
 
 	// check short id
-	if !req.PreferredAlias.IsValid() {
-		return shortreq.ResponseErrInvalidID.Send(ctx)
+	if !id.IsValid() {
+		return "", shortreq.ResponseErrInvalidID
 	}
 
-	// expiration
-	duration := time.Duration(req.ExpireAfterSeconds) * time.Second
-	var expiration *time.Time
-	if duration > 0 {
-		v := time.Now().Add(duration)
-		expiration = &v
+	// generate secret
+	var secretHash string
+	if secret, secretHash, err = newSecret(); err != nil {
+		return "", shortreq.ResponseErrDatabaseSave.WithMessage(err.Error())
 	}
+	sh.ID = id
+	sh.Secret = secretHash
+	return secret, nil
+}
 
-	// generate secret
-	secret := short.GenerateID(32, short.AlwaysTrue, 0)
+// deduplicate returns true if an existing short url should be returned for the {req}.
+// Urls with an alias or an expiration are always created.
+func (ws *WebServer) deduplicate(req *shortreq.CreateShortURLPayload) bool {
+	dedup := ws.config.WebServer.Deduplicate
+	if req.Deduplicate != nil {
+		dedup = *req.Deduplicate
+	}
+	return dedup && req.PreferredAlias == "" && req.ExpireAfterSeconds <= 0
+}
 
-	// create short url object
-	sh := &short.ShortURL{
-		ID:             req.PreferredAlias,
-		FullURL:        req.FullURL,
-		CreationDate:   time.Now(),
-		ExpirationDate: expiration,
-		Secret:         secret.String(),
+// findDuplicate returns an existing, not expired short url which redirects like {sh} or nil.
+// The url was created by someone else, so it is returned without secret.
+func (ws *WebServer) findDuplicate(sh *short.ShortURL) *short.ShortURL {
+	hash := sh.DedupHash()
+	if hash == "" {
+		return nil
+	}
+	existing, err := ws.persistentDB.FindDuplicate(hash)
+	if err != nil || existing == nil || existing.IsExpired() {
+		return nil
 	}
+	return existing.Redacted()
+}
 
-	// save to database
-	if err := ws.persistentDB.SaveShortenedURL(sh); err != nil {
-		return shortreq.ResponseErrDatabaseSave.SendWithMessage(ctx, err.Error())
+// checkFullURL returns the error response if the {fullURL} is invalid or blocked
+func (ws *WebServer) checkFullURL(fullURL string) *shortreq.Response {
+	// check url
+	if !shortreq.UrlRegex.MatchString(fullURL) {
+		log.Println("    └ 🤬 But the URL didn't match the regex")
+		return shortreq.ResponseErrInvalidURL
+	}
+	// parse given url
+	u, err := url.Parse(fullURL)
+	if err != nil {
+		return shortreq.ResponseErrInvalidURL
+	}
+	// check if url is blacklisted
+	if i, b := ws.getBlockedHostLocation(u); b {
+		return shortreq.ResponseErrDomainBlocked.WithMessage("domain is blocked (i#" + strconv.Itoa(i) + ")")
 	}
+	return nil
+}
 
-	log.Println("    └ 💚 Looks like it worked out")
-	return shortreq.ResponseOkCreate.SendWithData(ctx, sh)
+// checkDestinations returns the error response if the weight or url of a destination is invalid
+func (ws *WebServer) checkDestinations(destinations []*short.Destination) *shortreq.Response {
+	if len(destinations) > short.MaxDestinations {
+		return shortreq.ResponseErrInvalidDestinations
+	}
+	for _, d := range destinations {
+		if d == nil || d.Weight == 0 {
+			return shortreq.ResponseErrInvalidDestinations
+		}
+		if res := ws.checkFullURL(d.URL); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// checkTargets returns the error response if the conditions or url of a target rule are invalid
+func (ws *WebServer) checkTargets(rules []*short.TargetRule) *shortreq.Response {
+	if len(rules) > short.MaxTargets {
+		return shortreq.ResponseErrInvalidTargets
+	}
+	for _, r := range rules {
+		if r == nil || !r.IsValid() {
+			return shortreq.ResponseErrInvalidTargets
+		}
+		if res := ws.checkFullURL(r.URL); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// tagUTM adds the {utm} parameters of the request and the default UTM parameters for the host to {fullURL}.
+// The parameters of the request replace those of the url, the defaults only add missing parameters.
+func (ws *WebServer) tagUTM(fullURL string, utm *short.UTM) (res string, err error) {
+	if res, err = short.TagURL(fullURL, utm, true); err != nil {
+		return
+	}
+	for _, def := range ws.defaultUTM(short.Hostname(res)) {
+		if res, err = short.TagURL(res, def, false); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// validActivation returns false if {sh} would expire before it is activated
+func validActivation(sh *short.ShortURL) bool {
+	return sh.ActivationDate == nil || sh.ExpirationDate == nil || sh.ActivationDate.Before(*sh.ExpirationDate)
+}
+
+// expireAfter returns the expiration date in {seconds} or nil if the url should not expire
+func expireAfter(seconds int) *time.Time {
+	duration := time.Duration(seconds) * time.Second
+	if duration <= 0 {
+		return nil
+	}
+	v := time.Now().Add(duration)
+	return &v
 }
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"time"
+)
+
+// MaxBulkCreate -> maximum number of short urls per POST /create/bulk
+const MaxBulkCreate = 500
+
+// POST /create/bulk
+// Creates multiple short urls. The body is a JSON array or a stream of JSON objects (NDJSON).
+// The data holds a result ({success, code, message, data}) for every item in the same order.
+// All valid items are saved in one round trip, invalid items don't affect the others.
+func (ws *WebServer) fiberRouteCreateBulk(ctx *fiber.Ctx) (err error) {
+	reqs, err := decodeBulkPayload(ctx.Body())
+	if err != nil {
+		return shortreq.ResponseErrInvalidBulk.SendWithMessage(ctx, err.Error())
+	}
+	if len(reqs) > MaxBulkCreate {
+		return shortreq.ResponseErrBulkTooLarge.Send(ctx)
+	}
+	account, res := ws.account(ctx)
+	if res != nil {
+		return res.Send(ctx)
+	}
+
+	// aliases of the batch are not saved yet, but can't be used twice
+	aliases := make(map[short.ShortID]bool)
+	available := func(id *short.ShortID) bool {
+		return !aliases[*id] && ws.persistentDB.ShortURLAvailable(id)
+	}
+
+	// permanent, deduplicable urls of the batch by DedupHash
+	batch := make(map[string]*short.ShortURL)
+
+	// the quota of all urls is counted for the same day, so it can be refunded if the batch can't be saved
+	now := time.Now()
+
+	results := make([]*shortreq.Successable, len(reqs))
+	var created []*short.ShortURL
+	var secrets []string
+	var indices []int
+	for i, req := range reqs {
+		if req == nil {
+			results[i] = shortreq.ResponseErrInvalidURL.Successable(nil)
+			continue
+		}
+		dedup := ws.deduplicate(req)
+		sh, res := ws.newShortURL(req, ownerOf(account))
+		if res != nil {
+			results[i] = res.Successable(nil)
+			continue
+		}
+		if dedup {
+			if existing, ok := batch[sh.DedupHash()]; ok {
+				results[i] = shortreq.ResponseOkExisting.Successable(existing.Redacted())
+				continue
+			}
+			if existing := ws.findDuplicate(sh); existing != nil {
+				results[i] = shortreq.ResponseOkExisting.Successable(existing)
+				continue
+			}
+		}
+		secret, res := ws.assignID(sh, req, available)
+		if res != nil {
+			results[i] = res.Successable(nil)
+			continue
+		}
+		if res = ws.consumeQuota(account, now); res != nil {
+			results[i] = res.Successable(nil)
+			continue
+		}
+		if hash := sh.DedupHash(); hash != "" {
+			batch[hash] = sh
+		}
+		aliases[sh.ID] = true
+		created = append(created, sh)
+		secrets = append(secrets, secret)
+		indices = append(indices, i)
+	}
+
+	// save to database
+	if err := db.SaveShortenedURLs(ws.persistentDB, created); err != nil {
+		ws.refundQuota(account, now, len(created))
+		return shortreq.ResponseErrDatabaseSave.SendWithMessage(ctx, err.Error())
+	}
+	for i, sh := range created {
+		results[indices[i]] = shortreq.ResponseOkCreate.Successable(withSecret(sh, secrets[i]))
+	}
+
+	log.Println("    └ 💚 Created", len(created), "of", len(reqs), "short urls")
+	return shortreq.ResponseOkBulkCreate.SendWithData(ctx, results)
+}
+
+// decodeBulkPayload decodes a JSON array or a stream of JSON objects.
+// It stops after MaxBulkCreate + 1 objects, so too large streams are not decoded completely.
+func decodeBulkPayload(body []byte) (reqs []*shortreq.CreateShortURLPayload, err error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &reqs)
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for dec.More() && len(reqs) <= MaxBulkCreate {
+		var req *shortreq.CreateShortURLPayload
+		if err = dec.Decode(&req); err != nil {
+			return
+		}
+		reqs = append(reqs, req)
+	}
+	return
+}
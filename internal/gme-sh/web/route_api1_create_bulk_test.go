@@ -0,0 +1,88 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+)
+
+// bulkResult -> result of an item of POST /create/bulk
+type bulkResult struct {
+	Success bool            `json:"success"`
+	Code    int             `json:"code"`
+	Data    *short.ShortURL `json:"data"`
+}
+
+func TestCreateBulk(t *testing.T) {
+	ws := newTestServer(t, func(cfg *config.Config) {
+		cfg.WebServer.Deduplicate = true
+	})
+	create(t, ws, `{"full_url": "https://example.com/taken", "preferred_alias": "taken"}`)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected []int // internal codes of the items
+	}{
+		{"array", `[
+			{"full_url": "https://example.com/a"},
+			{"full_url": "not a url"},
+			{"full_url": "https://example.com/b", "preferred_alias": "bulk-b"}
+		]`, []int{shortreq.ResponseOkCreate.InternalCode, shortreq.ResponseErrInvalidURL.InternalCode,
+			shortreq.ResponseOkCreate.InternalCode}},
+		{"ndjson", `{"full_url": "https://example.com/c"}
+			{"full_url": "https://example.com/d"}`,
+			[]int{shortreq.ResponseOkCreate.InternalCode, shortreq.ResponseOkCreate.InternalCode}},
+		{"aliases", `[
+			{"full_url": "https://example.com/e", "preferred_alias": "taken"},
+			{"full_url": "https://example.com/e", "preferred_alias": "twice"},
+			{"full_url": "https://example.com/f", "preferred_alias": "twice"}
+		]`, []int{shortreq.ResponseErrAliasOccupied.InternalCode, shortreq.ResponseOkCreate.InternalCode,
+			shortreq.ResponseErrAliasOccupied.InternalCode}},
+		{"duplicates", `[
+			{"full_url": "https://example.com/a"},
+			{"full_url": "https://example.com/g"},
+			{"full_url": "https://example.com/g"}
+		]`, []int{shortreq.ResponseOkExisting.InternalCode, shortreq.ResponseOkCreate.InternalCode,
+			shortreq.ResponseOkExisting.InternalCode}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res := request(t, ws, http.MethodPost, "/create/bulk", tc.body)
+			expectStatus(t, res, 200, shortreq.ResponseOkBulkCreate.InternalCode)
+			var results []*bulkResult
+			res.decode(t, &results)
+			if len(results) != len(tc.expected) {
+				t.Fatalf("expected %d results, got %s", len(tc.expected), res.Data)
+			}
+			for i, code := range tc.expected {
+				if results[i].Code != code {
+					t.Errorf("#%d: expected %d, got %d", i, code, results[i].Code)
+				}
+				// created urls can be used immediately
+				if code == shortreq.ResponseOkCreate.InternalCode {
+					u := results[i].Data
+					if u == nil || u.Secret == "" {
+						t.Fatalf("#%d: expected the url with its secret, got %+v", i, u)
+					}
+					redirect := request(t, ws, http.MethodGet, "/"+u.ID.String(), "")
+					if location := redirect.Header.Get("Location"); location != u.FullURL {
+						t.Errorf("#%d: expected redirect to %q, got %q", i, u.FullURL, location)
+					}
+				}
+			}
+		})
+	}
+
+	// the whole request is rejected
+	res := request(t, ws, http.MethodPost, "/create/bulk", `{"full_url": `)
+	expectStatus(t, res, 400, shortreq.ResponseErrInvalidBulk.InternalCode)
+	res = request(t, ws, http.MethodPost, "/create/bulk",
+		"["+strings.Repeat(`{"full_url": "https://example.com"},`, MaxBulkCreate)+`{}]`)
+	expectStatus(t, res, 413, shortreq.ResponseErrBulkTooLarge.InternalCode)
+}
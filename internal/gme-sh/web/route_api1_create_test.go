@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+)
+
+func TestCreateReservedIDs(t *testing.T) {
+	ws := newTestServer(t, nil)
+	// the first segments of the other routes, fiber ignores the case
+	for _, id := range []string{"admin", "create", "dashboard", "health", "Health", "links", "pool", "stats"} {
+		id := id
+		t.Run(id, func(t *testing.T) {
+			res := request(t, ws, http.MethodPost, "/create",
+				`{"full_url": "https://example.com", "preferred_alias": "`+id+`"}`)
+			expectStatus(t, res, 409, shortreq.ResponseErrAliasOccupied.InternalCode)
+		})
+	}
+}
+
+func TestCreateInvalidDestinations(t *testing.T) {
+	ws := newTestServer(t, nil)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"nil", `{"destinations": [null]}`},
+		{"empty", `{"destinations": [{}]}`},
+		{"nil after valid", `{"destinations": [{"url": "https://example.com", "weight": 1}, null]}`},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res := request(t, ws, http.MethodPost, "/create", tc.body)
+			expectStatus(t, res, 400, shortreq.ResponseErrInvalidDestinations.InternalCode)
+		})
+	}
+}
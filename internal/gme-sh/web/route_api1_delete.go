@@ -27,7 +27,7 @@ func (ws *WebServer) fiberRouteDelete(ctx *fiber.Ctx) (err error) {
 	}
 
 	// compare secrets
-	if sh.Secret != secret {
+	if !sh.CheckSecret(secret) {
 		return shortreq.ResponseErrSecretMismatch.Send(ctx)
 	}
 
@@ -37,5 +37,5 @@ func (ws *WebServer) fiberRouteDelete(ctx *fiber.Ctx) (err error) {
 		return
 	}
 
-	return shortreq.ResponseOkDeleted.SendWithData(ctx, sh)
+	return shortreq.ResponseOkDeleted.SendWithData(ctx, sh.Redacted())
 }
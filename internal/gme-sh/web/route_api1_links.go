@@ -0,0 +1,58 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+const (
+	// defaultLinksLimit -> links per page of GET /links if no limit is set
+	defaultLinksLimit = 50
+	// maxLinksLimit -> max. links per page of GET /links
+	maxLinksLimit = 100
+)
+
+// linksPage -> data of GET /links
+type linksPage struct {
+	Links  []*short.ShortURL `json:"links"`
+	Total  int               `json:"total"`
+	Offset int               `json:"offset"`
+	Limit  int               `json:"limit"`
+}
+
+// GET /links?offset=&limit=
+// Lists the short urls of the API key (Authorization: Bearer {key}), newest first
+func (ws *WebServer) fiberRouteLinks(ctx *fiber.Ctx) (err error) {
+	account, res := ws.account(ctx)
+	if res != nil {
+		return res.Send(ctx)
+	}
+	if account == nil {
+		return shortreq.ResponseErrAPIKeyRequired.Send(ctx)
+	}
+
+	page := &linksPage{Limit: defaultLinksLimit}
+	if v := ctx.Query("offset"); v != "" {
+		if page.Offset, err = strconv.Atoi(v); err != nil || page.Offset < 0 {
+			return shortreq.ResponseErrInvalidPage.Send(ctx)
+		}
+	}
+	if v := ctx.Query("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil || page.Limit < 1 || page.Limit > maxLinksLimit {
+			return shortreq.ResponseErrInvalidPage.Send(ctx)
+		}
+	}
+
+	urls, total, err := ws.accountDB.FindShortURLsByOwner(&account.ID, page.Offset, page.Limit)
+	if err != nil {
+		return shortreq.ResponseErrAccountDatabase.SendWithMessage(ctx, err.Error())
+	}
+	page.Total = total
+	page.Links = make([]*short.ShortURL, len(urls))
+	for i, u := range urls {
+		page.Links[i] = u.Redacted()
+	}
+	return shortreq.ResponseOkLinks.SendWithData(ctx, page)
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
 	"github.com/gofiber/fiber/v2"
-	"log"
 	"time"
 )
 
@@ -13,9 +12,8 @@ func (ws *WebServer) fiberRoutePoolGet(ctx *fiber.Ctx) (err error) {
 	if pool, err = ws.findPoolOrDie(ctx); pool == nil {
 		return
 	}
-	log.Println("pool, err :=", pool, err)
 	// return pool
-	return shortreq.ResponseOkPoolGet.SendWithData(ctx, pool)
+	return shortreq.ResponseOkPoolGet.SendWithData(ctx, pool.Redacted())
 }
 
 func (ws *WebServer) fiberRoutePoolUpdate(ctx *fiber.Ctx) (err error) {
@@ -62,7 +60,7 @@ func (ws *WebServer) findPoolOrDie(ctx *fiber.Ctx) (pool *short.Pool, err error)
 		return
 	}
 	// check secret
-	if pool.Secret != secret {
+	if !ws.checkPoolSecret(pool, secret) {
 		pool = nil
 		err = shortreq.ResponseErrPoolSecretMismatch.Send(ctx)
 		return
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"crypto/subtle"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strings"
+)
+
+// POST /:id/:secret/rotate
+// Issues a new secret, the old secret is invalid afterwards
+func (ws *WebServer) fiberRouteRotate(ctx *fiber.Ctx) (err error) {
+	id := short.ShortID(ctx.Params("id"))
+	if id.IsEmpty() {
+		return shortreq.ResponseErrEmptyID.Send(ctx)
+	}
+
+	// find short url
+	sh, err := ws.persistentDB.FindShortenedURL(&id)
+	if err != nil {
+		return shortreq.ResponseErrURLNotFound.SendWithMessage(ctx, err.Error())
+	}
+
+	// the route shadows POST /{id}/{path...}, so the password of a protected url
+	// with path forwarding may be submitted to /{id}/{path}/rotate
+	passwordRoute := sh.IsProtected() && sh.ForwardPath
+
+	// check if locked
+	if sh.IsLocked() {
+		if passwordRoute {
+			return ctx.Next()
+		}
+		return shortreq.ResponseErrLocked.Send(ctx)
+	}
+
+	// compare secrets
+	owned := ws.checkSecret(sh, ctx.Params("secret"))
+	if owned == nil {
+		if passwordRoute {
+			return ctx.Next()
+		}
+		return shortreq.ResponseErrSecretMismatch.Send(ctx)
+	}
+
+	return ws.sendNewSecret(ctx, owned, shortreq.ResponseOkSecretRotated)
+}
+
+// POST /pool/:id/:secret/rotate
+// Issues a new secret for the pool, the old secret is invalid afterwards
+func (ws *WebServer) fiberRoutePoolRotate(ctx *fiber.Ctx) (err error) {
+	var pool *short.Pool
+	if pool, err = ws.findPoolOrDie(ctx); pool == nil {
+		return
+	}
+	return ws.sendNewPoolSecret(ctx, pool, shortreq.ResponseOkSecretRotated)
+}
+
+// POST /admin/reset/:id
+// Issues a new secret for a short url whose secret was lost or leaked (requires the admin token)
+func (ws *WebServer) fiberRouteAdminReset(ctx *fiber.Ctx) (err error) {
+	if res := ws.checkAdmin(ctx); res != nil {
+		return res.Send(ctx)
+	}
+	id := short.ShortID(ctx.Params("id"))
+	if id.IsEmpty() {
+		return shortreq.ResponseErrEmptyID.Send(ctx)
+	}
+	sh, err := ws.persistentDB.FindShortenedURL(&id)
+	if err != nil {
+		return shortreq.ResponseErrURLNotFound.SendWithMessage(ctx, err.Error())
+	}
+	log.Println("🔑 Admin reset the secret of", id)
+	return ws.sendNewSecret(ctx, sh, shortreq.ResponseOkSecretReset)
+}
+
+// POST /admin/pool/reset/:id
+// Issues a new secret for a pool whose secret was lost or leaked (requires the admin token)
+func (ws *WebServer) fiberRouteAdminPoolReset(ctx *fiber.Ctx) (err error) {
+	if res := ws.checkAdmin(ctx); res != nil {
+		return res.Send(ctx)
+	}
+	id := short.PoolID(ctx.Params("id"))
+	pool, err := ws.persistentDB.FindPool(&id)
+	if err != nil {
+		return shortreq.ResponseErrPoolNotFound.SendWithMessage(ctx, err.Error())
+	}
+	log.Println("🔑 Admin reset the secret of pool", id)
+	return ws.sendNewPoolSecret(ctx, pool, shortreq.ResponseOkSecretReset)
+}
+
+// checkAdmin returns the error response if the request has no valid admin token (Authorization: Bearer {token})
+func (ws *WebServer) checkAdmin(ctx *fiber.Ctx) *shortreq.Response {
+	token := ws.config.WebServer.AdminToken
+	if token == "" {
+		return shortreq.ResponseErrAdminDisabled
+	}
+	auth := ctx.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(auth, "Bearer ") ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+		return shortreq.ResponseErrAdminUnauthorized
+	}
+	return nil
+}
+
+// sendNewSecret saves {sh} with a new secret and sends it once with the {ok} response
+func (ws *WebServer) sendNewSecret(ctx *fiber.Ctx, sh *short.ShortURL, ok *shortreq.Response) error {
+	secret, hash, err := newSecret()
+	if err != nil {
+		return shortreq.ResponseErrSecretRotation.SendWithMessage(ctx, err.Error())
+	}
+	// the object may be shared with the cache, so only the copy is changed
+	rotated := *sh
+	rotated.Secret = hash
+	// UpdateShortenedURL keeps the clicks and breaks the cached copies on all nodes,
+	// so no node accepts the old secret afterwards
+	if err = ws.persistentDB.UpdateShortenedURL(&rotated); err != nil {
+		return shortreq.ResponseErrSecretRotation.SendWithMessage(ctx, err.Error())
+	}
+	return ok.SendWithData(ctx, withSecret(&rotated, secret))
+}
+
+// sendNewPoolSecret saves the {pool} with a new secret and sends it once with the {ok} response
+func (ws *WebServer) sendNewPoolSecret(ctx *fiber.Ctx, pool *short.Pool, ok *shortreq.Response) error {
+	secret, hash, err := newSecret()
+	if err != nil {
+		return shortreq.ResponseErrSecretRotation.SendWithMessage(ctx, err.Error())
+	}
+	pool.Secret = hash
+	if err = ws.persistentDB.SavePool(pool); err != nil {
+		return shortreq.ResponseErrSecretRotation.SendWithMessage(ctx, err.Error())
+	}
+	res := pool.Redacted()
+	res.Secret = secret
+	return ok.SendWithData(ctx, res)
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
 	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"time"
 )
 
 // GET /api/v1/stats/{id}
@@ -21,3 +23,55 @@ func (ws *WebServer) fiberRouteStats(ctx *fiber.Ctx) (err error) {
 
 	return shortreq.ResponseOkStats.SendWithData(ctx, stats)
 }
+
+// GET /stats/{id}/timeseries?from=&to=&granularity=
+// from and to are either unix timestamps (seconds) or RFC 3339 dates.
+// Defaults: granularity = hour, to = now, from = 24 hours (hour) / 30 days (day) before to
+func (ws *WebServer) fiberRouteStatsTimeSeries(ctx *fiber.Ctx) (err error) {
+	id := short.ShortID(ctx.Params("id"))
+	if id.IsEmpty() {
+		return shortreq.ResponseErrEmptyID.Send(ctx)
+	}
+
+	granularity := short.GranularityHour
+	if g := ctx.Query("granularity"); g != "" {
+		var ok bool
+		if granularity, ok = short.ParseGranularity(g); !ok {
+			return shortreq.ResponseErrInvalidGranularity.Send(ctx)
+		}
+	}
+
+	to := time.Now()
+	if v := ctx.Query("to"); v != "" {
+		if to, err = parseTime(v); err != nil {
+			return shortreq.ResponseErrInvalidTimeRange.SendWithMessage(ctx, "invalid to: "+err.Error())
+		}
+	}
+	from := to.Add(-24 * time.Hour)
+	if granularity == short.GranularityDay {
+		from = to.Add(-30 * 24 * time.Hour)
+	}
+	if v := ctx.Query("from"); v != "" {
+		if from, err = parseTime(v); err != nil {
+			return shortreq.ResponseErrInvalidTimeRange.SendWithMessage(ctx, "invalid from: "+err.Error())
+		}
+	}
+	if from.After(to) {
+		return shortreq.ResponseErrInvalidTimeRange.Send(ctx)
+	}
+
+	var ts *short.TimeSeries
+	if ts, err = ws.statsDB.FindTimeSeries(&id, granularity, from, to); err != nil {
+		return
+	}
+
+	return shortreq.ResponseOkStats.SendWithData(ctx, ts)
+}
+
+// parseTime parses a unix timestamp (seconds) or a RFC 3339 date
+func parseTime(v string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
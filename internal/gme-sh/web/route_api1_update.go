@@ -0,0 +1,118 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+// PATCH /:id/:secret
+func (ws *WebServer) fiberRouteUpdate(ctx *fiber.Ctx) (err error) {
+	id := short.ShortID(ctx.Params("id"))
+	if id.IsEmpty() {
+		return shortreq.ResponseErrEmptyID.Send(ctx)
+	}
+
+	secret := ctx.Params("secret")
+
+	req := new(shortreq.UpdateShortURLPayload)
+	if err = ctx.BodyParser(req); err != nil {
+		return
+	}
+
+	// find short url
+	sh, err := ws.persistentDB.FindShortenedURL(&id)
+	if err != nil {
+		return shortreq.ResponseErrURLNotFound.SendWithMessage(ctx, err.Error())
+	}
+
+	// check if locked
+	if sh.IsLocked() {
+		return shortreq.ResponseErrLocked.Send(ctx)
+	}
+
+	// compare secrets
+	if sh = ws.checkSecret(sh, secret); sh == nil {
+		return shortreq.ResponseErrSecretMismatch.Send(ctx)
+	}
+
+	// expired urls are deleted soon, they can't be revived
+	if sh.IsExpired() {
+		return shortreq.ResponseErrExpired.Send(ctx)
+	}
+
+	// the object may be shared with the cache, so only the copy is changed
+	updated := *sh
+	if req.FullURL != nil {
+		if res := ws.checkFullURL(*req.FullURL); res != nil {
+			return res.Send(ctx)
+		}
+		updated.FullURL = *req.FullURL
+	}
+	if req.FallbackURL != nil {
+		if *req.FallbackURL != "" {
+			if res := ws.checkFullURL(*req.FallbackURL); res != nil {
+				return res.Send(ctx)
+			}
+		}
+		updated.FallbackURL = *req.FallbackURL
+	}
+	if req.RedirectStatus != nil {
+		if *req.RedirectStatus != 0 && !short.IsValidRedirectStatus(*req.RedirectStatus) {
+			return shortreq.ResponseErrInvalidRedirectStatus.Send(ctx)
+		}
+		updated.RedirectStatus = *req.RedirectStatus
+	}
+	if req.ForwardQuery != nil {
+		updated.ForwardQuery = *req.ForwardQuery
+	}
+	if req.ForwardPath != nil {
+		updated.ForwardPath = *req.ForwardPath
+	}
+	if req.Destinations != nil {
+		if res := ws.checkDestinations(*req.Destinations); res != nil {
+			return res.Send(ctx)
+		}
+		updated.Destinations = *req.Destinations
+		if len(updated.Destinations) == 0 {
+			updated.Destinations = nil
+		}
+	}
+	if req.Targets != nil {
+		if res := ws.checkTargets(*req.Targets); res != nil {
+			return res.Send(ctx)
+		}
+		updated.Targets = *req.Targets
+		if len(updated.Targets) == 0 {
+			updated.Targets = nil
+		}
+	}
+	if req.ExpireAfterSeconds != nil {
+		updated.ExpirationDate = expireAfter(*req.ExpireAfterSeconds)
+	}
+	if req.ActivationDate != nil {
+		updated.ActivationDate = req.ActivationDate
+		if !updated.ActivationDate.After(time.Now()) {
+			updated.ActivationDate = nil
+		}
+	}
+	if !validActivation(&updated) {
+		return shortreq.ResponseErrInvalidActivation.Send(ctx)
+	}
+	if req.Password != nil {
+		updated.PasswordHash = ""
+		if *req.Password != "" {
+			if updated.PasswordHash, err = short.HashPassword(*req.Password); err != nil {
+				return shortreq.ResponseErrInvalidPassword.Send(ctx)
+			}
+		}
+	}
+
+	// save to database
+	if err := ws.persistentDB.UpdateShortenedURL(&updated); err != nil {
+		return shortreq.ResponseErrDatabaseSave.SendWithMessage(ctx, err.Error())
+	}
+
+	return shortreq.ResponseOkUpdated.SendWithData(ctx, updated.Redacted())
+}
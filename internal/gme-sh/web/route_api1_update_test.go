@@ -0,0 +1,67 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+)
+
+func TestUpdate(t *testing.T) {
+	ws := newTestServer(t, nil)
+	sh := create(t, ws, `{"full_url": "https://example.com/old"}`)
+	target := "/" + sh.ID.String() + "/" + sh.Secret
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		status   int
+		code     int
+		location string // of GET /{id} after the update
+	}{
+		{"wrong secret", "/" + sh.ID.String() + "/wrong", `{"full_url": "https://example.com/new"}`, 401,
+			shortreq.ResponseErrSecretMismatch.InternalCode, "https://example.com/old"},
+		{"invalid url", target, `{"full_url": "not a url"}`, 400,
+			shortreq.ResponseErrInvalidURL.InternalCode, "https://example.com/old"},
+		{"invalid redirect status", target, `{"redirect_status": 303}`, 400,
+			shortreq.ResponseErrInvalidRedirectStatus.InternalCode, "https://example.com/old"},
+		{"url and status", target, `{"full_url": "https://example.com/new", "redirect_status": 301}`, 200,
+			shortreq.ResponseOkUpdated.InternalCode, "https://example.com/new"},
+		{"unchanged fields are kept", target, `{"forward_query": true}`, 200,
+			shortreq.ResponseOkUpdated.InternalCode, "https://example.com/new?q=1"},
+		{"split", target, `{"destinations": [{"url": "https://example.com/split", "weight": 1}]}`, 200,
+			shortreq.ResponseOkUpdated.InternalCode, "https://example.com/split?q=1"},
+		{"remove split", target, `{"destinations": []}`, 200,
+			shortreq.ResponseOkUpdated.InternalCode, "https://example.com/new?q=1"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res := request(t, ws, http.MethodPatch, tc.target, tc.body)
+			expectStatus(t, res, tc.status, tc.code)
+			if res.Success {
+				updated := new(short.ShortURL)
+				res.decode(t, updated)
+				if updated.Secret != "" {
+					t.Errorf("expected no secret in the response, got %q", updated.Secret)
+				}
+			}
+			res = request(t, ws, http.MethodGet, "/"+sh.ID.String()+"?q=1", "")
+			if location := res.Header.Get("Location"); location != tc.location {
+				t.Errorf("expected redirect to %q, got %d %q", tc.location, res.Status, location)
+			}
+		})
+	}
+
+	// the password can be set and removed
+	res := request(t, ws, http.MethodPatch, target, `{"password": "open sesame"}`)
+	expectStatus(t, res, 200, shortreq.ResponseOkUpdated.InternalCode)
+	res = request(t, ws, http.MethodGet, "/"+sh.ID.String(), "")
+	expectStatus(t, res, 401, shortreq.ResponseErrPasswordRequired.InternalCode)
+	res = request(t, ws, http.MethodPatch, target, `{"password": ""}`)
+	expectStatus(t, res, 200, shortreq.ResponseOkUpdated.InternalCode)
+	res = request(t, ws, http.MethodGet, "/"+sh.ID.String(), "")
+	expectStatus(t, res, http.StatusMovedPermanently, 0)
+}
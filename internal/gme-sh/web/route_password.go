@@ -0,0 +1,112 @@
+package web
+
+import (
+	"html/template"
+	"log"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// MaxPasswordAttemptsPerIP -> number of passwords a client ip can submit per short url within PasswordAttemptWindow.
+	// Further attempts of the ip are rejected until the window is over, even if the password is correct.
+	MaxPasswordAttemptsPerIP = 10
+	// MaxPasswordAttempts -> number of passwords all clients together can submit per short url within
+	// PasswordAttemptWindow. The ip is taken from the spoofable proxy header, so this is the backstop
+	// against guessing with changing ips. It is much higher, so one client can't lock out everyone else.
+	MaxPasswordAttempts = 100
+	// PasswordAttemptWindow -> fixed window in which the attempts are counted
+	PasswordAttemptWindow = 5 * time.Minute
+)
+
+// passwordAttempt counts a submitted password of the client for {id} and returns false if there were too many.
+// The attempts are counted in the StatsDatabase, so the limits hold across all instances.
+// A correct password is counted as well, because the counters can't be read without counting.
+func (ws *WebServer) passwordAttempt(ctx *fiber.Ctx, id *short.ShortID) bool {
+	key := "password::" + id.String()
+	start := ws.now().Truncate(PasswordAttemptWindow)
+	// attempts of a blocked ip don't count towards the limit of the short url
+	return ws.countPasswordAttempt(key+"::ip::"+ctx.IP(), id, start, MaxPasswordAttemptsPerIP) &&
+		ws.countPasswordAttempt(key, id, start, MaxPasswordAttempts)
+}
+
+// countPasswordAttempt increments the attempt counter {key} of the window starting at {start}
+// and returns false if there were more than {max}
+func (ws *WebServer) countPasswordAttempt(key string, id *short.ShortID, start time.Time, max uint64) bool {
+	hits, err := ws.statsDB.IncrementRateLimit(key, start, PasswordAttemptWindow)
+	if err != nil {
+		// don't reject all passwords if the stats backend is not available
+		log.Println("⚠️ Error counting password attempts of", id, ":", err)
+		return true
+	}
+	return hits <= max
+}
+
+type passwordPayload struct {
+	Password string `json:"password" form:"password"`
+}
+
+// POST /:id, POST /:id/*
+// Used to submit the password of a protected short url
+func (ws *WebServer) fiberRouteRedirectPassword(ctx *fiber.Ctx) (err error) {
+	var sh *short.ShortURL
+	if sh, err = ws.findRedirectShortURL(ctx); sh == nil {
+		return
+	}
+	if !sh.IsProtected() {
+		return ws.redirect(ctx, sh, fiber.StatusSeeOther)
+	}
+	req := new(passwordPayload)
+	if err = ctx.BodyParser(req); err != nil {
+		return shortreq.ResponseErrPasswordRequired.Send(ctx)
+	}
+	if !ws.passwordAttempt(ctx, &sh.ID) {
+		return ws.sendPasswordChallenge(ctx, sh, shortreq.ResponseErrTooManyPasswordAttempts)
+	}
+	if !sh.CheckPassword(req.Password) {
+		return ws.sendPasswordChallenge(ctx, sh, shortreq.ResponseErrPasswordMismatch)
+	}
+	// 303 -> the browser follows the redirect with GET
+	return ws.redirect(ctx, sh, fiber.StatusSeeOther)
+}
+
+var passwordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>Password required</title>
+</head>
+<body>
+	<form method="post" action="{{.Action}}">
+		<p>The link <b>{{.ID}}</b> is protected by a password.</p>
+		{{if .Error}}<p style="color: red">{{.Error}}</p>{{end}}
+		<input type="password" name="password" autofocus required>
+		<button type="submit">Continue</button>
+	</form>
+</body>
+</html>
+`))
+
+// sendPasswordChallenge asks for the password of {sh} with the status of {res}.
+// Browsers get a form, other clients (Accept: application/json) the {res} as JSON.
+func (ws *WebServer) sendPasswordChallenge(ctx *fiber.Ctx, sh *short.ShortURL, res *shortreq.Response) error {
+	if acceptsJSON(ctx) {
+		return res.Send(ctx)
+	}
+	var msg string
+	if res != shortreq.ResponseErrPasswordRequired {
+		msg = res.Message
+	}
+	ctx.Status(res.StatusCode).Type("html", "utf-8")
+	// the form is posted to the same url, so the path suffix and query are kept
+	return passwordTemplate.Execute(ctx, struct {
+		ID     short.ShortID
+		Action string
+		Error  string
+	}{sh.ID, ctx.OriginalURL(), msg})
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPassword(t *testing.T) {
+	ws := newTestServer(t, nil)
+	sh := create(t, ws, `{"full_url": "https://example.com/protected", "password": "open sesame"}`)
+	target := "/" + sh.ID.String()
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		status   int
+		code     int
+		location string
+	}{
+		{"redirect requires the password", http.MethodGet, "", 401,
+			shortreq.ResponseErrPasswordRequired.InternalCode, ""},
+		{"wrong password", http.MethodPost, `{"password": "wrong"}`, 401,
+			shortreq.ResponseErrPasswordMismatch.InternalCode, ""},
+		{"correct password", http.MethodPost, `{"password": "open sesame"}`, http.StatusSeeOther, 0,
+			"https://example.com/protected"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res := request(t, ws, tc.method, target, tc.body)
+			expectStatus(t, res, tc.status, tc.code)
+			if location := res.Header.Get("Location"); location != tc.location {
+				t.Errorf("expected location %q, got %q", tc.location, location)
+			}
+		})
+	}
+
+	// browsers get the form
+	res := request(t, ws, http.MethodGet, target, "", "Accept", "text/html")
+	if res.Status != 401 || !strings.Contains(res.Body, "<form") {
+		t.Errorf("expected the password form, got %d: %s", res.Status, res.Body)
+	}
+}
+
+// TestPasswordForwardedPath -> passwords can be submitted to forwarded paths which look like other routes
+func TestPasswordForwardedPath(t *testing.T) {
+	ws := newTestServer(t, nil)
+	sh := create(t, ws, `{"full_url": "https://example.com/docs", "password": "open sesame", "forward_path": true}`)
+
+	res := request(t, ws, http.MethodPost, "/"+sh.ID.String()+"/keys/rotate", `{"password": "open sesame"}`)
+	expectStatus(t, res, http.StatusSeeOther, 0)
+	if location := res.Header.Get("Location"); location != "https://example.com/docs/keys/rotate" {
+		t.Errorf("expected redirect to the forwarded path, got %q", location)
+	}
+	res = request(t, ws, http.MethodPost, "/"+sh.ID.String()+"/keys/rotate", `{"password": "wrong"}`)
+	expectStatus(t, res, 401, shortreq.ResponseErrPasswordMismatch.InternalCode)
+}
+
+// protect saves a short url to {fullURL} which is protected by {password}
+// (hashed with the minimum cost, so the attempts of the test don't take seconds)
+func protect(t *testing.T, ws *WebServer, id, fullURL, password string) *short.ShortURL {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := &short.ShortURL{
+		ID:           short.ShortID(id),
+		FullURL:      fullURL,
+		CreationDate: time.Now(),
+		PasswordHash: string(hash),
+	}
+	if err = ws.persistentDB.SaveShortenedURL(sh); err != nil {
+		t.Fatal(err)
+	}
+	return sh
+}
+
+func TestPasswordAttempts(t *testing.T) {
+	ws := newTestServer(t, nil)
+	// all attempts must be counted in the same window
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	ws.now = func() time.Time { return start }
+	sh := protect(t, ws, "locked", "https://example.com/a", "open sesame")
+	other := protect(t, ws, "other", "https://example.com/b", "open sesame")
+
+	// one ip can't lock out the other clients
+	for i := 0; i < MaxPasswordAttemptsPerIP; i++ {
+		res := request(t, ws, http.MethodPost, "/"+sh.ID.String(), `{"password": "wrong"}`,
+			"X-Forwarded-For", "10.0.0.1")
+		expectStatus(t, res, 401, shortreq.ResponseErrPasswordMismatch.InternalCode)
+	}
+	res := request(t, ws, http.MethodPost, "/"+sh.ID.String(), `{"password": "open sesame"}`,
+		"X-Forwarded-For", "10.0.0.1")
+	expectStatus(t, res, 429, shortreq.ResponseErrTooManyPasswordAttempts.InternalCode)
+	res = request(t, ws, http.MethodPost, "/"+sh.ID.String(), `{"password": "open sesame"}`,
+		"X-Forwarded-For", "10.0.0.2")
+	expectStatus(t, res, http.StatusSeeOther, 0)
+
+	// changing the (spoofable) ip doesn't bypass the limit of the short url
+	// (the blocked attempt of 10.0.0.1 is not counted)
+	for i := MaxPasswordAttemptsPerIP + 1; i < MaxPasswordAttempts; i++ {
+		res = request(t, ws, http.MethodPost, "/"+sh.ID.String(), `{"password": "wrong"}`,
+			"X-Forwarded-For", "10.0."+strconv.Itoa(1+i/200)+"."+strconv.Itoa(i%200))
+		expectStatus(t, res, 401, shortreq.ResponseErrPasswordMismatch.InternalCode)
+	}
+	res = request(t, ws, http.MethodPost, "/"+sh.ID.String(), `{"password": "open sesame"}`,
+		"X-Forwarded-For", "10.0.2.1")
+	expectStatus(t, res, 429, shortreq.ResponseErrTooManyPasswordAttempts.InternalCode)
+
+	// other short urls are not affected
+	res = request(t, ws, http.MethodPost, "/"+other.ID.String(), `{"password": "open sesame"}`)
+	expectStatus(t, res, http.StatusSeeOther, 0)
+
+	// the limits are reset in the next window
+	ws.now = func() time.Time { return start.Add(PasswordAttemptWindow) }
+	res = request(t, ws, http.MethodPost, "/"+sh.ID.String(), `{"password": "open sesame"}`,
+		"X-Forwarded-For", "10.0.0.1")
+	expectStatus(t, res, http.StatusSeeOther, 0)
+}
@@ -1,52 +1,220 @@
 package web
 
 import (
+	"crypto/rand"
 	"fmt"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
 	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/shortreq"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"log"
+	"net/url"
 	"strings"
 )
 
 func (ws *WebServer) fiberRouteRedirect(ctx *fiber.Ctx) (err error) {
+	var sh *short.ShortURL
+	if sh, err = ws.findRedirectShortURL(ctx); sh == nil {
+		return
+	}
+	// protected urls are only redirected after the password was submitted (POST /:id)
+	if sh.IsProtected() {
+		return ws.sendPasswordChallenge(ctx, sh, shortreq.ResponseErrPasswordRequired)
+	}
+	return ws.redirect(ctx, sh, ws.redirectStatus(sh))
+}
+
+// findRedirectShortURL returns the short url of the request if it can be redirected.
+// Otherwise a response (error or redirect to the fallback url) is sent and sh is nil.
+func (ws *WebServer) findRedirectShortURL(ctx *fiber.Ctx) (sh *short.ShortURL, err error) {
 	id := short.ShortID(ctx.Params("id"))
 	if id.IsEmpty() {
 		// TODO: redirect to 404?!
-		return shortreq.ResponseErrEmptyID.Send(ctx)
+		return nil, shortreq.ResponseErrEmptyID.Send(ctx)
 	}
 	// check if requested file
 	if strings.Contains(id.String(), ".") {
-		return shortreq.ResponseErrRequestedFile.Send(ctx)
+		return nil, shortreq.ResponseErrRequestedFile.Send(ctx)
 	}
 	// find short url
-	var sh *short.ShortURL
 	sh, err = ws.persistentDB.FindShortenedURL(&id)
 	if sh == nil || err != nil {
-		// TODO: redirect to 404?!
-		return shortreq.ResponseErrURLNotFound.SendWithMessage(ctx,
+		if ok, err := ws.redirectFallback(ctx, nil); ok {
+			return nil, err
+		}
+		return nil, shortreq.ResponseErrURLNotFound.SendWithMessage(ctx,
 			fmt.Sprintf("short url [%s] not found", id.String()))
 	}
+	// a path after the id (/:id/*) is only valid if it is forwarded
+	if ctx.Params("*") != "" && !sh.ForwardPath {
+		return nil, shortreq.ResponseErrURLNotFound.SendWithMessage(ctx,
+			fmt.Sprintf("short url [%s] does not forward paths", id.String()))
+	}
 	// check if expired
 	if sh.IsExpired() {
 		// delete
-		err = ws.persistentDB.DeleteShortenedURL(&id)
-		if err != nil {
+		if err = ws.persistentDB.DeleteShortenedURL(&id); err != nil {
+			return nil, err
+		}
+		if ok, err := ws.redirectFallback(ctx, sh); ok {
+			return nil, err
+		}
+		return nil, shortreq.ResponseErrExpired.Send(ctx)
+	}
+	// check if activated
+	if sh.IsPending() {
+		if ok, err := ws.redirectFallback(ctx, sh); ok {
+			return nil, err
+		}
+		return nil, shortreq.ResponseErrNotActive.SendWithData(ctx, fiber.Map{
+			"activation_date": sh.ActivationDate,
+		})
+	}
+	return
+}
+
+// redirect counts the click and redirects to the long url of {sh}
+func (ws *WebServer) redirect(ctx *fiber.Ctx, sh *short.ShortURL, status int) (err error) {
+	// click limit, the url is deleted by the ExpirationCheck
+	if sh.IsClickLimited() && !ws.config.DryRedirect {
+		var ok bool
+		if ok, err = ws.persistentDB.ConsumeClick(&sh.ID, sh.MaxClicks); err != nil {
 			return
 		}
-		return shortreq.ResponseErrExpired.Send(ctx)
+		if !ok {
+			if redirected, err := ws.redirectFallback(ctx, sh); redirected {
+				return err
+			}
+			return shortreq.ResponseErrExpired.Send(ctx)
+		}
 	}
+	// targeting / a/b split
+	longURL, variant := ws.longURL(ctx, sh)
 	// add stats
 	if !sh.IsTemporary() {
+		id := sh.ID
+		click := ws.newClick(ctx)
+		click.Variant = variant
 		go func() {
-			_ = ws.statsDB.AddStats(&id)
+			_ = ws.statsDB.AddStats(&id, click)
 		}()
 	}
+	target := ws.redirectURL(ctx, sh, longURL)
 	// dry redirect (debug)
 	if ws.config.DryRedirect {
 		return shortreq.ResponseOkRedirectDry.SendWithMessage(ctx,
-			fmt.Sprintf("would redirect to [%s]", sh.GetRedirectURL()))
+			fmt.Sprintf("would redirect to [%s]", target))
 	}
 
 	// redirect
-	return ctx.Redirect(sh.GetRedirectURL(), 302)
+	return ctx.Redirect(target, status)
+}
+
+// longURL returns the url of the first target rule of {sh} matching the visitor,
+// otherwise the destination of the visitor (A/B split) or the FullURL.
+// {variant} is the label of the destination if the traffic is split, for the stats.
+func (ws *WebServer) longURL(ctx *fiber.Ctx, sh *short.ShortURL) (longURL, variant string) {
+	if rule := sh.MatchTarget(ctx.Get(fiber.HeaderUserAgent), ctx.Get(fiber.HeaderAcceptLanguage)); rule != nil {
+		return rule.URL, ""
+	}
+	if !sh.IsSplit() {
+		return sh.FullURL, ""
+	}
+	index := ws.pickDestination(ctx, sh)
+	return sh.DestinationURL(index), sh.DestinationVariant(index)
+}
+
+// redirectURL returns the redirect url of the {longURL} of {sh}
+// with the path suffix and query of the request (if forwarded)
+func (ws *WebServer) redirectURL(ctx *fiber.Ctx, sh *short.ShortURL, longURL string) string {
+	var query url.Values
+	if sh.ForwardQuery {
+		query, _ = url.ParseQuery(string(ctx.Request().URI().QueryString()))
+	}
+	// the wildcard param is not unescaped by fiber
+	suffix, err := url.PathUnescape(ctx.Params("*"))
+	if err != nil {
+		suffix = ctx.Params("*")
+	}
+	return sh.GetRedirectURLWith(longURL, suffix, query)
+}
+
+// redirectStatus returns the status code {sh} is redirected with
+func (ws *WebServer) redirectStatus(sh *short.ShortURL) int {
+	if short.IsValidRedirectStatus(sh.RedirectStatus) {
+		return sh.RedirectStatus
+	}
+	return ws.defaultRedirectStatus
+}
+
+// DefaultRedirectStatus returns the status code of redirects without a status code of their own
+func (ws *WebServer) DefaultRedirectStatus() int {
+	return ws.defaultRedirectStatus
+}
+
+// newDefaultRedirectStatus returns the configured default redirect status or 302
+func newDefaultRedirectStatus(cfg *config.WebServerConfig) int {
+	if cfg == nil || cfg.RedirectStatus == 0 {
+		return fiber.StatusFound
+	}
+	if !short.IsValidRedirectStatus(cfg.RedirectStatus) {
+		log.Println("⚠️ Invalid redirect status", cfg.RedirectStatus, "configured, using 302")
+		return fiber.StatusFound
+	}
+	return cfg.RedirectStatus
+}
+
+// redirectFallback redirects to the fallback url of {sh} (nil for unknown short urls)
+// or the default fallback url if the short url can't be redirected.
+// API clients (Accept: application/json) are not redirected, they get the error response instead.
+func (ws *WebServer) redirectFallback(ctx *fiber.Ctx, sh *short.ShortURL) (redirected bool, err error) {
+	if acceptsJSON(ctx) {
+		return false, nil
+	}
+	fallback := ws.config.WebServer.FallbackURL
+	if sh != nil && sh.FallbackURL != "" {
+		fallback = sh.FallbackURL
+	}
+	if fallback == "" {
+		return false, nil
+	}
+	return true, ctx.Redirect(short.RedirectURL(fallback), fiber.StatusFound)
+}
+
+// acceptsJSON returns true if the client prefers JSON over HTML
+func acceptsJSON(ctx *fiber.Ctx) bool {
+	return ctx.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON) == fiber.MIMEApplicationJSON
+}
+
+// newClick creates a short.Click from the request headers.
+// The values are copied, since fiber reuses them after the handler returned,
+// but the click is saved asynchronously.
+func (ws *WebServer) newClick(ctx *fiber.Ctx) *short.Click {
+	userAgent := utils.CopyString(ctx.Get(fiber.HeaderUserAgent))
+	click := short.NewClick(
+		utils.CopyString(ctx.Get(fiber.HeaderReferer)),
+		userAgent,
+		utils.CopyString(ctx.Get(fiber.HeaderAcceptLanguage)),
+	)
+	click.Visitor = short.VisitorID(ws.visitorKey, ctx.IP(), userAgent, click.Time)
+	// only the location is saved, not the IP address
+	if loc := ws.geoIP.Lookup(ctx.IP()); loc != nil {
+		click.Country, click.City, click.ASN = loc.Country, loc.City, loc.ASN
+	}
+	return click
+}
+
+// newVisitorKey returns the configured visitor secret or a random one
+func newVisitorKey(cfg *config.StatsConfig) []byte {
+	if cfg != nil && cfg.VisitorSecret != "" {
+		return []byte(cfg.VisitorSecret)
+	}
+	log.Println("⚠️ No visitor secret configured, using a random one. " +
+		"Unique visitors are only counted correctly with a single instance.")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalln("    └ ❌ FAILED:", err)
+	}
+	return key
 }
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"log"
+)
+
+// checkSecret returns the short url if the {secret} is correct, nil otherwise.
+// Plaintext secrets of old short urls are replaced by their hash on first use,
+// the returned copy holds the hash then ({sh} may be shared with the cache, so it is not changed).
+func (ws *WebServer) checkSecret(sh *short.ShortURL, secret string) *short.ShortURL {
+	if !sh.CheckSecret(secret) {
+		return nil
+	}
+	if short.IsHashedSecret(sh.Secret) {
+		return sh
+	}
+	hash, err := short.HashSecret(secret)
+	if err != nil {
+		log.Println("⚠️ Error hashing secret of", sh.ID, ":", err)
+		return sh
+	}
+	migrated := *sh
+	migrated.Secret = hash
+	if err = ws.persistentDB.UpdateShortenedURL(&migrated); err != nil {
+		log.Println("⚠️ Error saving hashed secret of", sh.ID, ":", err)
+		return sh
+	}
+	return &migrated
+}
+
+// checkPoolSecret returns true if the {secret} of the {pool} is correct.
+// Plaintext secrets of old pools are replaced by their hash on first use.
+func (ws *WebServer) checkPoolSecret(pool *short.Pool, secret string) bool {
+	if !pool.CheckSecret(secret) {
+		return false
+	}
+	if short.IsHashedSecret(pool.Secret) {
+		return true
+	}
+	hash, err := short.HashSecret(secret)
+	if err != nil {
+		log.Println("⚠️ Error hashing secret of pool", pool.ID, ":", err)
+		return true
+	}
+	plain := pool.Secret
+	pool.Secret = hash
+	if err = ws.persistentDB.SavePool(pool); err != nil {
+		log.Println("⚠️ Error saving hashed secret of pool", pool.ID, ":", err)
+		pool.Secret = plain
+	}
+	return true
+}
+
+// newSecret generates a secret and returns it with its hash, which is stored
+func newSecret() (secret, hash string, err error) {
+	if secret, err = short.GenerateSecret(); err != nil {
+		return
+	}
+	hash, err = short.HashSecret(secret)
+	return
+}
+
+// withSecret returns the response data of a new short url: the redacted url with the plain {secret},
+// which is only returned once
+func withSecret(sh *short.ShortURL, secret string) *short.ShortURL {
+	res := sh.Redacted()
+	res.Secret = secret
+	return res
+}
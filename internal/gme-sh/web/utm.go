@@ -0,0 +1,38 @@
+package web
+
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"strings"
+)
+
+// defaultUTM returns the UTM parameters of all rules matching the {host}, in the order of the config
+func (ws *WebServer) defaultUTM(host string) (res []*short.UTM) {
+	cfg := ws.config.UTM
+	if cfg == nil || host == "" {
+		return nil
+	}
+	for _, rule := range cfg.Rules {
+		for _, pattern := range rule.Hosts {
+			if !matchHost(strings.ToLower(pattern), host) {
+				continue
+			}
+			res = append(res, &short.UTM{
+				Source:   rule.Source,
+				Medium:   rule.Medium,
+				Campaign: rule.Campaign,
+				Term:     rule.Term,
+				Content:  rule.Content,
+			})
+			break
+		}
+	}
+	return
+}
+
+// matchHost returns true if the {host} is the {pattern} or a subdomain of a "*." {pattern}
+func matchHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
@@ -3,23 +3,29 @@ package web
 import (
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
 	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/geoip"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	recover2 "github.com/gofiber/fiber/v2/middleware/recover"
 	"log"
-	"net/http"
-	"strings"
 	"time"
 )
 
 // WebServer struct that holds databases and configs
 type WebServer struct {
 	persistentDB db.PersistentDatabase
+	accountDB    db.AccountDatabase
 	statsDB      db.StatsDatabase
+	geoIP        *geoip.Database
 	config       *config.Config
-	App          *fiber.App
+	visitorKey   []byte
+	idGenerators *idGenerators
+	// defaultRedirectStatus -> see config.WebServerConfig.RedirectStatus
+	defaultRedirectStatus int
+	// now -> clock of the fixed windows (e. g. of the password attempts), replaced by tests
+	now func() time.Time
+	App *fiber.App
 }
 
 // Start starts the WebServer and listens on the specified port
@@ -29,6 +35,18 @@ func (ws *WebServer) Start() {
 	// logger middleware
 	app.Use(logger.New())
 
+	ws.routes()
+
+	log.Println("🌎 Binding", ws.config.WebServer.Addr, "...")
+	if err := app.Listen(ws.config.WebServer.Addr); err != nil {
+		log.Fatalln("    └ ❌ FAILED:", err)
+	}
+}
+
+// routes registers the middlewares and routes of the WebServer
+func (ws *WebServer) routes() {
+	app := ws.App
+
 	// / -> redirect to github
 	app.Get("/", func(ctx *fiber.Ctx) error {
 		u := ws.config.WebServer.DefaultURL
@@ -38,23 +56,6 @@ func (ws *WebServer) Start() {
 		return ctx.Redirect(u)
 	})
 
-	// limiter middleware
-	app.Use(limiter.New(limiter.Config{
-		Max:        30,
-		Expiration: 1 * time.Minute,
-		Next: func(c *fiber.Ctx) bool {
-			// do not skip /create, /delete
-			if c.Method() == http.MethodDelete || c.Method() == http.MethodPost {
-				return false
-			}
-			// do not skip stats
-			if strings.HasPrefix(c.Path(), "/stats") {
-				return false
-			}
-			return true
-		},
-	}))
-
 	// panic middleware
 	app.Use(recover2.New(recover2.Config{
 		EnableStackTrace: true,
@@ -65,39 +66,83 @@ func (ws *WebServer) Start() {
 
 	// POST /create
 	// Used to create new short URLs
-	app.Post("/create", ws.fiberRouteCreate)
+	app.Post("/create", ws.rateLimit(rateLimitCreate), ws.fiberRouteCreate)
+
+	// POST /create/bulk
+	// Used to create multiple short URLs at once
+	app.Post("/create/bulk", ws.rateLimit(rateLimitCreate), ws.fiberRouteCreateBulk)
+
+	// POST /admin/reset/{id}, POST /admin/pool/reset/{id}
+	// Used by the admin to issue a new secret if it was lost or leaked
+	app.Post("/admin/reset/:id", ws.rateLimit(rateLimitAdmin), ws.fiberRouteAdminReset)
+	app.Post("/admin/pool/reset/:id", ws.rateLimit(rateLimitAdmin), ws.fiberRouteAdminPoolReset)
+
+	// POST /admin/accounts, GET /admin/accounts, DELETE /admin/accounts/{id}, POST /admin/accounts/{id}/rotate
+	// Used by the admin to manage the accounts and their API keys
+	app.Post("/admin/accounts", ws.rateLimit(rateLimitAdmin), ws.fiberRouteAdminAccountCreate)
+	app.Get("/admin/accounts", ws.rateLimit(rateLimitAdmin), ws.fiberRouteAdminAccounts)
+	app.Delete("/admin/accounts/:id", ws.rateLimit(rateLimitAdmin), ws.fiberRouteAdminAccountDelete)
+	app.Post("/admin/accounts/:id/rotate", ws.rateLimit(rateLimitAdmin), ws.fiberRouteAdminAccountRotate)
+
+	// GET /links?offset=&limit=
+	// Used to list the short URLs of an API key
+	app.Get("/links", ws.rateLimit(rateLimitStats), ws.fiberRouteLinks)
+
+	// POST /{id}/{secret}/rotate
+	// Used to replace the secret of short URLs
+	app.Post("/:id/:secret/rotate", ws.rateLimit(rateLimitDelete), ws.fiberRouteRotate)
 
 	// DELETE /{id}/{secret}
 	// Used to delete short URLs
-	app.Delete("/:id/:secret", ws.fiberRouteDelete)
+	app.Delete("/:id/:secret", ws.rateLimit(rateLimitDelete), ws.fiberRouteDelete)
+
+	// PATCH /{id}/{secret}
+	// Used to change the long url / expiration of short URLs
+	app.Patch("/:id/:secret", ws.rateLimit(rateLimitDelete), ws.fiberRouteUpdate)
 
 	// GET /stats/{id}
 	// Used to retrieve stats for a short url
-	app.Get("/stats/:id", ws.fiberRouteStats)
+	app.Get("/stats/:id", ws.rateLimit(rateLimitStats), ws.fiberRouteStats)
+
+	// GET /stats/{id}/timeseries?from=&to=&granularity=
+	// Used to retrieve the calls per hour / day for a short url
+	app.Get("/stats/:id/timeseries", ws.rateLimit(rateLimitStats), ws.fiberRouteStatsTimeSeries)
 
 	// POOL
-	app.Get("/pool/:id/:secret", ws.fiberRoutePoolGet)
-	app.Post("/pool/:id/:secret", ws.fiberRoutePoolUpdate)
+	app.Get("/pool/:id/:secret", ws.rateLimit(rateLimitPool), ws.fiberRoutePoolGet)
+	app.Post("/pool/:id/:secret", ws.rateLimit(rateLimitPool), ws.fiberRoutePoolUpdate)
+	app.Post("/pool/:id/:secret/rotate", ws.rateLimit(rateLimitPool), ws.fiberRoutePoolRotate)
 
 	// GET /{id}
 	// Used for redirection to long url
-	app.Get("/:id", ws.fiberRouteRedirect)
+	app.Get("/:id", ws.rateLimit(rateLimitRedirect), ws.fiberRouteRedirect)
+	// GET /{id}/{path...}
+	// Used for redirection with path forwarding
+	app.Get("/:id/*", ws.rateLimit(rateLimitRedirect), ws.fiberRouteRedirect)
 
-	log.Println("🌎 Binding", ws.config.WebServer.Addr, "...")
-	if err := app.Listen(ws.config.WebServer.Addr); err != nil {
-		log.Fatalln("    └ ❌ FAILED:", err)
-	}
+	// POST /{id}
+	// Used to submit the password of protected short urls
+	app.Post("/:id", ws.rateLimit(rateLimitRedirect), ws.fiberRouteRedirectPassword)
+	app.Post("/:id/*", ws.rateLimit(rateLimitRedirect), ws.fiberRouteRedirectPassword)
 }
 
 // NewWebServer returns a new WebServer object (reference)
-func NewWebServer(persistentDB db.PersistentDatabase, statsDB db.StatsDatabase, cfg *config.Config) *WebServer {
+func NewWebServer(persistentDB db.PersistentDatabase, statsDB db.StatsDatabase, geoIP *geoip.Database,
+	cfg *config.Config) *WebServer {
 	app := fiber.New(fiber.Config{
 		ProxyHeader: "X-Forwarded-For",
 	})
 	return &WebServer{
 		persistentDB: persistentDB,
+		accountDB:    db.MustAccounts(persistentDB),
 		statsDB:      statsDB,
+		geoIP:        geoIP,
 		config:       cfg,
+		visitorKey:   newVisitorKey(cfg.Stats),
+		idGenerators: newIDGenerators(persistentDB, cfg.WebServer),
 		App:          app,
+
+		defaultRedirectStatus: newDefaultRedirectStatus(cfg.WebServer),
+		now:                   time.Now,
 	}
 }
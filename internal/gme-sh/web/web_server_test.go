@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/config"
+	"github.com/gme-sh/gme.sh-api/internal/gme-sh/db"
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// testAPIKey -> API key of the testAccount
+const testAPIKey = "test-key"
+
+// testAccount -> account of newTestServer with the API key testAPIKey
+var testAccount = short.AccountID("test-account")
+
+// newTestServer returns a WebServer with all routes, backed by the in-memory databases.
+// The rate limit is disabled, unless {configure} sets another one.
+func newTestServer(t *testing.T, configure func(cfg *config.Config)) *WebServer {
+	t.Helper()
+	persistentDB, err := db.NewMemoryDatabase(db.NewLocalCache())
+	if err != nil {
+		t.Fatal("creating persistent database:", err)
+	}
+	statsDB, err := db.NewMemoryStats(&config.StatsConfig{})
+	if err != nil {
+		t.Fatal("creating stats database:", err)
+	}
+	if err = db.MustAccounts(persistentDB).SaveAccount(&short.Account{
+		ID:      testAccount,
+		KeyHash: short.HashAPIKey(testAPIKey),
+	}); err != nil {
+		t.Fatal("saving account:", err)
+	}
+	cfg := &config.Config{
+		WebServer: &config.WebServerConfig{},
+		Stats:     &config.StatsConfig{},
+		RateLimit: &config.RateLimitConfig{Disabled: true},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	ws := NewWebServer(persistentDB, statsDB, nil, cfg)
+	ws.routes()
+	return ws
+}
+
+// testResponse -> response of a test request, {Data} is decoded with decode
+type testResponse struct {
+	Status  int
+	Header  http.Header
+	Body    string
+	Success bool            `json:"success"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// decode decodes the data of the response into {v}
+func (r *testResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatalf("decoding data %s: %v", r.Data, err)
+	}
+}
+
+// request sends a request to the {ws} and returns the response.
+// The {body} is sent as JSON, {headers} are pairs of names and values.
+func request(t *testing.T, ws *WebServer, method, target, body string, headers ...string) *testResponse {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := ws.App.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response of %s %s: %v", method, target, err)
+	}
+	res := &testResponse{Status: resp.StatusCode, Header: resp.Header, Body: string(data)}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err = json.Unmarshal(data, res); err != nil {
+			t.Fatalf("decoding response of %s %s: %v", method, target, err)
+		}
+	}
+	return res
+}
+
+// create creates a short url with the JSON {payload} and returns it with its secret
+func create(t *testing.T, ws *WebServer, payload string, headers ...string) *short.ShortURL {
+	t.Helper()
+	res := request(t, ws, http.MethodPost, "/create", payload, headers...)
+	if !res.Success {
+		t.Fatalf("creating %s: %d %s", payload, res.Status, res.Body)
+	}
+	sh := new(short.ShortURL)
+	res.decode(t, sh)
+	return sh
+}
+
+// expectStatus fails the test if {res} doesn't have the {status} and internal {code} (if not 0)
+func expectStatus(t *testing.T, res *testResponse, status, code int) {
+	t.Helper()
+	if res.Status != status || code != 0 && res.Code != code {
+		t.Errorf("expected %d (%d), got %d: %s", status, code, res.Status, res.Body)
+	}
+}
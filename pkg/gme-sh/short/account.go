@@ -0,0 +1,72 @@
+package short
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AccountID -> ID of an Account
+type AccountID string
+
+func (id *AccountID) String() string {
+	return string(*id)
+}
+
+func (id *AccountID) Bytes() []byte {
+	return []byte(*id)
+}
+
+// Account -> owner of short urls and pools, authenticated with an API key (Authorization: Bearer {key})
+type Account struct {
+	ID      AccountID `json:"id" bson:"_id"`
+	Name    string    `json:"name" bson:"name"`
+	Created time.Time `json:"created" bson:"created"`
+	// KeyHash -> hash of the API key (see HashAPIKey), the key itself is not stored
+	KeyHash string `json:"key_hash,omitempty" bson:"key_hash"`
+	// DailyQuota -> max. short urls the account can create per day (UTC), 0 means unlimited
+	DailyQuota uint64 `json:"daily_quota" bson:"daily_quota"`
+}
+
+// APIKeyPrefix -> prefix of all API keys, so they can be recognized (e. g. by secret scanners)
+const APIKeyPrefix = "gme_"
+
+// GenerateAPIKey returns a new crypto random API key
+func GenerateAPIKey() (string, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + secret, nil
+}
+
+// HashAPIKey returns the hash of the API {key}.
+// Unlike secrets, the hash is not salted, so the account of a key can be looked up by the hash.
+// API keys are long random strings, so they can't be guessed from the hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Redacted returns a copy of the Account without the key hash, e. g. for responses
+func (a *Account) Redacted() *Account {
+	res := *a
+	res.KeyHash = ""
+	return &res
+}
+
+// HasQuota returns true if the number of short urls the account can create per day is limited
+func (a *Account) HasQuota() bool {
+	return a.DailyQuota > 0
+}
+
+// QuotaCounter returns the name of the counter of short urls created by the account on the day of {t}
+func (a *Account) QuotaCounter(t time.Time) string {
+	return "quota::" + a.ID.String() + "::" + t.UTC().Format("2006-01-02")
+}
+
+// QuotaReset returns the end of the day of {t} (midnight UTC), when the QuotaCounter of the day is no longer used
+func (a *Account) QuotaReset(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
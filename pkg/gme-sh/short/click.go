@@ -0,0 +1,136 @@
+package short
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Breakdown dimensions of a Click
+const (
+	BreakdownReferrer = "referrer"
+	BreakdownBrowser  = "browser"
+	BreakdownOS       = "os"
+	BreakdownDevice   = "device"
+	BreakdownLanguage = "language"
+	BreakdownCountry  = "country"
+	BreakdownCity     = "city"
+	BreakdownASN      = "asn"
+	BreakdownVariant  = "variant"
+)
+
+// Breakdowns contains all dimensions a Click is broken down by
+var Breakdowns = []string{
+	BreakdownReferrer,
+	BreakdownBrowser,
+	BreakdownOS,
+	BreakdownDevice,
+	BreakdownLanguage,
+	BreakdownCountry,
+	BreakdownCity,
+	BreakdownASN,
+	BreakdownVariant,
+}
+
+// ReferrerDirect is used as referrer if the visitor did not come from another site
+const ReferrerDirect = "direct"
+
+// Click -> a single redirect of a ShortURL.
+// It must not contain personal data like the IP address of the visitor.
+type Click struct {
+	Time     time.Time
+	Referrer string
+	Browser  string
+	OS       string
+	Device   string
+	Language string
+	// Country, City and ASN -> location of the visitor, empty if GeoIP is disabled
+	Country string
+	City    string
+	ASN     string
+	// Variant -> index of the destination of an A/B split (see DestinationVariant), empty if the url has no destinations.
+	// The url itself is not recorded, because the stats are public.
+	Variant string
+	// Visitor -> anonymous id of the visitor, used to count unique visitors.
+	// It is a salted hash which changes every day (see VisitorID), empty if unknown.
+	Visitor string
+}
+
+// NewClick creates a Click from the values of the request headers
+// Referer, User-Agent and Accept-Language
+func NewClick(referrer, userAgent, acceptLanguage string) *Click {
+	ua := ParseUserAgent(userAgent)
+	return &Click{
+		Time:     time.Now(),
+		Referrer: ReferrerHost(referrer),
+		Browser:  ua.Browser,
+		OS:       ua.OS,
+		Device:   ua.Device,
+		Language: PrimaryLanguage(acceptLanguage),
+	}
+}
+
+// Dimensions returns the value of the click for every dimension in Breakdowns.
+// Empty values (e. g. the location if GeoIP is disabled) are not included.
+func (c *Click) Dimensions() map[string]string {
+	res := make(map[string]string)
+	for dim, value := range map[string]string{
+		BreakdownReferrer: c.Referrer,
+		BreakdownBrowser:  c.Browser,
+		BreakdownOS:       c.OS,
+		BreakdownDevice:   c.Device,
+		BreakdownLanguage: c.Language,
+		BreakdownCountry:  c.Country,
+		BreakdownCity:     c.City,
+		BreakdownASN:      c.ASN,
+		BreakdownVariant:  c.Variant,
+	} {
+		if value != "" {
+			res[dim] = value
+		}
+	}
+	return res
+}
+
+// ReferrerHost returns the lower case host of the referrer (without "www.")
+// or ReferrerDirect if there is no referrer
+func ReferrerHost(referrer string) string {
+	if referrer == "" {
+		return ReferrerDirect
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return Unknown
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// PrimaryLanguage returns the primary language subtag of the first language
+// of an Accept-Language header (e. g. "de-DE,de;q=0.9,en;q=0.8" -> "de")
+func PrimaryLanguage(acceptLanguage string) string {
+	lang := strings.Split(acceptLanguage, ",")[0]
+	lang = strings.Split(lang, ";")[0]
+	lang = strings.Split(lang, "-")[0]
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || lang == "*" || len(lang) > 8 {
+		return Unknown
+	}
+	return lang
+}
+
+// VisitorID returns an anonymous id for the visitor with the {ip} and {userAgent} at {t}.
+// The id is a HMAC with the {secret} and the current day (UTC), so it changes every day
+// and can neither be reversed nor linked to the id of another day.
+func VisitorID(secret []byte, ip, userAgent string, t time.Time) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(GranularityDay.Bucket(t), 10)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,66 @@
+package short
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// NormalizeURL returns {fullURL} with a lower case scheme and host, without the default port,
+// with at least "/" as path and with sorted query parameters.
+// Urls which only differ in these points lead to the same page.
+func NormalizeURL(fullURL string) string {
+	u, schemeless, err := parseFullURL(fullURL)
+	if err != nil {
+		return fullURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port == "80" && u.Scheme != "https" || port == "443" && u.Scheme != "http" {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = u.Query().Encode()
+	res := u.String()
+	if schemeless {
+		res = strings.TrimPrefix(res, "//")
+	}
+	return res
+}
+
+// IsDeduplicable returns true if the url can be returned for another request with the same long url:
+// it is not protected, click limited, temporary, pending, split or targeted
+func (u *ShortURL) IsDeduplicable() bool {
+	return !u.IsProtected() && !u.IsClickLimited() && !u.IsTemporary() && u.ActivationDate == nil &&
+		!u.IsSplit() && len(u.Targets) == 0
+}
+
+// DedupHash returns the key of the url in the reverse index of the databases:
+// a hash of the normalized FullURL and the options which change the redirect.
+// Urls of different owners have different hashes, so nobody gets a short url of another account.
+// It is empty if the url is not deduplicable.
+func (u *ShortURL) DedupHash() string {
+	if !u.IsDeduplicable() {
+		return ""
+	}
+	h := sha256.New()
+	for _, part := range []string{
+		NormalizeURL(u.FullURL),
+		strconv.Itoa(u.RedirectStatus),
+		strconv.FormatBool(u.ForwardQuery),
+		strconv.FormatBool(u.ForwardPath),
+		u.FallbackURL,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	// the hash of anonymous urls stays the same as before accounts were added
+	if u.Owner != "" {
+		h.Write([]byte(u.Owner))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,90 @@
+package short_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		fullURL  string
+		expected string
+	}{
+		{"https://Example.COM", "https://example.com/"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"http://example.com:443/a", "http://example.com:443/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://example.com/?b=2&a=1", "https://example.com/?a=1&b=2"},
+		{"https://example.com/Path", "https://example.com/Path"},
+		{"Example.com/a?b=2&a=1", "example.com/a?a=1&b=2"},
+	}
+	for _, tc := range tests {
+		if actual := short.NormalizeURL(tc.fullURL); actual != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.fullURL, tc.expected, actual)
+		}
+	}
+}
+
+func TestDedupHash(t *testing.T) {
+	base := short.ShortURL{FullURL: "https://example.com/?a=1&b=2"}
+	hash := base.DedupHash()
+	if hash == "" {
+		t.Fatal("expected a hash for a plain url")
+	}
+
+	expiration := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		modify func(u *short.ShortURL)
+		same   bool
+	}{
+		{"normalized url", func(u *short.ShortURL) { u.FullURL = "https://EXAMPLE.com:443?b=2&a=1" }, true},
+		{"id and secret", func(u *short.ShortURL) { u.ID, u.Secret = "other", "secret" }, true},
+		{"other url", func(u *short.ShortURL) { u.FullURL = "https://example.com/other" }, false},
+		{"redirect status", func(u *short.ShortURL) { u.RedirectStatus = 301 }, false},
+		{"forward query", func(u *short.ShortURL) { u.ForwardQuery = true }, false},
+		{"forward path", func(u *short.ShortURL) { u.ForwardPath = true }, false},
+		{"fallback", func(u *short.ShortURL) { u.FallbackURL = "https://example.com/gone" }, false},
+		{"owner", func(u *short.ShortURL) { u.Owner = "account" }, false},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u := base
+			tc.modify(&u)
+			if actual := u.DedupHash(); (actual == hash) != tc.same {
+				t.Errorf("expected same hash: %v, got %q and %q", tc.same, hash, actual)
+			}
+		})
+	}
+
+	// urls which can't be shared have no hash
+	excluded := []struct {
+		name   string
+		modify func(u *short.ShortURL)
+	}{
+		{"protected", func(u *short.ShortURL) { u.PasswordHash = "$2a$10$hash" }},
+		{"click limited", func(u *short.ShortURL) { u.MaxClicks = 1 }},
+		{"temporary", func(u *short.ShortURL) { u.ExpirationDate = &expiration }},
+		{"pending", func(u *short.ShortURL) { u.ActivationDate = &expiration }},
+		{"split", func(u *short.ShortURL) {
+			u.Destinations = []*short.Destination{{URL: "https://example.com/b", Weight: 1}}
+		}},
+		{"targeted", func(u *short.ShortURL) {
+			u.Targets = []*short.TargetRule{{Device: short.DeviceMobile, URL: "https://example.com/m"}}
+		}},
+	}
+	for _, tc := range excluded {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u := base
+			tc.modify(&u)
+			if actual := u.DedupHash(); actual != "" {
+				t.Errorf("expected no hash, got %q", actual)
+			}
+		})
+	}
+}
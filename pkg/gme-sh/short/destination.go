@@ -0,0 +1,54 @@
+package short
+
+import "strconv"
+
+// MaxDestinations -> maximum number of weighted destinations of a ShortURL
+const MaxDestinations = 10
+
+// Destination -> a weighted long url of an A/B split (see ShortURL.Destinations)
+type Destination struct {
+	URL    string `json:"url" bson:"url"`
+	Weight uint   `json:"weight" bson:"weight"`
+}
+
+// IsSplit returns true if the traffic of the url is split between weighted destinations
+func (u *ShortURL) IsSplit() bool {
+	return len(u.Destinations) > 0
+}
+
+// PickDestination returns the index of a destination, chosen by the weights of the destinations.
+// {random} must be in [0, 1). Returns -1 (FullURL) if the url has no destinations.
+func (u *ShortURL) PickDestination(random float64) int {
+	var total uint
+	for _, d := range u.Destinations {
+		total += d.Weight
+	}
+	if total == 0 {
+		return -1
+	}
+	n := uint(random * float64(total))
+	for i, d := range u.Destinations {
+		if n < d.Weight {
+			return i
+		}
+		n -= d.Weight
+	}
+	return len(u.Destinations) - 1
+}
+
+// DestinationURL returns the url of the destination {index} or the FullURL if there is no such destination
+func (u *ShortURL) DestinationURL(index int) string {
+	if index < 0 || index >= len(u.Destinations) {
+		return u.FullURL
+	}
+	return u.Destinations[index].URL
+}
+
+// DestinationVariant returns the label of the destination {index} in the stats (its index),
+// or an empty string if there is no such destination
+func (u *ShortURL) DestinationVariant(index int) string {
+	if index < 0 || index >= len(u.Destinations) {
+		return ""
+	}
+	return strconv.Itoa(index)
+}
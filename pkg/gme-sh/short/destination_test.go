@@ -0,0 +1,72 @@
+package short_test
+
+import (
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+func TestPickDestination(t *testing.T) {
+	split := &short.ShortURL{
+		FullURL: "https://example.com",
+		Destinations: []*short.Destination{
+			{URL: "https://example.com/a", Weight: 1},
+			{URL: "https://example.com/b", Weight: 0},
+			{URL: "https://example.com/c", Weight: 3},
+		},
+	}
+	tests := []struct {
+		name     string
+		u        *short.ShortURL
+		random   float64
+		expected int
+	}{
+		{"no destinations", &short.ShortURL{FullURL: "https://example.com"}, 0.5, -1},
+		{"no weights", &short.ShortURL{Destinations: []*short.Destination{{URL: "https://example.com/a"}}}, 0.5, -1},
+		{"first", split, 0, 0},
+		{"end of first", split, 0.24, 0},
+		{"zero weight is skipped", split, 0.25, 2},
+		{"last", split, 0.99, 2},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.u.PickDestination(tc.random); actual != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+
+	// the weights are the share of the traffic
+	counts := make(map[int]int)
+	for i := 0; i < 400; i++ {
+		counts[split.PickDestination(float64(i)/400)]++
+	}
+	if counts[0] != 100 || counts[1] != 0 || counts[2] != 300 {
+		t.Errorf("expected 100 / 0 / 300 picks, got %v", counts)
+	}
+}
+
+func TestDestinationURLAndVariant(t *testing.T) {
+	u := &short.ShortURL{
+		FullURL:      "https://example.com",
+		Destinations: []*short.Destination{{URL: "https://example.com/a", Weight: 1}},
+	}
+	tests := []struct {
+		index   int
+		url     string
+		variant string
+	}{
+		{-1, "https://example.com", ""},
+		{0, "https://example.com/a", "0"},
+		{1, "https://example.com", ""},
+	}
+	for _, tc := range tests {
+		if actual := u.DestinationURL(tc.index); actual != tc.url {
+			t.Errorf("#%d: expected url %q, got %q", tc.index, tc.url, actual)
+		}
+		if actual := u.DestinationVariant(tc.index); actual != tc.variant {
+			t.Errorf("#%d: expected variant %q, got %q", tc.index, tc.variant, actual)
+		}
+	}
+}
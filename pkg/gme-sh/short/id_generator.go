@@ -1,49 +1,358 @@
 package short
 
 import (
-	"math/rand"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"strconv"
 	"strings"
-	"time"
+	"sync/atomic"
 )
 
-var availableCharacters = []byte("ABCDEFGHKLMNPRSTUVWXYZabcdefghkmnprstuvwxyz0123455689")
-
-// GenerateID generates a string of length {length} using the characters from availableCharacters.
-// This generated string is checked against the {accept} function and,
-// if this function returns true, it will return the generated string.
-// Otherwise, the process is tried a total of 5 more times until the {accept} function returns true,
-// or the attempts are exhausted. After that an empty string is returned.
+// GenerateID generates a random id (see RandomIDGenerator) of length {length}.
+// The id is checked against the {accept} function and, if this function returns true, it is returned.
+// Otherwise, the process is tried a total of 5 more times (counted from {try}) until the {accept} function
+// returns true, or the attempts are exhausted. After that an empty string is returned.
+//
+// Deprecated: use an IDGenerator
 func GenerateID(length int, accept func(id *ShortID) bool, try uint64) ShortID {
-	rand.Seed(time.Now().UnixNano())
+	// every id is accepted by the generator, so its ids keep their length
+	g := &RandomIDGenerator{growth: growth{level: int32(length), max: int32(length)}}
+	for ; try <= 5; try++ {
+		id, err := g.Generate(AlwaysTrue)
+		if err != nil {
+			return ""
+		}
+		if accept(&id) {
+			return id
+		}
+	}
+	return ""
+}
 
-	var res strings.Builder
-	for i := 0; i < length; i++ {
-		randByte := availableCharacters[rand.Intn(len(availableCharacters))]
-		res.WriteByte(randByte)
+// GenerateShortID generates a random id (see RandomIDGenerator) and checks it against the {accept} function.
+// An empty string is returned if no id was accepted.
+//
+// Deprecated: use an IDGenerator
+func GenerateShortID(accept func(id *ShortID) bool) ShortID {
+	id, _ := defaultIDGenerator.Generate(accept)
+	return id
+}
+
+// AlwaysTrue always returns true. Used for IDGenerator.Generate if every id can be used.
+func AlwaysTrue(_ *ShortID) bool {
+	return true
+}
+
+/*
+ * ==================================================================================================
+ *                                   I D   G E N E R A T O R S
+ * ==================================================================================================
+ */
+
+// Names of the IDGenerator strategies
+const (
+	IDGeneratorRandom     = "random"
+	IDGeneratorSequential = "sequential"
+	IDGeneratorHashids    = "hashids"
+	IDGeneratorWords      = "words"
+)
+
+const (
+	// DefaultIDLength -> initial length of random and hashids ids
+	DefaultIDLength = 5
+	// maxIDLength -> max. length of a ShortID
+	maxIDLength = 32
+	// idAttemptsPerLevel -> occupied ids after which the random and word generators produce longer ids
+	idAttemptsPerLevel = 3
+	// maxCounterAttempts -> counter values a sequential or hashids generator skips
+	// (because they are taken by custom aliases) before it gives up
+	maxCounterAttempts = 100
+)
+
+// ErrIDNotAvailable is returned by an IDGenerator if no generated id was accepted
+var ErrIDNotAvailable = errors.New("no generated id available")
+
+// IDGenerator -> strategy which generates the ids of new short urls
+type IDGenerator interface {
+	// Generate returns a new id which is accepted by {accept} (i. e. not occupied)
+	Generate(accept func(id *ShortID) bool) (ShortID, error)
+}
+
+// Counter atomically increments a counter and returns the new value (the first value is 1).
+// Used by the sequential and hashids generators.
+type Counter func() (uint64, error)
+
+// IsValidIDGenerator returns true if {name} is one of the IDGenerator strategies
+func IsValidIDGenerator(name string) bool {
+	switch name {
+	case IDGeneratorRandom, IDGeneratorSequential, IDGeneratorHashids, IDGeneratorWords:
+		return true
 	}
+	return false
+}
+
+// NewIDGenerator returns the IDGenerator strategy {name}.
+// {minLength} is the initial length of random and hashids ids,
+// {salt} shuffles hashids and {next} is the counter of the sequential and hashids generators.
+func NewIDGenerator(name string, minLength int, salt string, next Counter) (IDGenerator, error) {
+	switch name {
+	case IDGeneratorRandom:
+		return NewRandomIDGenerator(minLength), nil
+	case IDGeneratorSequential:
+		return NewSequentialIDGenerator(next), nil
+	case IDGeneratorHashids:
+		return NewHashidsIDGenerator(next, salt, minLength), nil
+	case IDGeneratorWords:
+		return NewWordIDGenerator(), nil
+	}
+	return nil, errors.New("unknown id generator: " + name)
+}
 
-	result := ShortID(res.String())
+var defaultIDGenerator = NewRandomIDGenerator(DefaultIDLength)
 
-	if !accept(&result) {
-		try++
-		if try > 5 {
-			return ""
+// base62Alphabet -> characters of random, sequential and hashids ids
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// encodeBase62 encodes {n} with the characters of {alphabet} and pads the result to {length}
+func encodeBase62(n uint64, alphabet string, length int) string {
+	var buf [maxIDLength]byte
+	i := len(buf)
+	for n > 0 || len(buf)-i < length {
+		i--
+		buf[i] = alphabet[n%62]
+		n /= 62
+	}
+	return string(buf[i:])
+}
+
+// randomIndex returns a crypto random number in [0, n)
+func randomIndex(n int) (int, error) {
+	// reject bytes above the largest multiple of n, so all indices are equally likely
+	max := 256 - 256%n
+	var b [1]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
 		}
+		if int(b[0]) < max {
+			return int(b[0]) % n, nil
+		}
+	}
+}
+
+// growth -> tries idAttemptsPerLevel ids per level and moves to the next level if all of them are occupied.
+// The level is kept, so a crowded generator doesn't try the short ids again.
+type growth struct {
+	level int32
+	max   int32
+}
 
-		return GenerateID(length, accept, try)
+func (g *growth) generate(accept func(id *ShortID) bool,
+	gen func(level int) (ShortID, error)) (ShortID, error) {
+	for level := atomic.LoadInt32(&g.level); level <= g.max; level++ {
+		for i := 0; i < idAttemptsPerLevel; i++ {
+			id, err := gen(int(level))
+			if err != nil {
+				return "", err
+			}
+			if accept(&id) {
+				return id, nil
+			}
+		}
+		atomic.CompareAndSwapInt32(&g.level, level, level+1)
 	}
+	return "", ErrIDNotAvailable
+}
+
+/// Random
 
-	return result
+// RandomIDGenerator -> crypto random base62 ids.
+// The ids start with the min. length and grow by one character whenever too many ids are occupied.
+type RandomIDGenerator struct {
+	growth growth
 }
 
-// GenerateShortID generates a 5 - 10 long string and checks it against the {accept} function.
-// More information: GenerateID()
-func GenerateShortID(accept func(id *ShortID) bool) ShortID {
-	length := rand.Intn(10-5) + 5
-	return GenerateID(length, accept, 0)
+// NewRandomIDGenerator returns a RandomIDGenerator starting with ids of length {minLength}
+func NewRandomIDGenerator(minLength int) *RandomIDGenerator {
+	if minLength <= 0 {
+		minLength = DefaultIDLength
+	} else if minLength > maxIDLength {
+		minLength = maxIDLength
+	}
+	return &RandomIDGenerator{growth: growth{level: int32(minLength), max: maxIDLength}}
 }
 
-// AlwaysTrue always returns true. Used for GenerateID and GenerateShortID.
-func AlwaysTrue(_ *ShortID) bool {
-	return true
+func (g *RandomIDGenerator) Generate(accept func(id *ShortID) bool) (ShortID, error) {
+	return g.growth.generate(accept, func(length int) (ShortID, error) {
+		buf := make([]byte, length)
+		for i := range buf {
+			idx, err := randomIndex(len(base62Alphabet))
+			if err != nil {
+				return "", err
+			}
+			buf[i] = base62Alphabet[idx]
+		}
+		return ShortID(buf), nil
+	})
+}
+
+/// Sequential
+
+// SequentialIDGenerator -> base62 encoded values of a counter (1, 2, ..., z, 10, ...).
+// The ids are collision-free across all instances as long as the counter is stored in the persistent backend.
+type SequentialIDGenerator struct {
+	next Counter
+}
+
+// NewSequentialIDGenerator returns a SequentialIDGenerator using the counter {next}
+func NewSequentialIDGenerator(next Counter) *SequentialIDGenerator {
+	return &SequentialIDGenerator{next: next}
+}
+
+func (g *SequentialIDGenerator) Generate(accept func(id *ShortID) bool) (ShortID, error) {
+	return generateFromCounter(g.next, accept, func(n uint64) ShortID {
+		return ShortID(encodeBase62(n, base62Alphabet, 1))
+	})
+}
+
+// generateFromCounter encodes values of {next} until an id is accepted (ids can be taken by custom aliases)
+func generateFromCounter(next Counter, accept func(id *ShortID) bool,
+	encode func(n uint64) ShortID) (ShortID, error) {
+	for i := 0; i < maxCounterAttempts; i++ {
+		n, err := next()
+		if err != nil {
+			return "", err
+		}
+		id := encode(n)
+		if accept(&id) {
+			return id, nil
+		}
+	}
+	return "", ErrIDNotAvailable
+}
+
+/// Hashids
+
+// maxHashidsLength -> 62^10 is the largest power of 62 which fits into an uint64,
+// longer ids are not obfuscated
+const maxHashidsLength = 10
+
+// HashidsIDGenerator -> obfuscated values of a counter.
+// Like hashids, the ids are short and collision-free but don't reveal the number of short urls.
+// Each value is mapped to an id of the smallest length >= the min. length by a salted bijection.
+type HashidsIDGenerator struct {
+	next       Counter
+	minLength  int
+	alphabet   string
+	multiplier uint64
+	offset     uint64
+}
+
+// NewHashidsIDGenerator returns a HashidsIDGenerator using the counter {next}.
+// All instances must use the same {salt}.
+func NewHashidsIDGenerator(next Counter, salt string, minLength int) *HashidsIDGenerator {
+	if minLength <= 0 {
+		minLength = DefaultIDLength
+	} else if minLength > maxHashidsLength {
+		minLength = maxHashidsLength
+	}
+	sum := sha256.Sum256([]byte(salt))
+	return &HashidsIDGenerator{
+		next:       next,
+		minLength:  minLength,
+		alphabet:   shuffleAlphabet(base62Alphabet, salt),
+		multiplier: binary.BigEndian.Uint64(sum[0:8]),
+		offset:     binary.BigEndian.Uint64(sum[8:16]),
+	}
+}
+
+// shuffleAlphabet returns a permutation of {alphabet} which only depends on the {salt}
+func shuffleAlphabet(alphabet, salt string) string {
+	res := []byte(alphabet)
+	for i := len(res) - 1; i > 0; i-- {
+		sum := sha256.Sum256([]byte(salt + ":" + strconv.Itoa(i)))
+		j := int(binary.BigEndian.Uint64(sum[:8]) % uint64(i+1))
+		res[i], res[j] = res[j], res[i]
+	}
+	return string(res)
+}
+
+func (g *HashidsIDGenerator) Generate(accept func(id *ShortID) bool) (ShortID, error) {
+	return generateFromCounter(g.next, accept, g.Encode)
+}
+
+// Encode returns the id of the counter value {n}
+func (g *HashidsIDGenerator) Encode(n uint64) ShortID {
+	length := g.minLength
+	mod := uint64(1)
+	for i := 0; i < length; i++ {
+		mod *= 62
+	}
+	for n >= mod {
+		if length == maxHashidsLength {
+			// out of the obfuscated range: longer than all obfuscated ids
+			return ShortID(encodeBase62(n, g.alphabet, maxHashidsLength+1))
+		}
+		length++
+		mod *= 62
+	}
+	// n -> (n * multiplier + offset) mod 62^length is a bijection if multiplier and 62^length are coprime
+	multiplier := g.multiplier % mod
+	if multiplier%2 == 0 {
+		multiplier++
+	}
+	for multiplier%31 == 0 {
+		multiplier = (multiplier + 2) % mod
+	}
+	hi, lo := bits.Mul64(n, multiplier)
+	_, m := bits.Div64(hi, lo, mod)
+	m = (m + g.offset%mod) % mod
+	return ShortID(encodeBase62(m, g.alphabet, length))
+}
+
+/// Words
+
+// WordIDGenerator -> pronounceable ids like "brave-otter".
+// Whenever too many ids are occupied, a number with one more digit is appended ("brave-otter-42").
+type WordIDGenerator struct {
+	growth growth
+}
+
+// maxWordDigits -> max. digits appended to word ids
+const maxWordDigits = 8
+
+// NewWordIDGenerator returns a WordIDGenerator
+func NewWordIDGenerator() *WordIDGenerator {
+	return &WordIDGenerator{growth: growth{level: 0, max: maxWordDigits}}
+}
+
+func (g *WordIDGenerator) Generate(accept func(id *ShortID) bool) (ShortID, error) {
+	return g.growth.generate(accept, func(digits int) (ShortID, error) {
+		adjective, err := randomIndex(len(idAdjectives))
+		if err != nil {
+			return "", err
+		}
+		noun, err := randomIndex(len(idNouns))
+		if err != nil {
+			return "", err
+		}
+		var res strings.Builder
+		res.WriteString(idAdjectives[adjective])
+		res.WriteByte('-')
+		res.WriteString(idNouns[noun])
+		if digits > 0 {
+			res.WriteByte('-')
+			for i := 0; i < digits; i++ {
+				d, err := randomIndex(10)
+				if err != nil {
+					return "", err
+				}
+				res.WriteByte(byte('0' + d))
+			}
+		}
+		return ShortID(res.String()), nil
+	})
 }
@@ -0,0 +1,88 @@
+package short_test
+
+import (
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+// counter returns a Counter starting with 1
+func counter() short.Counter {
+	var n uint64
+	return func() (uint64, error) {
+		n++
+		return n, nil
+	}
+}
+
+func TestHashidsEncode(t *testing.T) {
+	tests := []struct {
+		minLength int
+		values    uint64 // 62^minLength, all ids of the length
+	}{
+		{1, 62},
+		{2, 62 * 62},
+	}
+	for _, tc := range tests {
+		g := short.NewHashidsIDGenerator(counter(), "salt", tc.minLength)
+		// every value of the range has its own id of the min. length
+		seen := make(map[short.ShortID]uint64, tc.values)
+		for n := uint64(0); n < tc.values; n++ {
+			id := g.Encode(n)
+			if len(id) != tc.minLength || !id.IsValid() {
+				t.Fatalf("length %d: invalid id %q for %d", tc.minLength, id, n)
+			}
+			if other, ok := seen[id]; ok {
+				t.Fatalf("length %d: %d and %d have the same id %q", tc.minLength, other, n, id)
+			}
+			seen[id] = n
+		}
+		// the next value needs a longer id
+		if id := g.Encode(tc.values); len(id) != tc.minLength+1 {
+			t.Errorf("length %d: expected a longer id for %d, got %q", tc.minLength, tc.values, id)
+		}
+	}
+
+	// the ids depend on the salt, but not on the instance
+	a := short.NewHashidsIDGenerator(counter(), "a", 5)
+	b := short.NewHashidsIDGenerator(counter(), "b", 5)
+	if a.Encode(1) == b.Encode(1) {
+		t.Errorf("expected different ids for different salts, got %q", a.Encode(1))
+	}
+	if again := short.NewHashidsIDGenerator(counter(), "a", 5); a.Encode(1) != again.Encode(1) {
+		t.Errorf("expected the same id for the same salt, got %q and %q", a.Encode(1), again.Encode(1))
+	}
+	// values above the obfuscated range are still unique
+	if a.Encode(1<<63) == a.Encode(1<<63+1) {
+		t.Errorf("expected different ids for large values")
+	}
+}
+
+func TestSequentialIDGenerator(t *testing.T) {
+	g := short.NewSequentialIDGenerator(counter())
+	occupied := map[short.ShortID]bool{"2": true}
+	accept := func(id *short.ShortID) bool {
+		return !occupied[*id]
+	}
+	// occupied ids are skipped
+	for _, expected := range []short.ShortID{"1", "3", "4"} {
+		if id, err := g.Generate(accept); err != nil || id != expected {
+			t.Errorf("expected %q, got %q, %v", expected, id, err)
+		}
+	}
+}
+
+func TestGenerateID(t *testing.T) {
+	if id := short.GenerateID(8, short.AlwaysTrue, 0); len(id) != 8 || !id.IsValid() {
+		t.Errorf("expected a valid id of length 8, got %q", id)
+	}
+	// all attempts are rejected
+	attempts := 0
+	id := short.GenerateID(8, func(_ *short.ShortID) bool {
+		attempts++
+		return false
+	}, 0)
+	if id != "" || attempts != 6 {
+		t.Errorf("expected no id after 6 attempts, got %q after %d", id, attempts)
+	}
+}
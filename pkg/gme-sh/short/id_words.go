@@ -0,0 +1,25 @@
+package short
+
+// idAdjectives and idNouns -> short, pronounceable words of WordIDGenerator ids
+var (
+	idAdjectives = []string{
+		"agile", "amber", "bold", "brave", "breezy", "bright", "brisk", "calm",
+		"clever", "cosy", "crisp", "curly", "daring", "dizzy", "eager", "fancy",
+		"fluffy", "frosty", "funny", "gentle", "giant", "glad", "golden", "happy",
+		"hasty", "humble", "jolly", "keen", "kind", "lively", "lucky", "lunar",
+		"mellow", "merry", "mighty", "misty", "modest", "noble", "nimble", "olive",
+		"plucky", "polite", "proud", "quick", "quiet", "rapid", "rosy", "rusty",
+		"shiny", "silent", "silver", "sleepy", "smooth", "snowy", "solar", "sunny",
+		"swift", "tidy", "tiny", "vivid", "warm", "witty", "young", "zesty",
+	}
+	idNouns = []string{
+		"badger", "bear", "beaver", "bison", "camel", "canary", "cobra", "condor",
+		"coral", "cougar", "coyote", "crane", "dingo", "dolphin", "donkey", "eagle",
+		"falcon", "ferret", "finch", "gecko", "gopher", "heron", "hippo", "husky",
+		"ibis", "iguana", "jaguar", "koala", "lemur", "lizard", "llama", "lobster",
+		"magpie", "marmot", "moose", "narwhal", "newt", "ocelot", "otter", "owl",
+		"panda", "parrot", "pelican", "penguin", "puffin", "python", "rabbit", "raven",
+		"salmon", "seal", "sparrow", "squid", "tapir", "tiger", "toucan", "turtle",
+		"viper", "walrus", "wombat", "yak", "zebra", "mole", "robin", "tuna",
+	}
+)
@@ -0,0 +1,43 @@
+package short
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordLength -> bcrypt only uses the first 72 bytes of a password
+const MaxPasswordLength = 72
+
+// ErrPasswordTooLong -> the password is longer than MaxPasswordLength bytes
+var ErrPasswordTooLong = errors.New("password must not be longer than 72 bytes")
+
+// HashPassword returns the bcrypt hash of the {password}
+func HashPassword(password string) (string, error) {
+	if len(password) > MaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// IsProtected returns true if the ShortURL can only be followed with a password
+func (u *ShortURL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// CheckPassword returns true if the ShortURL is not protected or the {password} is correct
+func (u *ShortURL) CheckPassword(password string) bool {
+	if !u.IsProtected() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// Redacted returns a copy of the ShortURL without the password hash and the secret (hash), e. g. for responses
+func (u *ShortURL) Redacted() *ShortURL {
+	res := *u
+	res.PasswordHash = ""
+	res.Secret = ""
+	return &res
+}
@@ -15,7 +15,7 @@ func (id *PoolID) Bytes() []byte {
 type Pool struct {
 	ID      PoolID                  `bson:"id" json:"id"`
 	Created time.Time               `bson:"created" json:"created"`
-	Secret  string                  `bson:"secret" json:"secret"`
+	Secret  string                  `bson:"secret" json:"secret,omitempty"` // salted hash, see HashSecret
 	Entries map[string][]*PoolEntry `bson:"entries" json:"entries"`
 }
 
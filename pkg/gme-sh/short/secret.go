@@ -0,0 +1,87 @@
+package short
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	// secretBytes -> random bytes of a secret (32 characters base64)
+	secretBytes = 24
+	// secretSaltBytes -> random bytes of the salt of a secret hash
+	secretSaltBytes = 16
+	// secretHashPrefix -> marks hashed secrets. Plaintext secrets of old short urls and pools
+	// never contain "$", so they can be told apart (and are hashed on first use).
+	secretHashPrefix = "$sha256$"
+)
+
+// GenerateSecret returns a new crypto random secret, which can be used in urls
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashSecret returns the salted hash of the {secret} ($sha256${salt}${hash}).
+// Secrets are long random strings, so a fast hash is sufficient (unlike passwords).
+func HashSecret(secret string) (string, error) {
+	salt := make([]byte, secretSaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hashSecret(salt, secret), nil
+}
+
+func hashSecret(salt []byte, secret string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return secretHashPrefix + base64.RawURLEncoding.EncodeToString(salt) + "$" +
+		base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// IsHashedSecret returns true if the stored {secret} is a hash (see HashSecret), not a plaintext secret
+func IsHashedSecret(secret string) bool {
+	return strings.HasPrefix(secret, secretHashPrefix)
+}
+
+// CheckSecret compares the {secret} with the {stored} hash (or plaintext secret) in constant time
+func CheckSecret(stored, secret string) bool {
+	if stored == "" {
+		return false
+	}
+	if !IsHashedSecret(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(secret)) == 1
+	}
+	parts := strings.Split(strings.TrimPrefix(stored, secretHashPrefix), "$")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(salt, secret)), []byte(stored)) == 1
+}
+
+// CheckSecret returns true if the {secret} of the ShortURL is correct
+func (u *ShortURL) CheckSecret(secret string) bool {
+	return CheckSecret(u.Secret, secret)
+}
+
+// CheckSecret returns true if the {secret} of the Pool is correct
+func (p *Pool) CheckSecret(secret string) bool {
+	return CheckSecret(p.Secret, secret)
+}
+
+// Redacted returns a copy of the Pool without the secret (hash), e. g. for responses
+func (p *Pool) Redacted() *Pool {
+	res := *p
+	res.Secret = ""
+	return &res
+}
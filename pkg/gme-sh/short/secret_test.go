@@ -0,0 +1,50 @@
+package short_test
+
+import (
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+func TestCheckSecret(t *testing.T) {
+	secret, err := short.GenerateSecret()
+	if err != nil {
+		t.Fatalf("generating secret: %v", err)
+	}
+	hash, err := short.HashSecret(secret)
+	if err != nil {
+		t.Fatalf("hashing secret: %v", err)
+	}
+	if !short.IsHashedSecret(hash) || short.IsHashedSecret(secret) {
+		t.Errorf("expected only %q to be a hash", hash)
+	}
+	// the hashes are salted
+	if other, _ := short.HashSecret(secret); other == hash {
+		t.Errorf("expected different hashes for the same secret, got %q twice", hash)
+	}
+
+	tests := []struct {
+		name     string
+		stored   string
+		secret   string
+		expected bool
+	}{
+		{"hash", hash, secret, true},
+		{"wrong secret", hash, secret + "x", false},
+		{"empty secret", hash, "", false},
+		{"hash as secret", hash, hash, false},
+		{"plaintext", "plain-secret", "plain-secret", true},
+		{"wrong plaintext", "plain-secret", "plain", false},
+		{"nothing stored", "", "", false},
+		{"invalid hash", "$sha256$no-salt", "", false},
+		{"invalid salt", "$sha256$!$hash", "", false},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := short.CheckSecret(tc.stored, tc.secret); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"log"
+	"net/http"
+	"net/url"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -14,7 +17,30 @@ type ShortURL struct {
 	FullURL        string     `json:"full_url" bson:"full_url"`
 	CreationDate   time.Time  `json:"creation_date" bson:"creation_date"`
 	ExpirationDate *time.Time `json:"expiration_date" bson:"expiration_date"`
-	Secret         string     `json:"secret" bson:"secret"`
+	// Secret -> salted hash of the secret (see HashSecret), old short urls may still have a plaintext secret
+	Secret string `json:"secret,omitempty" bson:"secret"`
+	// ActivationDate -> the url is not redirected before, nil means active since creation
+	ActivationDate *time.Time `json:"activation_date,omitempty" bson:"activation_date"`
+	// FallbackURL -> visitors are redirected here while the url is not active (yet), expired or exhausted
+	FallbackURL string `json:"fallback_url,omitempty" bson:"fallback_url"`
+	// RedirectStatus -> status code of the redirect (301, 302, 307 or 308), 0 means the default of the instance
+	RedirectStatus int `json:"redirect_status,omitempty" bson:"redirect_status"`
+	// ForwardQuery -> the query parameters of the request are added to the FullURL
+	ForwardQuery bool `json:"forward_query,omitempty" bson:"forward_query"`
+	// ForwardPath -> the path after the ID (/:id/*) is appended to the path of the FullURL
+	ForwardPath bool `json:"forward_path,omitempty" bson:"forward_path"`
+	// Destinations -> optional weighted long urls the traffic is split between (A/B test),
+	// the FullURL is only used if there are no destinations
+	Destinations []*Destination `json:"destinations,omitempty" bson:"destinations"`
+	// Targets -> optional rules which redirect visitors by device, operating system or language,
+	// the first matching rule is used (before the Destinations)
+	Targets []*TargetRule `json:"targets,omitempty" bson:"targets"`
+	// MaxClicks -> the url can only be followed MaxClicks times, 0 means unlimited
+	MaxClicks uint64 `json:"max_clicks,omitempty" bson:"max_clicks"`
+	// PasswordHash -> bcrypt hash of the password (see HashPassword), empty if the url is not protected
+	PasswordHash string `json:"password_hash,omitempty" bson:"password_hash"`
+	// Owner -> account which created the url with its API key, empty for anonymous urls
+	Owner AccountID `json:"owner,omitempty" bson:"owner,omitempty"`
 }
 
 func (u *ShortURL) String() string {
@@ -35,11 +61,63 @@ func init() {
 }
 
 func (u *ShortURL) GetRedirectURL() (url string) {
-	url = u.FullURL
+	return RedirectURL(u.FullURL)
+}
+
+// GetRedirectURLWith returns the redirect url of {longURL} (the FullURL, a destination or a target)
+// with the path {suffix} (ForwardPath) and the {query} parameters of the request (ForwardQuery).
+// Parameters of the long url take precedence, request parameters with the same key are dropped.
+func (u *ShortURL) GetRedirectURLWith(longURL, suffix string, query url.Values) string {
+	target := RedirectURL(longURL)
+	forwardPath := u.ForwardPath && strings.Trim(suffix, "/") != ""
+	forwardQuery := u.ForwardQuery && len(query) > 0
+	if !forwardPath && !forwardQuery {
+		return target
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	if forwardPath {
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/" + strings.TrimPrefix(suffix, "/")
+		parsed.RawPath = ""
+	}
+	if forwardQuery {
+		own := parsed.Query()
+		extra := url.Values{}
+		for key, values := range query {
+			if _, ok := own[key]; !ok {
+				extra[key] = values
+			}
+		}
+		// the query of the FullURL is kept as it is, only the new parameters are encoded
+		if len(extra) > 0 {
+			if parsed.RawQuery != "" {
+				parsed.RawQuery += "&"
+			}
+			parsed.RawQuery += extra.Encode()
+		}
+	}
+	return parsed.String()
+}
+
+// RedirectURL returns the {url} as it can be used in a Location header
+// (urls without protocol are prefixed with //)
+func RedirectURL(url string) string {
 	if !protocolRegex.MatchString(url) {
 		url = "//" + url
 	}
-	return
+	return url
+}
+
+// IsValidRedirectStatus returns true if short urls and templates can be redirected with {status}:
+// 301 / 308 (permanent) or 302 / 307 (temporary), 307 and 308 preserve the request method
+func IsValidRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
 }
 
 ///////////////////////////////////////////////////////////////////////
@@ -51,14 +129,27 @@ func (u *ShortURL) BsonUpdate() bson.M {
 	}
 }
 
+// IsExpired returns true if the expiration date of the url has passed.
+// ActivationDate and ExpirationDate are the window in which the url is redirected,
+// so a url with an activation date expires at its ExpirationDate, even if it was never active.
 func (u *ShortURL) IsExpired() bool {
 	return u.IsTemporary() && time.Now().After(*u.ExpirationDate)
 }
 
+// IsPending returns true if the activation date of the url is not reached yet
+func (u *ShortURL) IsPending() bool {
+	return u.ActivationDate != nil && time.Now().Before(*u.ActivationDate)
+}
+
 func (u *ShortURL) IsTemporary() bool {
 	return u.ExpirationDate != nil
 }
 
+// IsClickLimited returns true if the url can only be followed MaxClicks times
+func (u *ShortURL) IsClickLimited() bool {
+	return u.MaxClicks > 0
+}
+
 func (u *ShortURL) IsLocked() bool {
 	return u.Secret == ""
 }
@@ -0,0 +1,40 @@
+package short_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+func TestGetRedirectURLWith(t *testing.T) {
+	tests := []struct {
+		name         string
+		forwardPath  bool
+		forwardQuery bool
+		longURL      string
+		suffix       string
+		query        url.Values
+		expected     string
+	}{
+		{"plain", false, false, "https://example.com/a", "b", url.Values{"q": {"1"}}, "https://example.com/a"},
+		{"no protocol", false, false, "example.com/a", "", nil, "//example.com/a"},
+		{"path", true, false, "https://example.com/a/", "/b/c", nil, "https://example.com/a/b/c"},
+		{"empty path", true, false, "https://example.com/a", "/", nil, "https://example.com/a"},
+		{"escaped path", true, false, "https://example.com/a", "b c", nil, "https://example.com/a/b%20c"},
+		{"query", false, true, "https://example.com/a", "", url.Values{"q": {"1 2"}}, "https://example.com/a?q=1+2"},
+		{"query of the long url wins", false, true, "https://example.com/a?q=own&x=%2F", "",
+			url.Values{"q": {"req"}, "r": {"1"}}, "https://example.com/a?q=own&x=%2F&r=1"},
+		{"path and query", true, true, "https://example.com", "b", url.Values{"q": {"1"}},
+			"https://example.com/b?q=1"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			sh := &short.ShortURL{ForwardPath: tc.forwardPath, ForwardQuery: tc.forwardQuery}
+			if actual := sh.GetRedirectURLWith(tc.longURL, tc.suffix, tc.query); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
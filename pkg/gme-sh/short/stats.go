@@ -1,5 +1,7 @@
 package short
 
+import "sort"
+
 // Stats -> struct that holds the stats of a ShortURL
 type Stats struct {
 	// Calls -> Global Calls
@@ -7,4 +9,39 @@ type Stats struct {
 
 	// Calls60 -> Calls in 60 minutes
 	Calls60 uint64
+
+	// Visitors -> approximate number of unique visitors.
+	// Visitor ids change every day, so a visitor returning on another day is counted again.
+	Visitors uint64
+
+	// VisitorsToday -> approximate number of unique visitors of the current day (UTC)
+	VisitorsToday uint64
+
+	// Breakdowns -> the most common values per dimension (see Breakdowns), ordered by calls
+	Breakdowns map[string][]*StatsEntry `json:",omitempty"`
+}
+
+// StatsEntry -> calls with a specific value of a breakdown dimension
+type StatsEntry struct {
+	Value string `json:"value"`
+	Calls uint64 `json:"calls"`
+}
+
+// TopStatsEntries returns the {n} values with the most calls,
+// ordered by calls (descending) and value (ascending)
+func TopStatsEntries(counts map[string]uint64, n int) (res []*StatsEntry) {
+	res = make([]*StatsEntry, 0, len(counts))
+	for v, c := range counts {
+		res = append(res, &StatsEntry{Value: v, Calls: c})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Calls != res[j].Calls {
+			return res[i].Calls > res[j].Calls
+		}
+		return res[i].Value < res[j].Value
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+	return
 }
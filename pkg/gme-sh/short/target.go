@@ -0,0 +1,60 @@
+package short
+
+import "strings"
+
+// MaxTargets -> maximum number of target rules of a ShortURL
+const MaxTargets = 20
+
+// TargetRule -> visitors whose device, operating system and language match the rule are redirected to the URL.
+// Empty conditions match every visitor, but a rule needs at least one condition.
+type TargetRule struct {
+	// Device -> DeviceDesktop, DeviceMobile, DeviceTablet or DeviceBot
+	Device string `json:"device,omitempty" bson:"device"`
+	// OS -> family of the operating system (see ParseUserAgent), e. g. iOS or Android
+	OS string `json:"os,omitempty" bson:"os"`
+	// Language -> primary language of the Accept-Language header, e. g. de
+	Language string `json:"language,omitempty" bson:"language"`
+	URL      string `json:"url" bson:"url"`
+}
+
+// IsValid returns true if the rule has an url, at least one condition and a known device (if set)
+func (r *TargetRule) IsValid() bool {
+	if r.URL == "" || r.Device == "" && r.OS == "" && r.Language == "" {
+		return false
+	}
+	switch strings.ToLower(r.Device) {
+	case "", DeviceDesktop, DeviceMobile, DeviceTablet, DeviceBot:
+		return true
+	}
+	return false
+}
+
+// Matches returns true if the {ua} and the primary {language} of the visitor meet all conditions of the rule
+func (r *TargetRule) Matches(ua *UserAgent, language string) bool {
+	if r.Device != "" && !strings.EqualFold(r.Device, ua.Device) {
+		return false
+	}
+	if r.OS != "" && !strings.EqualFold(r.OS, ua.OS) {
+		return false
+	}
+	// "de-DE" in a rule matches every "de" visitor
+	if r.Language != "" && PrimaryLanguage(r.Language) != language {
+		return false
+	}
+	return true
+}
+
+// MatchTarget returns the first target rule matching the User-Agent and Accept-Language headers
+// or nil if no rule matches
+func (u *ShortURL) MatchTarget(userAgent, acceptLanguage string) *TargetRule {
+	if len(u.Targets) == 0 {
+		return nil
+	}
+	ua, language := ParseUserAgent(userAgent), PrimaryLanguage(acceptLanguage)
+	for _, r := range u.Targets {
+		if r != nil && r.Matches(ua, language) {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package short_test
+
+import (
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+const (
+	uaFirefoxLinux  = "Mozilla/5.0 (X11; Linux x86_64; rv:85.0) Gecko/20100101 Firefox/85.0"
+	uaChromeWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+		"(KHTML, like Gecko) Chrome/88.0.4324.150 Safari/537.36"
+	uaEdgeWindows  = uaChromeWindows + " Edg/88.0.705.63"
+	uaSafariIPhone = "Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 " +
+		"(KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1"
+	uaSafariIPad = "Mozilla/5.0 (iPad; CPU OS 14_4 like Mac OS X) AppleWebKit/605.1.15 " +
+		"(KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1"
+	uaChromeAndroid = "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 " +
+		"(KHTML, like Gecko) Chrome/88.0.4324.152 Mobile Safari/537.36"
+	uaAndroidTablet = "Mozilla/5.0 (Linux; Android 10; SM-T510) AppleWebKit/537.36 " +
+		"(KHTML, like Gecko) Chrome/88.0.4324.152 Safari/537.36"
+	uaChromeOS = "Mozilla/5.0 (X11; CrOS x86_64 13597.84.0) AppleWebKit/537.36 " +
+		"(KHTML, like Gecko) Chrome/88.0.4324.186 Safari/537.36"
+	uaOfficeMac = "Microsoft Office/16.0 (Macintosh; Mac OS X 10_15_7; Microsoft Outlook 16.45.1201)"
+	uaGooglebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua       string
+		expected short.UserAgent
+	}{
+		{"", short.UserAgent{Browser: short.Unknown, OS: short.Unknown, Device: short.Unknown}},
+		{uaFirefoxLinux, short.UserAgent{Browser: "Firefox", OS: "Linux", Device: short.DeviceDesktop}},
+		{uaChromeWindows, short.UserAgent{Browser: "Chrome", OS: "Windows", Device: short.DeviceDesktop}},
+		{uaEdgeWindows, short.UserAgent{Browser: "Edge", OS: "Windows", Device: short.DeviceDesktop}},
+		{uaSafariIPhone, short.UserAgent{Browser: "Safari", OS: "iOS", Device: short.DeviceMobile}},
+		{uaSafariIPad, short.UserAgent{Browser: "Safari", OS: "iOS", Device: short.DeviceTablet}},
+		{uaChromeAndroid, short.UserAgent{Browser: "Chrome", OS: "Android", Device: short.DeviceMobile}},
+		{uaAndroidTablet, short.UserAgent{Browser: "Chrome", OS: "Android", Device: short.DeviceTablet}},
+		{uaChromeOS, short.UserAgent{Browser: "Chrome", OS: "Chrome OS", Device: short.DeviceDesktop}},
+		{uaOfficeMac, short.UserAgent{Browser: "Other", OS: "macOS", Device: short.DeviceDesktop}},
+		{uaGooglebot, short.UserAgent{Browser: "Other", OS: "Other", Device: short.DeviceBot}},
+		{"curl/7.74.0", short.UserAgent{Browser: "curl", OS: "Other", Device: short.DeviceBot}},
+	}
+	for _, tc := range tests {
+		if actual := short.ParseUserAgent(tc.ua); *actual != tc.expected {
+			t.Errorf("%q: expected %+v, got %+v", tc.ua, tc.expected, *actual)
+		}
+	}
+}
+
+func TestMatchTarget(t *testing.T) {
+	u := &short.ShortURL{
+		FullURL: "https://example.com",
+		Targets: []*short.TargetRule{
+			{Device: short.DeviceMobile, OS: "iOS", URL: "https://apps.apple.com"},
+			{OS: "android", URL: "https://play.google.com"},
+			{Language: "de-DE", URL: "https://example.de"},
+		},
+	}
+	tests := []struct {
+		name     string
+		ua       string
+		language string
+		expected string
+	}{
+		{"device and os", uaSafariIPhone, "en-US,en;q=0.9", "https://apps.apple.com"},
+		{"device doesn't match", uaSafariIPad, "en", ""},
+		{"os is case insensitive", uaAndroidTablet, "en", "https://play.google.com"},
+		{"first rule wins", uaChromeAndroid, "de", "https://play.google.com"},
+		{"primary language", uaFirefoxLinux, "de-AT,de;q=0.9", "https://example.de"},
+		{"no rule matches", uaFirefoxLinux, "en", ""},
+		{"no headers", "", "", ""},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			actual := ""
+			if r := u.MatchTarget(tc.ua, tc.language); r != nil {
+				actual = r.URL
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+
+	if r := (&short.ShortURL{}).MatchTarget(uaSafariIPhone, "en"); r != nil {
+		t.Errorf("expected no rule for an url without targets, got %+v", r)
+	}
+}
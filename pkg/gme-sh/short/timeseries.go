@@ -0,0 +1,58 @@
+package short
+
+import (
+	"strings"
+	"time"
+)
+
+// Granularity -> size of the buckets of a TimeSeries
+type Granularity string
+
+const (
+	// GranularityHour -> one bucket per hour
+	GranularityHour Granularity = "hour"
+
+	// GranularityDay -> one bucket per day (UTC)
+	GranularityDay Granularity = "day"
+)
+
+// ParseGranularity returns the Granularity for {s} and false if {s} is no valid granularity
+func ParseGranularity(s string) (Granularity, bool) {
+	switch g := Granularity(strings.ToLower(s)); g {
+	case GranularityHour, GranularityDay:
+		return g, true
+	}
+	return "", false
+}
+
+// Duration returns the size of a single bucket
+func (g Granularity) Duration() time.Duration {
+	if g == GranularityDay {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// Bucket returns the number of the bucket {t} belongs to (unix time / bucket size)
+func (g Granularity) Bucket(t time.Time) int64 {
+	return t.Unix() / int64(g.Duration()/time.Second)
+}
+
+// Time returns the start time of the bucket {bucket}
+func (g Granularity) Time(bucket int64) time.Time {
+	return time.Unix(bucket*int64(g.Duration()/time.Second), 0).UTC()
+}
+
+// TimeSeries -> calls of a ShortURL per hour / day
+type TimeSeries struct {
+	Granularity Granularity        `json:"granularity"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Points      []*TimeSeriesPoint `json:"points"`
+}
+
+// TimeSeriesPoint -> calls in the bucket starting at Time
+type TimeSeriesPoint struct {
+	Time  time.Time `json:"time"`
+	Calls uint64    `json:"calls"`
+}
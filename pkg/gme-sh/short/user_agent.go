@@ -0,0 +1,100 @@
+package short
+
+import "strings"
+
+const (
+	// DeviceDesktop -> desktop computers / laptops
+	DeviceDesktop = "desktop"
+	// DeviceMobile -> smartphones
+	DeviceMobile = "mobile"
+	// DeviceTablet -> tablets
+	DeviceTablet = "tablet"
+	// DeviceBot -> crawlers, link previews, command line tools
+	DeviceBot = "bot"
+	// Unknown is used if a value could not be determined
+	Unknown = "unknown"
+)
+
+// UserAgent -> the parts of a User-Agent header we are interested in
+type UserAgent struct {
+	// Browser -> family of the browser (e. g. Firefox, Chrome)
+	Browser string
+	// OS -> family of the operating system (e. g. Windows, iOS)
+	OS string
+	// Device -> class of the device (DeviceDesktop, DeviceMobile, DeviceTablet, DeviceBot)
+	Device string
+}
+
+// the order matters, since most browsers claim to be another browser, too
+var (
+	uaBots = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview",
+		"curl/", "wget", "python", "go-http-client", "java/", "okhttp"}
+	uaBrowsers = [][2]string{
+		{"edg", "Edge"},
+		{"opr/", "Opera"},
+		{"opera", "Opera"},
+		{"samsungbrowser", "Samsung Internet"},
+		{"firefox/", "Firefox"},
+		{"fxios", "Firefox"},
+		{"chromium", "Chromium"},
+		{"crios", "Chrome"},
+		{"chrome/", "Chrome"},
+		{"version/", "Safari"},
+		{"msie ", "Internet Explorer"},
+		{"trident/", "Internet Explorer"},
+		{"curl/", "curl"},
+		{"wget", "Wget"},
+	}
+	uaSystems = [][2]string{
+		{"windows", "Windows"},
+		{"iphone", "iOS"},
+		{"ipad", "iOS"},
+		{"ipod", "iOS"},
+		{"android", "Android"},
+		{"cros ", "Chrome OS"}, // with the space, "microsoft" contains "cros"
+		{"mac os x", "macOS"},
+		{"macintosh", "macOS"},
+		{"linux", "Linux"},
+	}
+)
+
+// ParseUserAgent determines the browser, os and device class of a User-Agent header.
+// It only knows the common browsers and systems, everything else is "Other".
+func ParseUserAgent(ua string) (res *UserAgent) {
+	res = &UserAgent{
+		Browser: "Other",
+		OS:      "Other",
+		Device:  DeviceDesktop,
+	}
+	ua = strings.ToLower(strings.TrimSpace(ua))
+	if ua == "" {
+		res.Browser, res.OS, res.Device = Unknown, Unknown, Unknown
+		return
+	}
+	for _, b := range uaBrowsers {
+		if strings.Contains(ua, b[0]) {
+			res.Browser = b[1]
+			break
+		}
+	}
+	for _, s := range uaSystems {
+		if strings.Contains(ua, s[0]) {
+			res.OS = s[1]
+			break
+		}
+	}
+	for _, b := range uaBots {
+		if strings.Contains(ua, b) {
+			res.Device = DeviceBot
+			return
+		}
+	}
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		res.Device = DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		res.Device = DeviceMobile
+	}
+	return
+}
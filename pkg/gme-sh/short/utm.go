@@ -0,0 +1,101 @@
+package short
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTM -> campaign parameters (utm_source, utm_medium, ...) which are added to the query of a url
+type UTM struct {
+	Source   string `json:"source"`
+	Medium   string `json:"medium"`
+	Campaign string `json:"campaign"`
+	Term     string `json:"term"`
+	Content  string `json:"content"`
+}
+
+// utmKeys -> the utm_* query keys in the order they are appended to a url
+var utmKeys = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content"}
+
+// Values returns the parameters which are set as utm_* query values
+func (u *UTM) Values() url.Values {
+	values := url.Values{}
+	if u == nil {
+		return values
+	}
+	for i, value := range []string{u.Source, u.Medium, u.Campaign, u.Term, u.Content} {
+		if value != "" {
+			values.Set(utmKeys[i], value)
+		}
+	}
+	return values
+}
+
+// TagURL adds the parameters of {utm} to the query of {fullURL}.
+// Existing parameters with the same key are replaced if {override} is true, otherwise they are kept.
+// The other parameters keep their order and encoding, the utm_* parameters are appended.
+func TagURL(fullURL string, utm *UTM, override bool) (res string, err error) {
+	values := utm.Values()
+	if len(values) == 0 {
+		return fullURL, nil
+	}
+	u, schemeless, err := parseFullURL(fullURL)
+	if err != nil {
+		return
+	}
+	var params []string
+	existing := make(map[string]bool)
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key := strings.SplitN(param, "=", 2)[0]
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if _, ok := values[key]; ok {
+			if override {
+				// replaced by the appended parameter
+				continue
+			}
+			existing[key] = true
+		}
+		params = append(params, param)
+	}
+	changed := false
+	for _, key := range utmKeys {
+		if _, ok := values[key]; !ok || existing[key] {
+			continue
+		}
+		params = append(params, key+"="+url.QueryEscape(values.Get(key)))
+		changed = true
+	}
+	if !changed {
+		return fullURL, nil
+	}
+	u.RawQuery = strings.Join(params, "&")
+	res = u.String()
+	if schemeless {
+		res = strings.TrimPrefix(res, "//")
+	}
+	return
+}
+
+// Hostname returns the lower case host of {fullURL} without the port
+func Hostname(fullURL string) string {
+	u, _, err := parseFullURL(fullURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// parseFullURL parses {fullURL}, which may have no protocol (see RedirectURL)
+func parseFullURL(fullURL string) (u *url.URL, schemeless bool, err error) {
+	schemeless = !protocolRegex.MatchString(fullURL)
+	if schemeless {
+		fullURL = "//" + fullURL
+	}
+	u, err = url.Parse(fullURL)
+	return
+}
@@ -0,0 +1,43 @@
+package short_test
+
+import (
+	"testing"
+
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+)
+
+func TestTagURL(t *testing.T) {
+	utm := &short.UTM{Source: "news letter", Campaign: "launch"}
+	tests := []struct {
+		name     string
+		fullURL  string
+		utm      *short.UTM
+		override bool
+		expected string
+	}{
+		{"no parameters", "https://example.com/?b=2&a=1", nil, false, "https://example.com/?b=2&a=1"},
+		{"empty query", "https://example.com/a", utm, false,
+			"https://example.com/a?utm_source=news+letter&utm_campaign=launch"},
+		{"no protocol", "example.com", utm, false, "example.com?utm_source=news+letter&utm_campaign=launch"},
+		{"order and encoding are kept", "https://example.com/?z=1&a=%2F&b#top", utm, false,
+			"https://example.com/?z=1&a=%2F&b&utm_source=news+letter&utm_campaign=launch#top"},
+		{"existing parameters are kept", "https://example.com/?utm_source=x&a=1", utm, false,
+			"https://example.com/?utm_source=x&a=1&utm_campaign=launch"},
+		{"all parameters exist", "https://example.com/?utm_campaign=y&utm_source=x", utm, false,
+			"https://example.com/?utm_campaign=y&utm_source=x"},
+		{"override", "https://example.com/?utm_source=x&a=1&utm_source=y", utm, true,
+			"https://example.com/?a=1&utm_source=news+letter&utm_campaign=launch"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := short.TagURL(tc.fullURL, tc.utm, tc.override)
+			if err != nil {
+				t.Fatalf("tagging %q: %v", tc.fullURL, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
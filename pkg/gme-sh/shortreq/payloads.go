@@ -1,14 +1,75 @@
 package shortreq
 
-import "github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
+	"time"
+)
 
 type CreateShortURLPayload struct {
 	FullURL            string        `json:"full_url"`
 	PreferredAlias     short.ShortID `json:"preferred_alias"`
 	ExpireAfterSeconds int           `json:"expire_after_seconds"`
+	// Password -> optional password which has to be entered before the redirect
+	Password string `json:"password"`
+	// MaxClicks -> optional number of redirects after which the url expires
+	MaxClicks uint64 `json:"max_clicks"`
+	// ActivationDate -> optional date (RFC 3339) before which the url is not redirected
+	ActivationDate *time.Time `json:"activation_date"`
+	// FallbackURL -> optional url visitors are redirected to while the url is not active, expired or exhausted
+	FallbackURL string `json:"fallback_url"`
+	// RedirectStatus -> optional status code of the redirect (301, 302, 307 or 308)
+	RedirectStatus int `json:"redirect_status"`
+	// ForwardQuery -> add the query parameters of the request to the full url
+	ForwardQuery bool `json:"forward_query"`
+	// ForwardPath -> append the path after the id (/:id/*) to the full url
+	ForwardPath bool `json:"forward_path"`
+	// UTM -> optional campaign parameters which are added to the full url
+	UTM *short.UTM `json:"utm"`
+	// Destinations -> optional weighted urls the traffic is split between,
+	// the full url defaults to the first destination
+	Destinations []*short.Destination `json:"destinations"`
+	// Targets -> optional rules which redirect visitors by device, os or language
+	Targets []*short.TargetRule `json:"targets"`
+	// Deduplicate -> return an existing short url for the same long url instead of creating a new one.
+	// The default is configured per instance.
+	Deduplicate *bool `json:"deduplicate"`
+	// IDGenerator -> optional strategy for the generated id ("random", "sequential", "hashids" or "words"),
+	// the default is configured per instance
+	IDGenerator string `json:"id_generator"`
+}
+
+// UpdateShortURLPayload -> PATCH /:id/:secret
+// Fields which are not set (null) are not changed.
+type UpdateShortURLPayload struct {
+	FullURL *string `json:"full_url"`
+	// ExpireAfterSeconds -> new expiration relative to now, 0 removes the expiration
+	ExpireAfterSeconds *int `json:"expire_after_seconds"`
+	// Password -> new password, "" removes the password
+	Password *string `json:"password"`
+	// ActivationDate -> new activation date, a date in the past activates the url immediately
+	ActivationDate *time.Time `json:"activation_date"`
+	// FallbackURL -> new fallback url, "" removes the fallback url
+	FallbackURL *string `json:"fallback_url"`
+	// RedirectStatus -> new status code of the redirect, 0 uses the default of the instance
+	RedirectStatus *int `json:"redirect_status"`
+	// ForwardQuery -> add the query parameters of the request to the full url
+	ForwardQuery *bool `json:"forward_query"`
+	// ForwardPath -> append the path after the id (/:id/*) to the full url
+	ForwardPath *bool `json:"forward_path"`
+	// Destinations -> new weighted urls, [] removes the destinations
+	Destinations *[]*short.Destination `json:"destinations"`
+	// Targets -> new target rules, [] removes the rules
+	Targets *[]*short.TargetRule `json:"targets"`
 }
 
 type UpdatePoolPayload struct {
 	Name string `json:"name"`
 	URL  string `json:"url"`
 }
+
+// CreateAccountPayload -> POST /admin/accounts
+type CreateAccountPayload struct {
+	Name string `json:"name"`
+	// DailyQuota -> max. short urls the account can create per day, 0 means unlimited
+	DailyQuota uint64 `json:"daily_quota"`
+}
@@ -17,7 +17,8 @@ type Successable struct {
 	Data    interface{} `json:"data"`
 }
 
-func (r *Response) successable(data interface{}) *Successable {
+// Successable returns the body of the response with the {data}
+func (r *Response) Successable(data interface{}) *Successable {
 	return &Successable{
 		Success: r.StatusCode >= 200 && r.StatusCode < 400,
 		Message: r.Message,
@@ -26,12 +27,19 @@ func (r *Response) successable(data interface{}) *Successable {
 	}
 }
 
+// WithMessage returns a copy of the response with another message
+func (r *Response) WithMessage(message string) *Response {
+	res := *r
+	res.Message = message
+	return &res
+}
+
 func (r *Response) Send(ctx *fiber.Ctx) error {
 	return r.SendWithData(ctx, nil)
 }
 
 func (r *Response) SendWithData(ctx *fiber.Ctx, data interface{}) error {
-	return ctx.Status(r.StatusCode).JSON(r.successable(data))
+	return ctx.Status(r.StatusCode).JSON(r.Successable(data))
 }
 
 func (r *Response) SendWithMessage(ctx *fiber.Ctx, message string) error {
@@ -39,7 +47,7 @@ func (r *Response) SendWithMessage(ctx *fiber.Ctx, message string) error {
 }
 
 func (r *Response) SendWithMessageData(ctx *fiber.Ctx, message string, data interface{}) error {
-	s := r.successable(data)
+	s := r.Successable(data)
 	s.Message = message
 	return ctx.Status(r.StatusCode).JSON(s)
 }
@@ -0,0 +1,69 @@
+package shortreq
+
+// OK
+var (
+	ResponseOkLinks = &Response{
+		InternalCode: +10001,
+		StatusCode:   200,
+		Message:      "links",
+	}
+	ResponseOkAccountCreated = &Response{
+		InternalCode: +10002,
+		StatusCode:   201,
+		Message:      "account created",
+	}
+	ResponseOkAccounts = &Response{
+		InternalCode: +10003,
+		StatusCode:   200,
+		Message:      "accounts",
+	}
+	ResponseOkAccountDeleted = &Response{
+		InternalCode: +10004,
+		StatusCode:   200,
+		Message:      "account deleted",
+	}
+	ResponseOkAPIKeyRotated = &Response{
+		InternalCode: +10005,
+		StatusCode:   200,
+		Message:      "api key rotated",
+	}
+)
+
+// ERR
+var (
+	ResponseErrInvalidAPIKey = &Response{
+		InternalCode: -10001,
+		StatusCode:   401,
+		Message:      "invalid api key",
+	}
+	ResponseErrAPIKeyRequired = &Response{
+		InternalCode: -10002,
+		StatusCode:   401,
+		Message:      "api key required (Authorization: Bearer {key})",
+	}
+	ResponseErrAccountNotFound = &Response{
+		InternalCode: -10003,
+		StatusCode:   404,
+		Message:      "account not found",
+	}
+	ResponseErrInvalidAccount = &Response{
+		InternalCode: -10004,
+		StatusCode:   400,
+		Message:      "account needs a name",
+	}
+	ResponseErrInvalidPage = &Response{
+		InternalCode: -10005,
+		StatusCode:   400,
+		Message:      "offset must be >= 0 and limit between 1 and 100",
+	}
+	ResponseErrAccountDatabase = &Response{
+		InternalCode: -10006,
+		StatusCode:   503,
+		Message:      "error accessing accounts",
+	}
+	ResponseErrQuotaExceeded = &Response{
+		InternalCode: -10007,
+		StatusCode:   429,
+		Message:      "daily quota of the api key exceeded",
+	}
+)
@@ -7,6 +7,16 @@ var (
 		StatusCode:   201,
 		Message:      "created",
 	}
+	ResponseOkBulkCreate = &Response{
+		InternalCode: +2002,
+		StatusCode:   200,
+		Message:      "processed",
+	}
+	ResponseOkExisting = &Response{
+		InternalCode: +2003,
+		StatusCode:   200,
+		Message:      "short url already exists",
+	}
 )
 
 // ERR
@@ -41,4 +51,39 @@ var (
 		StatusCode:   400,
 		Message:      "invalid id (alias)",
 	}
+	ResponseErrInvalidActivation = &Response{
+		InternalCode: -2007,
+		StatusCode:   400,
+		Message:      "activation date must be before the expiration date",
+	}
+	ResponseErrInvalidRedirectStatus = &Response{
+		InternalCode: -2008,
+		StatusCode:   400,
+		Message:      "redirect status must be 301, 302, 307 or 308",
+	}
+	ResponseErrInvalidDestinations = &Response{
+		InternalCode: -2009,
+		StatusCode:   400,
+		Message:      "destinations need a weight > 0 (max. 10 destinations)",
+	}
+	ResponseErrInvalidTargets = &Response{
+		InternalCode: -2010,
+		StatusCode:   400,
+		Message:      "targets need an url and a known device, an os or a language (max. 20 targets)",
+	}
+	ResponseErrInvalidBulk = &Response{
+		InternalCode: -2011,
+		StatusCode:   400,
+		Message:      "expected a JSON array or a stream of JSON objects",
+	}
+	ResponseErrBulkTooLarge = &Response{
+		InternalCode: -2012,
+		StatusCode:   413,
+		Message:      "too many short urls (max. 500)",
+	}
+	ResponseErrInvalidIDGenerator = &Response{
+		InternalCode: -2013,
+		StatusCode:   400,
+		Message:      "id generator must be random, sequential, hashids or words",
+	}
 )
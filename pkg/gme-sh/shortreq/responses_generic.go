@@ -16,4 +16,14 @@ var (
 		StatusCode:   0,
 		Message:      "url not found",
 	}
+	ResponseErrNotActive = &Response{
+		InternalCode: -1002,
+		StatusCode:   403,
+		Message:      "url is not active yet",
+	}
+	ResponseErrRateLimited = &Response{
+		InternalCode: -1003,
+		StatusCode:   429,
+		Message:      "too many requests",
+	}
 )
@@ -0,0 +1,25 @@
+package shortreq
+
+// ERR
+var (
+	ResponseErrPasswordRequired = &Response{
+		InternalCode: -8001,
+		StatusCode:   401,
+		Message:      "password required",
+	}
+	ResponseErrPasswordMismatch = &Response{
+		InternalCode: -8002,
+		StatusCode:   401,
+		Message:      "wrong password",
+	}
+	ResponseErrTooManyPasswordAttempts = &Response{
+		InternalCode: -8003,
+		StatusCode:   429,
+		Message:      "too many wrong passwords, try again later",
+	}
+	ResponseErrInvalidPassword = &Response{
+		InternalCode: -8004,
+		StatusCode:   400,
+		Message:      "invalid password (max. 72 bytes)",
+	}
+)
@@ -0,0 +1,34 @@
+package shortreq
+
+// OK
+var (
+	ResponseOkSecretRotated = &Response{
+		InternalCode: +9001,
+		StatusCode:   200,
+		Message:      "secret rotated",
+	}
+	ResponseOkSecretReset = &Response{
+		InternalCode: +9002,
+		StatusCode:   200,
+		Message:      "secret reset",
+	}
+)
+
+// ERR
+var (
+	ResponseErrAdminDisabled = &Response{
+		InternalCode: -9001,
+		StatusCode:   404,
+		Message:      "admin api is disabled",
+	}
+	ResponseErrAdminUnauthorized = &Response{
+		InternalCode: -9002,
+		StatusCode:   401,
+		Message:      "invalid admin token",
+	}
+	ResponseErrSecretRotation = &Response{
+		InternalCode: -9003,
+		StatusCode:   500,
+		Message:      "error saving the new secret",
+	}
+)
@@ -11,6 +11,16 @@ var (
 
 // ERR
 var (
+	ResponseErrInvalidGranularity = &Response{
+		InternalCode: -4001,
+		StatusCode:   400,
+		Message:      "invalid granularity (hour, day)",
+	}
+	ResponseErrInvalidTimeRange = &Response{
+		InternalCode: -4002,
+		StatusCode:   400,
+		Message:      "invalid time range",
+	}
 	ResponseErrRequestedFile = &Response{
 		InternalCode: -5001,
 		StatusCode:   404,
@@ -0,0 +1,10 @@
+package shortreq
+
+// OK
+var (
+	ResponseOkUpdated = &Response{
+		InternalCode: +7001,
+		StatusCode:   200,
+		Message:      "updated",
+	}
+)
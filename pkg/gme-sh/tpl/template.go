@@ -1,6 +1,7 @@
 package tpl
 
 import (
+	"github.com/gme-sh/gme.sh-api/pkg/gme-sh/short"
 	"github.com/gofiber/fiber/v2"
 	"log"
 	"regexp"
@@ -10,7 +11,9 @@ import (
 type Template struct {
 	TemplateURL string `json:"template_url" bson:"template_url"`
 	FullURL     string `json:"full_url" bson:"full_url"`
-	params      []string
+	// RedirectStatus -> status code of the redirect (301, 302, 307 or 308), 0 means the default of the instance
+	RedirectStatus int `json:"redirect_status,omitempty" bson:"redirect_status"`
+	params         []string
 }
 
 const paramPattern = `\:([A-Za-z]+)`
@@ -61,17 +64,27 @@ func (t *Template) Check() (val bool) {
 			val = false
 		}
 	}
+	if t.RedirectStatus != 0 && !short.IsValidRedirectStatus(t.RedirectStatus) {
+		log.Println("WARN :: Invalid redirect status", t.RedirectStatus, "for template", t.TemplateURL)
+		val = false
+	}
 	return
 }
 
-func (t *Template) Register(app *fiber.App) {
+// Register adds the template route to the {app}.
+// Templates without a valid redirect status are redirected with {defaultStatus}.
+func (t *Template) Register(app *fiber.App, defaultStatus int) {
 	log.Println("TPL :: Registering", t.TemplateURL)
+	status := defaultStatus
+	if short.IsValidRedirectStatus(t.RedirectStatus) {
+		status = t.RedirectStatus
+	}
 	app.Get(t.TemplateURL, func(ctx *fiber.Ctx) error {
 		url := t.FullURL
 		for _, p := range t.Params() {
 			url = strings.ReplaceAll(url, ":"+p, ctx.Params(p))
 		}
 		log.Println("Redirecting Template", t.TemplateURL, "to", url)
-		return ctx.Redirect(url)
+		return ctx.Redirect(url, status)
 	})
 }
@@ -1,5 +0,0 @@
-CREATE TABLE ShortenedUrls (
-    id VARCHAR(50) PRIMARY KEY,
-    full_url TEXT NOT NULL,
-    created_at DATE
-);
\ No newline at end of file